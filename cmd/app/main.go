@@ -2,26 +2,65 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/mymmrac/telego"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/yermakovsa/alchemyws"
+	"github.com/yermakovsa/eth-watcher/internal/addressbook"
 	"github.com/yermakovsa/eth-watcher/internal/aggregator"
+	"github.com/yermakovsa/eth-watcher/internal/alerting"
+	"github.com/yermakovsa/eth-watcher/internal/api"
+	"github.com/yermakovsa/eth-watcher/internal/balance"
+	"github.com/yermakovsa/eth-watcher/internal/bot"
 	"github.com/yermakovsa/eth-watcher/internal/config"
+	"github.com/yermakovsa/eth-watcher/internal/contract"
+	"github.com/yermakovsa/eth-watcher/internal/ethrpc"
+	"github.com/yermakovsa/eth-watcher/internal/health"
+	"github.com/yermakovsa/eth-watcher/internal/history"
+	"github.com/yermakovsa/eth-watcher/internal/logging"
+	"github.com/yermakovsa/eth-watcher/internal/logwatcher"
+	"github.com/yermakovsa/eth-watcher/internal/metrics"
+	"github.com/yermakovsa/eth-watcher/internal/nonce"
 	"github.com/yermakovsa/eth-watcher/internal/notifier"
+	"github.com/yermakovsa/eth-watcher/internal/price"
+	"github.com/yermakovsa/eth-watcher/internal/sanctions"
+	"github.com/yermakovsa/eth-watcher/internal/settings"
+	"github.com/yermakovsa/eth-watcher/internal/tracing"
+	"github.com/yermakovsa/eth-watcher/internal/txexpr"
 	"github.com/yermakovsa/eth-watcher/internal/watcher"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const (
+	// receiptBatchDelay is how long receipt lookups are collected before being sent as one batch.
+	receiptBatchDelay = 100 * time.Millisecond
+	// shutdownTimeout bounds how long in-flight HTTP requests may take to finish on exit.
+	shutdownTimeout = 5 * time.Second
 )
 
 func main() {
 	// Load .env file (optional, non-fatal)
 	_ = godotenv.Load()
 
+	if len(os.Args) > 1 && os.Args[1] == "history" {
+		os.Exit(runHistory(os.Args[2:]))
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -31,11 +70,102 @@ func main() {
 
 	// Load application config
 	cfg := config.Load()
+	logger := mustInitLogger(cfg.LogLevel, cfg.LogFormat)
+	slog.SetDefault(logger)
+	if cfg.AdminToken != "" && cfg.SettingsFile == "" {
+		fatal("API_ADMIN_TOKEN requires SETTINGS_FILE to persist changes")
+	}
+	store, stored := mustLoadSettings(cfg.SettingsFile)
+	overrideWallets(&cfg, stored)
 
 	// Initialize services
-	bot := mustInitTelegramBot(cfg.TelegramBotAPIKey)
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	m := metrics.New(reg)
+	tp, shutdownTracing := mustInitTracing(ctx, cfg.OTLPEndpoint)
+	defer shutdownTracing()
+
+	alertHistory := mustOpenHistory(cfg.HistoryFile, time.Duration(cfg.HistoryRetentionDays)*24*time.Hour, logger)
+	if alertHistory != nil {
+		defer alertHistory.Close()
+	}
+
+	tg := mustInitTelegramBot(cfg.TelegramBotAPIKey)
 	chatID := mustParseChatID(cfg.TelegramChatID)
-	notif := notifier.NewTelegramNotifier(bot, chatID)
+	alertChats := []int64{chatID}
+	var telegramOpts []notifier.TelegramOption
+	if cfg.TelegramCommands {
+		telegramOpts = append(telegramOpts, notifier.WithAlertButtons())
+	}
+	routes := map[notifier.Severity]notifier.Notifier{}
+	for severity, id := range map[notifier.Severity]string{
+		notifier.SeverityInfo:     cfg.InfoChatID,
+		notifier.SeverityCritical: cfg.CriticalChatID,
+	} {
+		if id != "" {
+			severityChat := mustParseChatID(id)
+			alertChats = append(alertChats, severityChat)
+			routes[severity] = notifier.NewTelegramNotifier(tg, severityChat, telegramOpts...)
+		}
+	}
+	router := notifier.NewRouter(notifier.NewTelegramNotifier(tg, chatID, telegramOpts...), routes)
+	delivery := notifier.Instrument("telegram", router, m, logger, tp)
+	snoozes := aggregator.NewSnoozes()
+	trackerOpts := []alerting.Option{alerting.WithMetrics(m), alerting.WithLogger(logger)}
+	if cfg.EscalationChatID != "" {
+		escalationChat := mustParseChatID(cfg.EscalationChatID)
+		alertChats = append(alertChats, escalationChat)
+		escalation := alertHistory.RecordDeliveries("telegram_escalation",
+			notifier.Instrument("telegram_escalation", notifier.NewTelegramNotifier(tg, escalationChat, telegramOpts...), m, logger, tp))
+		trackerOpts = append(trackerOpts, alerting.WithEscalation(escalation, time.Duration(cfg.EscalationTimeoutSeconds)*time.Second))
+	}
+	alerts := alerting.New(alertHistory.RecordDeliveries("telegram", delivery), snoozes, trackerOpts...)
+	muter := notifier.NewMuter(alerts, logger,
+		notifier.WithSnoozes(snoozes),
+		notifier.WithMuterMetrics(m),
+		notifier.WithHeld(alertHistory.RecordHeld),
+	)
+	notif := alertHistory.Record(muter)
+	rpcClient := ethrpc.NewClient(cfg.RPCURL, nil)
+	receipts := ethrpc.NewReceipts(rpcClient, receiptBatchDelay)
+	rules := mustLoadRules(cfg.RulesFile, cfg.Watchlist, stored.Rules)
+	book := mustLoadAddressBook(cfg.AddressBookFile)
+
+	aggOpts := []aggregator.Option{
+		aggregator.WithBlockTimes(rpcClient),
+		aggregator.WithNetFlow(cfg.WalletsNet, cfg.NetThresholdETH),
+		aggregator.WithRules(rules),
+		aggregator.WithCounterpartyWarmUp(time.Duration(cfg.CounterpartyWarmUpSeconds) * time.Second),
+		aggregator.WithAddressBook(book),
+		aggregator.WithThresholdTiers(mustThresholdFactors(cfg.ThresholdInfoFactor, cfg.ThresholdCriticalFactor)),
+		aggregator.WithMetrics(m),
+		aggregator.WithLogger(logger),
+		aggregator.WithTracerProvider(tp),
+	}
+	if cfg.CheckTxStatus {
+		aggOpts = append(aggOpts,
+			aggregator.WithReceipts(receipts),
+			aggregator.WithFailedTxAlert(cfg.FailedTxCount, time.Duration(cfg.FailedTxSeconds)*time.Second),
+		)
+	}
+	if cfg.FeeThresholdETH > 0 {
+		aggOpts = append(aggOpts, aggregator.WithFeeSpend(receipts, cfg.FeeThresholdETH, time.Duration(cfg.FeeWindowSeconds)*time.Second))
+	}
+	if feed := newPriceFeed(cfg, logger); feed != nil {
+		aggOpts = append(aggOpts, aggregator.WithPrices(feed, cfg.ThresholdUSD, cfg.NetThresholdUSD))
+	} else if cfg.ThresholdUSD > 0 || cfg.NetThresholdUSD > 0 {
+		fatal("USD thresholds require PRICE_FEED_URL, PRICE_FILE or ETH_USD_PRICE")
+	}
+	if cfg.BalanceFile != "" {
+		monitor := balance.New(rpcClient, notif, mustLoadBalanceThresholds(cfg.BalanceFile), book, logger)
+		go monitor.Run(ctx, time.Duration(cfg.BalancePollSeconds)*time.Second)
+		aggOpts = append(aggOpts, aggregator.WithBalances(monitor))
+	}
+	if len(cfg.SanctionsFiles) > 0 {
+		list := mustLoadSanctions(cfg.SanctionsFiles, logger)
+		go list.Run(ctx, time.Duration(cfg.SanctionsRefreshSeconds)*time.Second)
+		aggOpts = append(aggOpts, aggregator.WithSanctions(list))
+	}
 
 	agg := aggregator.NewAggregator(
 		ctx,
@@ -43,31 +173,239 @@ func main() {
 		cfg.ThresholdETH,
 		time.Duration(cfg.WindowSeconds)*time.Second,
 		time.Duration(cfg.CooldownSeconds)*time.Second,
+		aggOpts...,
 	)
+	for direction, t := range stored.Thresholds {
+		if err := agg.SetThresholds(direction, aggregator.Thresholds{ETH: t.ETH, USD: t.USD}); err != nil {
+			fatal("Invalid stored thresholds", "path", cfg.SettingsFile, "direction", direction, "error", err)
+		}
+	}
+	for wallet, eth := range stored.WalletThresholds {
+		if err := agg.SetWalletThreshold(wallet, eth); err != nil {
+			fatal("Invalid stored thresholds", "path", cfg.SettingsFile, "wallet", wallet, "error", err)
+		}
+	}
 
 	client, err := alchemyws.NewAlchemyClient(cfg.AlchemyAPIKey, nil)
 	if err != nil {
-		log.Fatalf("[Main] Failed to initialize Alchemy client: %v", err)
+		fatal("Failed to initialize Alchemy client", "error", err)
+	}
+
+	watcherOpts := []watcher.Option{
+		watcher.WithDialer(func() (watcher.AlchemyClient, error) {
+			return alchemyws.NewAlchemyClient(cfg.AlchemyAPIKey, nil)
+		}),
+		watcher.WithCatchUp(rpcClient, uint64(cfg.CatchUpMaxBlocks)),
+		watcher.WithNetWallets(cfg.WalletsNet),
+		watcher.WithMetrics(m),
+		watcher.WithLogger(logger),
+		watcher.WithTracerProvider(tp),
+	}
+	if cfg.ContractsFile != "" {
+		watcherOpts = append(watcherOpts, watcher.WithContracts(mustLoadContracts(cfg.ContractsFile)))
+	}
+	if len(cfg.NonceWallets) > 0 {
+		tracker := nonce.New(rpcClient, notif, cfg.NonceWallets, time.Duration(cfg.StuckAfterSeconds)*time.Second, book, logger)
+		go tracker.Run(ctx, time.Duration(cfg.NoncePollSeconds)*time.Second)
+		watcherOpts = append(watcherOpts, watcher.WithNonces(tracker))
+	}
+	if cfg.TraceMethod != "" {
+		watcherOpts = append(watcherOpts, watcher.WithTracer(mustInitTracer(rpcClient, cfg.TraceMethod)))
+	}
+	if cfg.TxFilter != "" {
+		watcherOpts = append(watcherOpts, watcher.WithFilter(mustCompileFilter(cfg.TxFilter, cfg.Watchlist)))
 	}
 
-	w := watcher.NewWatcher(ctx, client, cfg.WalletsFrom, cfg.WalletsTo, agg)
+	w := watcher.NewWatcher(ctx, client, cfg.WalletsFrom, cfg.WalletsTo, agg, watcherOpts...)
 
-	log.Println("[Main] Starting transaction watcher...")
+	slog.Info("Starting transaction watcher", "component", "main")
 	if err := w.Start(); err != nil {
-		log.Fatalf("[Main] Watcher failed to start: %v", err)
+		fatal("Watcher failed to start", "error", err)
+	}
+
+	manager := settings.NewManager(w, agg, store, settings.WithRules(cfg.Watchlist, stored.Rules))
+	staleness := time.Duration(cfg.StreamStaleSeconds) * time.Second
+
+	if cfg.TelegramCommands {
+		commands := bot.New(tg, mustParseCommandChats(cfg.TelegramCommandChats, alertChats), w, agg, muter, manager,
+			bot.WithAddressBook(book),
+			bot.WithStreamStaleness(staleness),
+			bot.WithAlerts(alerts),
+			bot.WithLogger(logger),
+		)
+		if err := commands.Start(ctx); err != nil {
+			fatal("Telegram commands failed to start", "error", err)
+		}
+	}
+
+	var lw *logwatcher.LogWatcher
+	if cfg.LogsFile != "" {
+		subs := mustLoadLogSubscriptions(cfg.LogsFile)
+		lw = logwatcher.NewLogWatcher(ctx, rpcClient, subs, agg, time.Duration(cfg.LogsPollSeconds)*time.Second, logger)
+		if err := lw.Start(); err != nil {
+			fatal("Log watcher failed to start", "error", err)
+		}
+	}
+
+	var server *http.Server
+	if cfg.HTTPAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+
+		liveness := time.Duration(cfg.LivenessSeconds) * time.Second
+		mux.Handle("/healthz", health.Handler(
+			health.Check{Name: "watcher", Run: func() error { return w.Alive(liveness) }},
+			health.Check{Name: "notifier", Run: func() error { return delivery.Alive(liveness) }},
+		))
+		mux.Handle("/readyz", health.Handler(
+			health.Check{Name: "stream", Run: func() error { return w.Ready(staleness) }},
+		))
+		apiOpts := []api.Option{api.WithAlerts(alerts, snoozes)}
+		if alertHistory != nil {
+			apiOpts = append(apiOpts, api.WithHistory(alertHistory))
+		}
+		if cfg.AdminToken != "" {
+			apiOpts = append(apiOpts, api.WithManagement(cfg.AdminToken, manager))
+		}
+		mux.Handle("/api/", api.New(agg, w, book, apiOpts...))
+		server = startHTTPServer(cfg.HTTPAddr, mux)
 	}
 
 	// Wait for termination signal
 	<-sigChan
-	log.Println("[Main] Shutdown signal received. Cleaning up...")
+	slog.Info("Shutdown signal received, cleaning up", "component", "main")
 	w.Stop()
+	if lw != nil {
+		lw.Stop()
+	}
+	if server != nil {
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancelShutdown()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Error("HTTP server shutdown failed", "component", "main", "error", err)
+		}
+	}
+}
+
+// runHistory prints the stored alerts matching the command line filters, returning the exit code.
+// The watcher locks the history file while it runs, so the API serves the same queries meanwhile.
+func runHistory(args []string) int {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	path := flags.String("file", os.Getenv("HISTORY_FILE"), "alert history file, defaults to HISTORY_FILE")
+	wallet := flags.String("wallet", "", "only alerts about this wallet")
+	severity := flags.String("severity", "", "only alerts of this severity: info, warning or critical")
+	since := flags.String("since", "", "only alerts fired since this time, date or duration ago, such as 24h")
+	until := flags.String("until", "", "only alerts fired until this time, date or duration ago")
+	limit := flags.Int("limit", 0, "maximum number of alerts, newest first; 0 lists all")
+	format := flags.String("format", history.FormatJSON, "output format: json or csv")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	q := history.Query{Wallet: strings.ToLower(*wallet), Severity: notifier.Severity(*severity), Limit: *limit}
+	if q.Severity != "" && !q.Severity.Valid() {
+		fmt.Fprintln(os.Stderr, "severity must be info, warning or critical")
+		return 2
+	}
+	if *format != history.FormatJSON && *format != history.FormatCSV {
+		fmt.Fprintln(os.Stderr, "format must be json or csv")
+		return 2
+	}
+	now := time.Now()
+	for raw, bound := range map[string]*time.Time{*since: &q.Since, *until: &q.Until} {
+		if raw == "" {
+			continue
+		}
+		t, err := history.ParseTime(raw, now)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		*bound = t
+	}
+	if *path == "" {
+		fmt.Fprintln(os.Stderr, "history file is not set, use -file or HISTORY_FILE")
+		return 2
+	}
+
+	store, err := history.Open(*path, history.ReadOnly())
+	if errors.Is(err, history.ErrLocked) {
+		fmt.Fprintln(os.Stderr, err.Error()+"; stop the watcher or query GET /api/v1/history instead")
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "open alert history:", err)
+		return 1
+	}
+	defer store.Close()
+
+	entries, err := store.Find(q)
+	if err == nil {
+		err = history.Write(os.Stdout, *format, entries)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// startHTTPServer serves handler on addr in the background, exiting if the server fails.
+func startHTTPServer(addr string, handler http.Handler) *http.Server {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		slog.Info("Serving HTTP", "component", "main", "addr", addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("HTTP server failed", "error", err)
+		}
+	}()
+	return server
+}
+
+// mustInitLogger creates the application logger from the configured level and format or exits on failure.
+func mustInitLogger(level, format string) *slog.Logger {
+	logger, err := logging.New(os.Stderr, format, level)
+	if err != nil {
+		fatal("Failed to initialize logger", "error", err)
+	}
+	return logger
+}
+
+// mustInitTracing creates the OTLP tracer provider when an endpoint is configured, or a
+// provider discarding spans otherwise. The returned function flushes pending spans.
+func mustInitTracing(ctx context.Context, endpoint string) (trace.TracerProvider, func()) {
+	if endpoint == "" {
+		return noop.NewTracerProvider(), func() {}
+	}
+	tp, err := tracing.NewProvider(ctx, endpoint)
+	if err != nil {
+		fatal("Failed to initialize tracing", "error", err)
+	}
+	slog.Info("Exporting traces", "component", "main", "endpoint", endpoint)
+	return tp, func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := tp.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Failed to flush traces", "component", "main", "error", err)
+		}
+	}
+}
+
+// fatal logs an unrecoverable startup error and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, append([]any{"component", "main"}, args...)...)
+	os.Exit(1)
 }
 
 // mustInitTelegramBot initializes the Telegram bot or exits on failure.
 func mustInitTelegramBot(apiKey string) *telego.Bot {
 	bot, err := telego.NewBot(apiKey, telego.WithDiscardLogger())
 	if err != nil {
-		log.Fatalf("[Telegram] Failed to initialize bot: %v", err)
+		fatal("Failed to initialize Telegram bot", "error", err)
 	}
 	return bot
 }
@@ -76,7 +414,177 @@ func mustInitTelegramBot(apiKey string) *telego.Bot {
 func mustParseChatID(chatIDStr string) int64 {
 	id, err := strconv.ParseInt(chatIDStr, 10, 64)
 	if err != nil {
-		log.Fatalf("[Telegram] Invalid chat ID '%s': %v", chatIDStr, err)
+		fatal("Invalid Telegram chat ID", "chat_id", chatIDStr, "error", err)
 	}
 	return id
 }
+
+// mustThresholdFactors checks that info alerts fire below the threshold and critical ones
+// above it, or exits on failure. Zero disables a tier.
+func mustThresholdFactors(info, critical float64) (float64, float64) {
+	if info < 0 || info >= 1 {
+		fatal("THRESHOLD_INFO_FACTOR must be between 0 and 1", "value", info)
+	}
+	if critical < 0 || (critical > 0 && critical <= 1) {
+		fatal("THRESHOLD_CRITICAL_FACTOR must be greater than 1", "value", critical)
+	}
+	return info, critical
+}
+
+// mustParseCommandChats converts the chat IDs allowed to send commands, defaulting to
+// the chats receiving alerts, or exits on failure.
+func mustParseCommandChats(ids []string, alertChats []int64) []int64 {
+	if len(ids) == 0 {
+		return alertChats
+	}
+	chats := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		chats = append(chats, mustParseChatID(id))
+	}
+	return chats
+}
+
+// mustLoadRules returns the stored rules, if any were changed at runtime, or reads alert
+// rules from path, if configured. It exits on failure.
+func mustLoadRules(path string, watchlist []string, stored []aggregator.Rule) []aggregator.Rule {
+	if stored != nil {
+		if err := aggregator.CompileRules(stored, watchlist); err != nil {
+			fatal("Invalid stored rules", "error", err)
+		}
+		slog.Info("Using stored rules instead of the rules file", "component", "main", "rules", len(stored))
+		return stored
+	}
+	if path == "" {
+		return nil
+	}
+	rules, err := aggregator.LoadRules(path, watchlist)
+	if err != nil {
+		fatal("Failed to load rules", "path", path, "error", err)
+	}
+	return rules
+}
+
+// mustLoadAddressBook reads the address book from path, if configured, or exits on failure.
+func mustLoadAddressBook(path string) *addressbook.Book {
+	if path == "" {
+		return nil
+	}
+	book, err := addressbook.Load(path)
+	if err != nil {
+		fatal("Failed to load address book", "path", path, "error", err)
+	}
+	return book
+}
+
+// mustOpenHistory opens the alert history file, if one is configured, or exits on failure.
+// Alerts older than retention are deleted, unless it is zero.
+func mustOpenHistory(path string, retention time.Duration, logger *slog.Logger) *history.Store {
+	if path == "" {
+		return nil
+	}
+	store, err := history.Open(path, history.WithRetention(retention), history.WithLogger(logger))
+	if err != nil {
+		fatal("Failed to open alert history", "path", path, "error", err)
+	}
+	return store
+}
+
+// mustLoadSettings opens the settings store, if configured, and reads the settings saved
+// through the management API, or exits on failure.
+func mustLoadSettings(path string) (*settings.Store, settings.Settings) {
+	if path == "" {
+		return nil, settings.Settings{}
+	}
+	store := settings.NewStore(path)
+	stored, ok, err := store.Load()
+	if err != nil {
+		fatal("Failed to load settings", "path", path, "error", err)
+	}
+	if ok {
+		slog.Info("Loaded stored settings, overriding monitored wallets and thresholds", "component", "main", "path", path)
+	}
+	return store, stored
+}
+
+// overrideWallets replaces the monitored wallets of every direction present in the stored settings.
+func overrideWallets(cfg *config.Config, stored settings.Settings) {
+	if wallets, ok := stored.Wallets[aggregator.From]; ok {
+		cfg.WalletsFrom = wallets
+	}
+	if wallets, ok := stored.Wallets[aggregator.To]; ok {
+		cfg.WalletsTo = wallets
+	}
+	if wallets, ok := stored.Wallets[aggregator.Net]; ok {
+		cfg.WalletsNet = wallets
+	}
+}
+
+// newPriceFeed returns the configured ETH price source, preferring an HTTP endpoint over
+// a local file over a static price, or nil if none is configured.
+func newPriceFeed(cfg config.Config, logger *slog.Logger) price.Feed {
+	switch {
+	case cfg.PriceFeedURL != "":
+		return price.NewHTTP(cfg.PriceFeedURL, cfg.PriceFeedField, time.Duration(cfg.PriceCacheSeconds)*time.Second, nil, logger)
+	case cfg.PriceFile != "":
+		return price.NewFile(cfg.PriceFile)
+	case cfg.PriceStaticUSD > 0:
+		return price.Static(cfg.PriceStaticUSD)
+	default:
+		return nil
+	}
+}
+
+// mustLoadBalanceThresholds reads the per-wallet balance thresholds or exits on failure.
+func mustLoadBalanceThresholds(path string) map[string]balance.Threshold {
+	thresholds, err := balance.Load(path)
+	if err != nil {
+		fatal("Failed to load balance thresholds", "path", path, "error", err)
+	}
+	return thresholds
+}
+
+// mustLoadSanctions performs the initial load of the flagged address lists or exits on failure.
+func mustLoadSanctions(paths []string, logger *slog.Logger) *sanctions.List {
+	list := sanctions.New(paths, logger)
+	if err := list.Reload(); err != nil {
+		fatal("Failed to load sanctions lists", "error", err)
+	}
+	slog.Info("Loaded flagged addresses", "component", "main", "addresses", list.Len())
+	return list
+}
+
+// mustLoadContracts reads the watched contracts and their ABIs or exits on failure.
+func mustLoadContracts(path string) *contract.Matcher {
+	contracts, err := contract.Load(path)
+	if err != nil {
+		fatal("Failed to load contracts", "path", path, "error", err)
+	}
+	return contracts
+}
+
+// mustLoadLogSubscriptions reads the watched events and their ABIs or exits on failure.
+func mustLoadLogSubscriptions(path string) []logwatcher.Subscription {
+	subs, err := logwatcher.Load(path)
+	if err != nil {
+		fatal("Failed to load log subscriptions", "path", path, "error", err)
+	}
+	return subs
+}
+
+// mustInitTracer creates the transaction tracer for the configured trace method or exits on failure.
+func mustInitTracer(client *ethrpc.Client, method string) *ethrpc.Tracer {
+	tracer, err := ethrpc.NewTracer(client, ethrpc.TraceMethod(method))
+	if err != nil {
+		fatal("Failed to initialize tracer", "error", err)
+	}
+	return tracer
+}
+
+// mustCompileFilter compiles the transaction filter expression or exits on failure.
+func mustCompileFilter(source string, watchlist []string) *txexpr.Program {
+	program, err := txexpr.Compile(source, watchlist)
+	if err != nil {
+		fatal("Invalid transaction filter", "error", err)
+	}
+	return program
+}
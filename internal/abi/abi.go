@@ -0,0 +1,274 @@
+package abi
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Argument is a method or event parameter.
+type Argument struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Indexed bool   `json:"indexed,omitempty"`
+}
+
+// Method is a contract function identified by its 4-byte selector.
+type Method struct {
+	Name     string
+	Inputs   []Argument
+	Selector string // 0x-prefixed hex
+}
+
+// Event is a contract event identified by the hash of its signature (topic 0).
+type Event struct {
+	Name      string
+	Inputs    []Argument
+	Topic     string // 0x-prefixed hex
+	Anonymous bool
+}
+
+// ABI holds the functions and events of a contract.
+type ABI struct {
+	methods map[string]Method // keyed by selector
+	events  map[string]Event  // keyed by topic
+	// skipped lists, by name, the entries whose argument types cannot be decoded
+	skipped map[string][]string
+}
+
+type entry struct {
+	Type      string     `json:"type"`
+	Name      string     `json:"name"`
+	Inputs    []Argument `json:"inputs"`
+	Anonymous bool       `json:"anonymous"`
+}
+
+// Parse decodes a standard JSON ABI. Functions and events with argument types that
+// cannot be decoded, such as tuples, are skipped; looking one up reports why.
+func Parse(data []byte) (*ABI, error) {
+	var entries []entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse abi: %w", err)
+	}
+
+	a := &ABI{
+		methods: make(map[string]Method),
+		events:  make(map[string]Event),
+		skipped: make(map[string][]string),
+	}
+	for _, e := range entries {
+		kind := e.Type
+		if kind == "" {
+			kind = "function"
+		}
+		if t := unsupportedInput(e.Inputs); t != "" {
+			a.skipped[kind+" "+e.Name] = append(a.skipped[kind+" "+e.Name], t)
+			continue
+		}
+
+		hash := Keccak256([]byte(signature(e.Name, e.Inputs)))
+		switch kind {
+		case "function":
+			m := Method{Name: e.Name, Inputs: e.Inputs, Selector: "0x" + hex.EncodeToString(hash[:4])}
+			a.methods[m.Selector] = m
+		case "event":
+			ev := Event{Name: e.Name, Inputs: e.Inputs, Topic: "0x" + hex.EncodeToString(hash), Anonymous: e.Anonymous}
+			a.events[ev.Topic] = ev
+		}
+	}
+	return a, nil
+}
+
+// Load reads and parses a JSON ABI file.
+func Load(path string) (*ABI, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// MethodBySelector finds a method by its 0x-prefixed selector.
+func (a *ABI) MethodBySelector(selector string) (Method, bool) {
+	m, ok := a.methods[strings.ToLower(selector)]
+	return m, ok
+}
+
+// MethodByName finds a method by name, or by signature such as "transfer(address,uint256)"
+// when the name is overloaded.
+func (a *ABI) MethodByName(name string) (Method, error) {
+	var matches []Method
+	for _, m := range a.methods {
+		if m.Name == name || m.Signature() == canonicalSignature(name) {
+			matches = append(matches, m)
+		}
+	}
+	signatures := make([]string, len(matches))
+	for i, m := range matches {
+		signatures[i] = m.Signature()
+	}
+	if err := a.resolve("function", name, signatures); err != nil {
+		return Method{}, err
+	}
+	return matches[0], nil
+}
+
+// EventByTopic finds an event by its 0x-prefixed topic hash.
+func (a *ABI) EventByTopic(topic string) (Event, bool) {
+	ev, ok := a.events[strings.ToLower(topic)]
+	return ev, ok
+}
+
+// EventByName finds an event by name, or by signature such as
+// "Transfer(address,address,uint256)" when the name is overloaded.
+func (a *ABI) EventByName(name string) (Event, error) {
+	var matches []Event
+	for _, ev := range a.events {
+		if ev.Name == name || ev.Signature() == canonicalSignature(name) {
+			matches = append(matches, ev)
+		}
+	}
+	signatures := make([]string, len(matches))
+	for i, ev := range matches {
+		signatures[i] = ev.Signature()
+	}
+	if err := a.resolve("event", name, signatures); err != nil {
+		return Event{}, err
+	}
+	return matches[0], nil
+}
+
+// resolve checks that a lookup matched exactly one function or event. A bare name
+// also counts the overloads skipped by Parse, so it never silently picks one of them.
+func (a *ABI) resolve(kind, name string, signatures []string) error {
+	skipped := a.skipped[kind+" "+name]
+	switch {
+	case len(signatures) == 1 && len(skipped) == 0:
+		return nil
+	case len(signatures) == 0 && len(skipped) > 0:
+		return fmt.Errorf("%s %q has unsupported argument type %q", kind, name, skipped[0])
+	case len(signatures) > 0:
+		sort.Strings(signatures)
+		return fmt.Errorf("%s %q is overloaded, use one of %s", kind, name, strings.Join(signatures, ", "))
+	}
+	return fmt.Errorf("%s %q not found in ABI", kind, name)
+}
+
+// Signature returns the canonical signature, e.g. "transfer(address,uint256)".
+func (m Method) Signature() string {
+	return signature(m.Name, m.Inputs)
+}
+
+// DecodeInput decodes the arguments of a call from its full calldata, selector included.
+func (m Method) DecodeInput(calldata []byte) ([]Value, error) {
+	if len(calldata) < 4 {
+		return nil, fmt.Errorf("%s: calldata too short", m.Name)
+	}
+	return decodeTuple(m.Inputs, calldata[4:])
+}
+
+// Signature returns the canonical signature, e.g. "Transfer(address,address,uint256)".
+func (e Event) Signature() string {
+	return signature(e.Name, e.Inputs)
+}
+
+// DecodeLog decodes an event from its topics and data. Indexed arguments of dynamic
+// type are only available as their hash and are returned as raw bytes.
+func (e Event) DecodeLog(topics [][]byte, data []byte) ([]Value, error) {
+	if !e.Anonymous {
+		if len(topics) == 0 {
+			return nil, fmt.Errorf("%s: missing topics", e.Name)
+		}
+		topics = topics[1:]
+	}
+
+	var indexed, plain []Argument
+	for _, in := range e.Inputs {
+		if in.Indexed {
+			indexed = append(indexed, in)
+		} else {
+			plain = append(plain, in)
+		}
+	}
+	if len(topics) != len(indexed) {
+		return nil, fmt.Errorf("%s: expected %d indexed topics, got %d", e.Name, len(indexed), len(topics))
+	}
+
+	decoded, err := decodeTuple(plain, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", e.Name, err)
+	}
+
+	values := make([]Value, 0, len(e.Inputs))
+	var ti, di int
+	for _, in := range e.Inputs {
+		if !in.Indexed {
+			values = append(values, decoded[di])
+			di++
+			continue
+		}
+
+		topic := topics[ti]
+		ti++
+		if isDynamic(in.Type) {
+			values = append(values, Value{Name: in.Name, Type: in.Type, Value: topic})
+			continue
+		}
+		v, err := decodeStatic(in.Type, topic)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Name, err)
+		}
+		values = append(values, Value{Name: in.Name, Type: in.Type, Value: v})
+	}
+	return values, nil
+}
+
+// Keccak256 hashes data with the legacy Keccak-256 used by Ethereum.
+func Keccak256(data []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func signature(name string, inputs []Argument) string {
+	types := make([]string, len(inputs))
+	for i, in := range inputs {
+		types[i] = canonicalType(in.Type)
+	}
+	return name + "(" + strings.Join(types, ",") + ")"
+}
+
+// canonicalSignature normalizes a signature written by hand, e.g. "transfer(address, uint)".
+func canonicalSignature(sig string) string {
+	open := strings.Index(sig, "(")
+	if open < 0 || !strings.HasSuffix(sig, ")") {
+		return sig
+	}
+	var types []string
+	if params := strings.TrimSpace(sig[open+1 : len(sig)-1]); params != "" {
+		for _, t := range strings.Split(params, ",") {
+			types = append(types, canonicalType(strings.TrimSpace(t)))
+		}
+	}
+	return strings.TrimSpace(sig[:open]) + "(" + strings.Join(types, ",") + ")"
+}
+
+// canonicalType expands the uint/int aliases used in signatures.
+func canonicalType(t string) string {
+	base, suffix := t, ""
+	if i := strings.Index(t, "["); i >= 0 {
+		base, suffix = t[:i], t[i:]
+	}
+	switch base {
+	case "uint":
+		base = "uint256"
+	case "int":
+		base = "int256"
+	}
+	return base + suffix
+}
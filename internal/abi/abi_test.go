@@ -0,0 +1,192 @@
+package abi
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testABI = `[
+	{"type": "function", "name": "transferOwnership", "inputs": [{"name": "newOwner", "type": "address"}]},
+	{"type": "function", "name": "upgradeTo", "inputs": [{"name": "newImplementation", "type": "address"}]},
+	{"type": "function", "name": "transfer", "inputs": [{"name": "to", "type": "address"}, {"name": "amount", "type": "uint"}]},
+	{"type": "function", "name": "setName", "inputs": [{"name": "name", "type": "string"}, {"name": "ids", "type": "uint256[]"}, {"name": "flag", "type": "bool"}]},
+	{"type": "event", "name": "Transfer", "inputs": [
+		{"name": "from", "type": "address", "indexed": true},
+		{"name": "to", "type": "address", "indexed": true},
+		{"name": "value", "type": "uint256"}
+	]}
+]`
+
+func word(hexStr string) string {
+	return strings.Repeat("0", 64-len(hexStr)) + hexStr
+}
+
+func mustHex(t testing.TB, s string) []byte {
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	require.NoError(t, err)
+	return b
+}
+
+func TestParse_Selectors(t *testing.T) {
+	a, err := Parse([]byte(testABI))
+	require.NoError(t, err)
+
+	m, err := a.MethodByName("transferOwnership")
+	require.NoError(t, err)
+	assert.Equal(t, "0xf2fde38b", m.Selector)
+
+	m, ok := a.MethodBySelector("0x3659CFE6")
+	require.True(t, ok)
+	assert.Equal(t, "upgradeTo", m.Name)
+
+	m, _ = a.MethodByName("transfer")
+	assert.Equal(t, "transfer(address,uint256)", m.Signature())
+	assert.Equal(t, "0xa9059cbb", m.Selector)
+
+	ev, err := a.EventByName("Transfer")
+	require.NoError(t, err)
+	assert.Equal(t, "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef", ev.Topic)
+}
+
+func TestParse_SkipsUnsupportedTypes(t *testing.T) {
+	a, err := Parse([]byte(`[
+		{"type": "function", "name": "f", "inputs": [{"name": "t", "type": "tuple"}]},
+		{"type": "function", "name": "pause", "inputs": []}
+	]`))
+	require.NoError(t, err)
+
+	_, err = a.MethodByName("pause")
+	assert.NoError(t, err)
+	_, err = a.MethodByName("f")
+	assert.EqualError(t, err, `function "f" has unsupported argument type "tuple"`)
+	_, err = a.MethodByName("g")
+	assert.EqualError(t, err, `function "g" not found in ABI`)
+}
+
+func TestParse_Overloads(t *testing.T) {
+	a, err := Parse([]byte(`[
+		{"type": "function", "name": "safeTransferFrom", "inputs": [
+			{"name": "from", "type": "address"}, {"name": "to", "type": "address"}, {"name": "id", "type": "uint256"}
+		]},
+		{"type": "function", "name": "safeTransferFrom", "inputs": [
+			{"name": "from", "type": "address"}, {"name": "to", "type": "address"}, {"name": "id", "type": "uint256"},
+			{"name": "data", "type": "bytes"}
+		]},
+		{"type": "function", "name": "mint", "inputs": [{"name": "to", "type": "address"}]},
+		{"type": "function", "name": "mint", "inputs": [{"name": "p", "type": "tuple"}]},
+		{"type": "event", "name": "Log", "inputs": [{"name": "a", "type": "uint"}]},
+		{"type": "event", "name": "Log", "inputs": [{"name": "a", "type": "string"}]}
+	]`))
+	require.NoError(t, err)
+
+	_, err = a.MethodByName("safeTransferFrom")
+	assert.EqualError(t, err, `function "safeTransferFrom" is overloaded, use one of `+
+		`safeTransferFrom(address,address,uint256), safeTransferFrom(address,address,uint256,bytes)`)
+	m, err := a.MethodByName("safeTransferFrom(address, address, uint)")
+	require.NoError(t, err)
+	assert.Equal(t, "0x42842e0e", m.Selector)
+	m, err = a.MethodByName("safeTransferFrom(address,address,uint256,bytes)")
+	require.NoError(t, err)
+	assert.Equal(t, "0xb88d4fde", m.Selector)
+
+	_, err = a.MethodByName("mint")
+	assert.ErrorContains(t, err, "is overloaded", "an unsupported overload still makes the name ambiguous")
+	_, err = a.MethodByName("mint(address)")
+	assert.NoError(t, err)
+
+	_, err = a.EventByName("Log")
+	assert.EqualError(t, err, `event "Log" is overloaded, use one of Log(string), Log(uint256)`)
+	ev, err := a.EventByName("Log(string)")
+	require.NoError(t, err)
+	assert.Equal(t, "string", ev.Inputs[0].Type)
+}
+
+func TestMethod_DecodeInput(t *testing.T) {
+	a, err := Parse([]byte(testABI))
+	require.NoError(t, err)
+
+	m, _ := a.MethodByName("transferOwnership")
+	values, err := m.DecodeInput(mustHex(t, m.Selector+word("00000000000000000000000000000000000000ab")))
+	require.NoError(t, err)
+	require.Len(t, values, 1)
+	assert.Equal(t, "newOwner=0x00000000000000000000000000000000000000ab", values[0].String())
+}
+
+func TestMethod_DecodeInput_Dynamic(t *testing.T) {
+	a, err := Parse([]byte(testABI))
+	require.NoError(t, err)
+	m, _ := a.MethodByName("setName")
+
+	calldata := m.Selector +
+		word("60") + // offset of name
+		word("a0") + // offset of ids
+		word("1") + // flag
+		word("5") + "68656c6c6f" + strings.Repeat("0", 54) + // "hello"
+		word("2") + word("7") + word("9") // ids
+
+	values, err := m.DecodeInput(mustHex(t, calldata))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", values[0].Value)
+	assert.Equal(t, []any{big.NewInt(7), big.NewInt(9)}, values[1].Value)
+	assert.Equal(t, true, values[2].Value)
+	assert.Equal(t, `name="hello"`, values[0].String())
+	assert.Equal(t, "ids=[7, 9]", values[1].String())
+}
+
+func TestMethod_DecodeInput_Truncated(t *testing.T) {
+	a, err := Parse([]byte(testABI))
+	require.NoError(t, err)
+	m, _ := a.MethodByName("transfer")
+
+	_, err = m.DecodeInput(mustHex(t, m.Selector+word("ab")))
+	assert.Error(t, err)
+}
+
+func TestEvent_DecodeLog(t *testing.T) {
+	a, err := Parse([]byte(testABI))
+	require.NoError(t, err)
+	ev, _ := a.EventByName("Transfer")
+
+	topics := [][]byte{
+		mustHex(t, ev.Topic),
+		mustHex(t, word("aa")),
+		mustHex(t, word("bb")),
+	}
+	values, err := ev.DecodeLog(topics, mustHex(t, word("de0b6b3a7640000")))
+	require.NoError(t, err)
+	require.Len(t, values, 3)
+	assert.Equal(t, "0x00000000000000000000000000000000000000aa", values[0].Value)
+	assert.Equal(t, "0x00000000000000000000000000000000000000bb", values[1].Value)
+	assert.Equal(t, "1000000000000000000", values[2].Value.(*big.Int).String())
+
+	_, err = ev.DecodeLog(topics[:2], nil)
+	assert.Error(t, err)
+}
+
+func TestEncodeTopic(t *testing.T) {
+	topic, err := EncodeTopic("address", "0x00000000000000000000000000000000000000AB")
+	require.NoError(t, err)
+	assert.Equal(t, "0x"+word("ab"), topic)
+
+	topic, err = EncodeTopic("uint256", "255")
+	require.NoError(t, err)
+	assert.Equal(t, "0x"+word("ff"), topic)
+
+	topic, err = EncodeTopic("bool", "true")
+	require.NoError(t, err)
+	assert.Equal(t, "0x"+word("1"), topic)
+
+	_, err = EncodeTopic("address", "0x1234")
+	assert.Error(t, err)
+
+	_, err = EncodeTopic("string", "hello")
+	assert.Error(t, err)
+
+	_, err = EncodeTopic("uint7", "1")
+	assert.Error(t, err)
+}
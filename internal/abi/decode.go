@@ -0,0 +1,303 @@
+package abi
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const wordSize = 32
+
+// Value is a decoded argument. Value holds a string for addresses and strings,
+// *big.Int for integers, bool, []byte for bytes and []any for arrays.
+type Value struct {
+	Name  string
+	Type  string
+	Value any
+}
+
+// String renders the value for humans, e.g. "newOwner=0xabc...".
+func (v Value) String() string {
+	if v.Name == "" {
+		return formatValue(v.Value)
+	}
+	return v.Name + "=" + formatValue(v.Value)
+}
+
+func formatValue(v any) string {
+	switch val := v.(type) {
+	case []byte:
+		return "0x" + hex.EncodeToString(val)
+	case string:
+		if strings.HasPrefix(val, "0x") {
+			return val
+		}
+		return strconv.Quote(val)
+	case []any:
+		parts := make([]string, len(val))
+		for i, item := range val {
+			parts[i] = formatValue(item)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// supported reports whether a type can be decoded: elementary types and arrays of them.
+func supported(t string) bool {
+	if elem, _, ok := arrayType(t); ok {
+		return supported(elem)
+	}
+	base := canonicalType(t)
+	switch {
+	case base == "address", base == "bool", base == "string", base == "bytes":
+		return true
+	case strings.HasPrefix(base, "uint"):
+		return validIntSize(strings.TrimPrefix(base, "uint"))
+	case strings.HasPrefix(base, "int"):
+		return validIntSize(strings.TrimPrefix(base, "int"))
+	case strings.HasPrefix(base, "bytes"):
+		n, ok := typeSize(strings.TrimPrefix(base, "bytes"))
+		return ok && n >= 1 && n <= 32
+	}
+	return false
+}
+
+// validIntSize reports whether bits is an integer size from 8 to 256 in steps of 8.
+func validIntSize(bits string) bool {
+	n, ok := typeSize(bits)
+	return ok && n >= 8 && n <= 256 && n%8 == 0
+}
+
+// typeSize parses the size that ends a type name such as uint256 or bytes32, rejecting
+// signs and leading zeros.
+func typeSize(s string) (int, bool) {
+	n, err := strconv.Atoi(s)
+	return n, err == nil && strconv.Itoa(n) == s
+}
+
+// unsupportedInput returns the first argument type that cannot be decoded, if any.
+func unsupportedInput(inputs []Argument) string {
+	for _, in := range inputs {
+		if !supported(in.Type) {
+			return in.Type
+		}
+	}
+	return ""
+}
+
+// arrayType splits "T[]" or "T[k]" into the element type and length (-1 for dynamic).
+func arrayType(t string) (string, int, bool) {
+	if !strings.HasSuffix(t, "]") {
+		return "", 0, false
+	}
+	i := strings.LastIndex(t, "[")
+	if i < 0 {
+		return "", 0, false
+	}
+	size := t[i+1 : len(t)-1]
+	if size == "" {
+		return t[:i], -1, true
+	}
+	n, err := strconv.Atoi(size)
+	if err != nil {
+		return "", 0, false
+	}
+	return t[:i], n, true
+}
+
+func isDynamic(t string) bool {
+	if elem, n, ok := arrayType(t); ok {
+		return n < 0 || isDynamic(elem)
+	}
+	return t == "string" || t == "bytes"
+}
+
+// headSize is the number of bytes a type occupies in the head of a tuple.
+func headSize(t string) int {
+	if elem, n, ok := arrayType(t); ok && n >= 0 && !isDynamic(elem) {
+		return n * headSize(elem)
+	}
+	return wordSize
+}
+
+func decodeTuple(args []Argument, data []byte) ([]Value, error) {
+	values := make([]Value, len(args))
+	offset := 0
+	for i, arg := range args {
+		v, err := decodeAt(arg.Type, data, offset)
+		if err != nil {
+			return nil, fmt.Errorf("argument %q: %w", arg.Name, err)
+		}
+		values[i] = Value{Name: arg.Name, Type: arg.Type, Value: v}
+		offset += headSize(arg.Type)
+	}
+	return values, nil
+}
+
+// decodeAt decodes the value whose head starts at offset within data.
+func decodeAt(t string, data []byte, offset int) (any, error) {
+	if isDynamic(t) {
+		ptr, err := readWord(data, offset)
+		if err != nil {
+			return nil, err
+		}
+		start, err := toInt(ptr)
+		if err != nil {
+			return nil, err
+		}
+		return decodeDynamic(t, data, start)
+	}
+
+	if elem, n, ok := arrayType(t); ok {
+		return decodeArray(elem, n, data, offset)
+	}
+
+	word, err := readWord(data, offset)
+	if err != nil {
+		return nil, err
+	}
+	return decodeStatic(t, word)
+}
+
+func decodeDynamic(t string, data []byte, start int) (any, error) {
+	if start > len(data) {
+		return nil, fmt.Errorf("%s offset %d out of bounds", t, start)
+	}
+	if elem, n, ok := arrayType(t); ok {
+		if n < 0 {
+			length, err := readWord(data, start)
+			if err != nil {
+				return nil, err
+			}
+			if n, err = toInt(length); err != nil {
+				return nil, err
+			}
+			start += wordSize
+		}
+		// Elements of a dynamic array are encoded as a tuple starting after the length
+		return decodeArray(elem, n, data[start:], 0)
+	}
+
+	length, err := readWord(data, start)
+	if err != nil {
+		return nil, err
+	}
+	n, err := toInt(length)
+	if err != nil {
+		return nil, err
+	}
+	start += wordSize
+	if start+n > len(data) {
+		return nil, fmt.Errorf("%s out of bounds", t)
+	}
+
+	raw := append([]byte(nil), data[start:start+n]...)
+	if t == "string" {
+		return string(raw), nil
+	}
+	return raw, nil
+}
+
+func decodeArray(elem string, n int, data []byte, offset int) (any, error) {
+	if n > len(data)/wordSize+1 {
+		return nil, fmt.Errorf("array length %d out of bounds", n)
+	}
+	items := make([]any, n)
+	for i := range items {
+		v, err := decodeAt(elem, data, offset)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = v
+		offset += headSize(elem)
+	}
+	return items, nil
+}
+
+func decodeStatic(t string, word []byte) (any, error) {
+	if len(word) != wordSize {
+		return nil, fmt.Errorf("%s: invalid word length %d", t, len(word))
+	}
+
+	base := canonicalType(t)
+	switch {
+	case base == "address":
+		return "0x" + hex.EncodeToString(word[12:]), nil
+	case base == "bool":
+		return word[wordSize-1] == 1, nil
+	case strings.HasPrefix(base, "uint"):
+		return new(big.Int).SetBytes(word), nil
+	case strings.HasPrefix(base, "int"):
+		v := new(big.Int).SetBytes(word)
+		if word[0]&0x80 != 0 {
+			v.Sub(v, new(big.Int).Lsh(big.NewInt(1), 256))
+		}
+		return v, nil
+	case strings.HasPrefix(base, "bytes"):
+		n, _ := strconv.Atoi(strings.TrimPrefix(base, "bytes"))
+		return append([]byte(nil), word[:n]...), nil
+	}
+	return nil, fmt.Errorf("unsupported type %q", t)
+}
+
+func readWord(data []byte, offset int) ([]byte, error) {
+	if offset < 0 || offset+wordSize > len(data) {
+		return nil, fmt.Errorf("read at %d out of bounds", offset)
+	}
+	return data[offset : offset+wordSize], nil
+}
+
+func toInt(word []byte) (int, error) {
+	v := new(big.Int).SetBytes(word)
+	if !v.IsInt64() || v.Int64() > 1<<32 {
+		return 0, fmt.Errorf("offset or length %s too large", v)
+	}
+	return int(v.Int64()), nil
+}
+
+// EncodeTopic encodes a value of an elementary static type as a 32-byte log topic,
+// for filtering logs by indexed arguments. Integers may be decimal or 0x-prefixed hex.
+func EncodeTopic(t, value string) (string, error) {
+	word := make([]byte, wordSize)
+	base := canonicalType(t)
+
+	switch {
+	case base == "address":
+		raw, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(value), "0x"))
+		if err != nil || len(raw) != 20 {
+			return "", fmt.Errorf("invalid address %q", value)
+		}
+		copy(word[12:], raw)
+	case base == "bool":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid bool %q", value)
+		}
+		if b {
+			word[wordSize-1] = 1
+		}
+	case (strings.HasPrefix(base, "uint") || strings.HasPrefix(base, "int")) && supported(base):
+		n, ok := new(big.Int).SetString(value, 0)
+		if !ok {
+			return "", fmt.Errorf("invalid integer %q", value)
+		}
+		if n.Sign() < 0 {
+			n.Add(n, new(big.Int).Lsh(big.NewInt(1), 256))
+		}
+		n.FillBytes(word)
+	case strings.HasPrefix(base, "bytes") && supported(base) && base != "bytes":
+		raw, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(value), "0x"))
+		if err != nil || len(raw) > wordSize {
+			return "", fmt.Errorf("invalid %s %q", t, value)
+		}
+		copy(word, raw)
+	default:
+		return "", fmt.Errorf("cannot filter on %s arguments", t)
+	}
+
+	return "0x" + hex.EncodeToString(word), nil
+}
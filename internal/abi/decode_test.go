@@ -0,0 +1,73 @@
+package abi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const malformedABI = `[
+	{"type": "function", "name": "text", "inputs": [{"name": "s", "type": "string"}]},
+	{"type": "function", "name": "list", "inputs": [{"name": "a", "type": "uint256[]"}]},
+	{"type": "function", "name": "pair", "inputs": [{"name": "p", "type": "string[2]"}]},
+	{"type": "function", "name": "nested", "inputs": [{"name": "n", "type": "uint256[][]"}]},
+	{"type": "function", "name": "blobs", "inputs": [{"name": "b", "type": "bytes[]"}, {"name": "f", "type": "bytes4[2]"}]}
+]`
+
+func TestMethod_DecodeInput_Malformed(t *testing.T) {
+	a, err := Parse([]byte(malformedABI))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		method   string
+		calldata string
+	}{
+		{"string offset past end", "text", word("ffff")},
+		{"string offset too large", "text", strings.Repeat("ff", wordSize)},
+		{"string length past end", "text", word("20") + word("ff")},
+		{"array offset past end", "list", word("ffff")},
+		{"array length past end", "list", word("20") + word("ffff")},
+		{"fixed array offset past end", "pair", word("ffff")},
+		{"fixed array offset at end", "pair", word("20")},
+		{"fixed array element offset past end", "pair", word("20") + word("ffff") + word("0")},
+		{"nested array offset past end", "nested", word("20") + word("1") + word("ffff")},
+		{"nested array length past end", "nested", word("20") + word("1") + word("20") + word("ffff")},
+		{"bytes element offset past end", "blobs", word("60") + word("0") + word("0") + word("1") + word("ffff")},
+		{"static tail truncated", "blobs", word("60")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := a.MethodByName(tt.method)
+			require.NoError(t, err)
+			_, err = m.DecodeInput(mustHex(t, m.Selector+tt.calldata))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSupported(t *testing.T) {
+	for _, typ := range []string{"uint", "int", "uint8", "int256", "uint64[]", "bytes32", "bytes", "address[2]"} {
+		assert.True(t, supported(typ), typ)
+	}
+	for _, typ := range []string{"tintin8", "intint8", "unit8", "uint7", "uint0", "int264", "uint+8", "uint08", "bytes33", "bytes+8", "tuple"} {
+		assert.False(t, supported(typ), typ)
+	}
+}
+
+func FuzzMethod_DecodeInput(f *testing.F) {
+	a, err := Parse([]byte(malformedABI))
+	require.NoError(f, err)
+
+	f.Add(mustHex(f, word("20")+word("ffff")+word("0")))
+	f.Add(mustHex(f, word("20")+word("1")+word("20")+word("2")+word("7")+word("9")))
+	f.Add(mustHex(f, word("40")+word("80")+word("5")+"68656c6c6f"+strings.Repeat("0", 54)))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, name := range []string{"text", "list", "pair", "nested", "blobs"} {
+			m, _ := a.MethodByName(name)
+			_, _ = m.DecodeInput(append(mustHex(t, m.Selector), data...))
+		}
+	})
+}
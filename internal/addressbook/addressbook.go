@@ -0,0 +1,94 @@
+package addressbook
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// wildcard in an allow list matches every counterparty.
+const wildcard = "*"
+
+// Entry describes a known address and, for monitored wallets, which counterparties are expected.
+type Entry struct {
+	Label string `json:"label,omitempty"`
+	// Allow lists counterparties whose transfers are expected and excluded from alerting volume.
+	Allow []string `json:"allow,omitempty"`
+	// Deny lists counterparties that are always counted, even when matched by Allow.
+	Deny []string `json:"deny,omitempty"`
+}
+
+// Book maps addresses to their entries. A nil Book is empty.
+type Book struct {
+	entries map[string]entry
+}
+
+type entry struct {
+	label string
+	allow map[string]struct{}
+	deny  map[string]struct{}
+}
+
+// New builds an address book from entries keyed by address.
+func New(entries map[string]Entry) *Book {
+	b := &Book{entries: make(map[string]entry, len(entries))}
+	for addr, e := range entries {
+		b.entries[strings.ToLower(addr)] = entry{
+			label: e.Label,
+			allow: toSet(e.Allow),
+			deny:  toSet(e.Deny),
+		}
+	}
+	return b
+}
+
+// Load reads an address book from a JSON object keyed by address.
+func Load(path string) (*Book, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries map[string]Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse address book: %w", err)
+	}
+	return New(entries), nil
+}
+
+// Label returns the label of an address, or an empty string if it is unknown.
+func (b *Book) Label(addr string) string {
+	if b == nil {
+		return ""
+	}
+	return b.entries[strings.ToLower(addr)].label
+}
+
+// Excluded reports whether transfers between wallet and counterparty are expected
+// and should not count towards the wallet's alerting volume.
+func (b *Book) Excluded(wallet, counterparty string) bool {
+	if b == nil {
+		return false
+	}
+	e, ok := b.entries[strings.ToLower(wallet)]
+	if !ok {
+		return false
+	}
+
+	counterparty = strings.ToLower(counterparty)
+	if _, ok := e.deny[counterparty]; ok {
+		return false
+	}
+	_, all := e.allow[wildcard]
+	_, listed := e.allow[counterparty]
+	return all || listed
+}
+
+func toSet(addresses []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(addresses))
+	for _, addr := range addresses {
+		set[strings.ToLower(strings.TrimSpace(addr))] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,43 @@
+package addressbook
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "addressbook.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"0xHOT": {"label": "Hot wallet", "allow": ["0xCOLD"]},
+		"0xcold": {"label": "Cold wallet"}
+	}`), 0o600))
+
+	book, err := Load(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Hot wallet", book.Label("0xhot"))
+	assert.Equal(t, "Cold wallet", book.Label("0xCOLD"))
+	assert.Equal(t, "", book.Label("0xunknown"))
+	assert.True(t, book.Excluded("0xhot", "0xcold"))
+	assert.False(t, book.Excluded("0xhot", "0xother"))
+	assert.False(t, book.Excluded("0xcold", "0xhot"), "exclusions are per wallet")
+}
+
+func TestExcluded_WildcardWithDeny(t *testing.T) {
+	book := New(map[string]Entry{
+		"0xhot": {Allow: []string{"*"}, Deny: []string{"0xattacker"}},
+	})
+
+	assert.True(t, book.Excluded("0xhot", "0xanyone"))
+	assert.False(t, book.Excluded("0xhot", "0xATTACKER"))
+}
+
+func TestNilBook(t *testing.T) {
+	var book *Book
+	assert.Equal(t, "", book.Label("0xhot"))
+	assert.False(t, book.Excluded("0xhot", "0xcold"))
+}
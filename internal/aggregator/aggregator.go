@@ -2,14 +2,25 @@ package aggregator
 
 import (
 	"context"
-	"log"
-	"math/big"
+	"fmt"
+	"log/slog"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/yermakovsa/alchemyws"
+	"github.com/yermakovsa/eth-watcher/internal/addressbook"
+	"github.com/yermakovsa/eth-watcher/internal/contract"
+	"github.com/yermakovsa/eth-watcher/internal/ethrpc"
+	"github.com/yermakovsa/eth-watcher/internal/logging"
+	"github.com/yermakovsa/eth-watcher/internal/logwatcher"
+	"github.com/yermakovsa/eth-watcher/internal/metrics"
 	"github.com/yermakovsa/eth-watcher/internal/notifier"
+	"github.com/yermakovsa/eth-watcher/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Direction string
@@ -17,113 +28,839 @@ type Direction string
 const (
 	From Direction = "from"
 	To   Direction = "to"
+	Net  Direction = "net"
 )
 
 type TxRecord struct {
+	Hash      string
 	Amount    float64
+	USD       float64 // value at the ETH price when the transaction was observed
 	Timestamp time.Time
 }
 
+// BlockTimeSource resolves the timestamp of a block by its number.
+type BlockTimeSource interface {
+	BlockTimestamp(ctx context.Context, number uint64) (time.Time, error)
+}
+
+// Option configures optional Aggregator behaviour.
+type Option func(*Aggregator)
+
+// WithBlockTimes timestamps records by the block they were mined in instead of arrival time.
+func WithBlockTimes(src BlockTimeSource) Option {
+	return func(a *Aggregator) {
+		a.blockTimes = src
+	}
+}
+
+// WithClock overrides the clock used for cooldowns and as a fallback record timestamp.
+func WithClock(now func() time.Time) Option {
+	return func(a *Aggregator) {
+		a.now = now
+	}
+}
+
+// WithNetFlow enables the net direction for the given wallets. Inflows and outflows are
+// summed as signed amounts and an alert fires when the absolute net change reaches threshold.
+func WithNetFlow(wallets []string, threshold float64) Option {
+	return func(a *Aggregator) {
+		for _, w := range wallets {
+			a.netWallets[strings.ToLower(w)] = struct{}{}
+		}
+		t := a.thresholds[Net]
+		t.ETH = threshold
+		a.thresholds[Net] = t
+	}
+}
+
+// WithRules evaluates the given rules on every processed transaction in addition to the threshold.
+func WithRules(rules []Rule) Option {
+	return func(a *Aggregator) {
+		a.rules = rules
+	}
+}
+
+// WithCounterpartyWarmUp learns the counterparties of every wallet for d after startup
+// before new_counterparty conditions can match, so a restart does not report every
+// known counterparty as new.
+func WithCounterpartyWarmUp(d time.Duration) Option {
+	return func(a *Aggregator) {
+		a.counterpartyWarmUp = d
+	}
+}
+
+// WithAddressBook excludes expected transfers between wallets and their allowed counterparties
+// from alerting volume and labels wallets in alerts.
+func WithAddressBook(book *addressbook.Book) Option {
+	return func(a *Aggregator) {
+		a.book = book
+	}
+}
+
+// SanctionsList looks up flagged addresses, returning the reason they were listed.
+type SanctionsList interface {
+	Lookup(addr string) (string, bool)
+}
+
+// WithSanctions alerts on every interaction between a monitored wallet and a flagged address,
+// regardless of amount.
+func WithSanctions(list SanctionsList) Option {
+	return func(a *Aggregator) {
+		a.sanctions = list
+	}
+}
+
+// BalanceObserver is notified of wallets whose balance may have changed.
+type BalanceObserver interface {
+	Observe(ctx context.Context, wallet string)
+}
+
+// WithBalances asks the observer to re-check a wallet's balance after each of its transactions.
+func WithBalances(observer BalanceObserver) Option {
+	return func(a *Aggregator) {
+		a.balances = observer
+	}
+}
+
+// WithMetrics records alert and window metrics.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(a *Aggregator) {
+		a.metrics = m
+	}
+}
+
+// WithLogger sets the logger used instead of the default one.
+func WithLogger(logger *slog.Logger) Option {
+	return func(a *Aggregator) {
+		a.logger = logger
+	}
+}
+
+// WithTracerProvider records spans with tp instead of the global tracer provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(a *Aggregator) {
+		a.tracerProvider = tp
+	}
+}
+
 // Aggregator monitors wallet activity and triggers alerts when volume exceeds threshold.
 type Aggregator struct {
-	mu        sync.Mutex
-	data      map[Direction]map[string][]TxRecord
-	alerted   map[Direction]map[string]time.Time
-	threshold float64
-	window    time.Duration
-	cooldown  time.Duration
-	notifier  notifier.Notifier
-	ctx       context.Context
+	mu       sync.Mutex
+	data     map[Direction]map[string][]TxRecord
+	excluded map[Direction]map[string][]TxRecord
+	// logData and logAlerted hold aggregated contract events by spec name, then wallet.
+	// Wallets are forgotten once idle, see pruneLogs.
+	logData    map[string]map[string][]TxRecord
+	logAlerted map[string]map[string]time.Time
+	logPruned  time.Time
+	alerted    map[Direction]map[string]time.Time
+	thresholds map[Direction]Thresholds
+	// infoFactor and criticalFactor scale the thresholds into the info and critical tiers
+	infoFactor     float64
+	criticalFactor float64
+	// severities holds the severity of the last graded alert, keyed by scope and wallet
+	severities map[string]map[string]notifier.Severity
+	// walletThresholds replace the thresholds of every direction for individual wallets, in ETH
+	walletThresholds map[string]float64
+	netWallets       map[string]struct{}
+	window           time.Duration
+	cooldown         time.Duration
+	rules            []Rule
+	book             *addressbook.Book
+	sanctions        SanctionsList
+	balances         BalanceObserver
+	ruleAlerted      map[string]map[string]time.Time
+	receipts         ReceiptSource
+	// checkStatus leaves reverted transactions out of the volume, see WithReceipts
+	checkStatus bool
+	// feeData and feeAlerted track gas fees paid by each wallet over feeWindow
+	feeData      map[string][]TxRecord
+	feeAlerted   map[string]time.Time
+	feeThreshold float64
+	feeWindow    time.Duration
+	// failedData and failedAlerted track reverted transactions sent by each wallet over failedWindow
+	failedData    map[string][]TxRecord
+	failedAlerted map[string]time.Time
+	failedCount   int
+	failedWindow  time.Duration
+	// prices values records in USD, enabling the USD thresholds
+	prices PriceFeed
+	// counterparties holds when each wallet last transacted with each counterparty. They are
+	// only learned while a rule matches new counterparties, and within counterpartyWarmUp of
+	// started they are recorded without being reported as new.
+	counterparties      map[string]map[string]time.Time
+	trackCounterparties bool
+	counterpartyWarmUp  time.Duration
+	started             time.Time
+	notifier            notifier.Notifier
+	metrics             *metrics.Metrics
+	logger              *slog.Logger
+	tracerProvider      trace.TracerProvider
+	tracer              trace.Tracer
+	blockTimes          BlockTimeSource
+	now                 func() time.Time
+	ctx                 context.Context
 }
 
 // NewAggregator initializes an Aggregator.
-func NewAggregator(ctx context.Context, notifier notifier.Notifier, threshold float64, window time.Duration, cooldown time.Duration) *Aggregator {
-	return &Aggregator{
+func NewAggregator(ctx context.Context, notifier notifier.Notifier, threshold float64, window time.Duration, cooldown time.Duration, opts ...Option) *Aggregator {
+	a := &Aggregator{
 		data: map[Direction]map[string][]TxRecord{
 			From: make(map[string][]TxRecord),
 			To:   make(map[string][]TxRecord),
+			Net:  make(map[string][]TxRecord),
+		},
+		excluded: map[Direction]map[string][]TxRecord{
+			From: make(map[string][]TxRecord),
+			To:   make(map[string][]TxRecord),
+			Net:  make(map[string][]TxRecord),
 		},
 		alerted: map[Direction]map[string]time.Time{
 			From: make(map[string]time.Time),
 			To:   make(map[string]time.Time),
+			Net:  make(map[string]time.Time),
+		},
+		thresholds: map[Direction]Thresholds{
+			From: {ETH: threshold},
+			To:   {ETH: threshold},
+			Net:  {},
 		},
-		threshold: threshold,
-		window:    window,
-		cooldown:  cooldown,
-		notifier:  notifier,
-		ctx:       ctx,
+		walletThresholds: make(map[string]float64),
+		netWallets:       make(map[string]struct{}),
+		window:           window,
+		cooldown:         cooldown,
+		ruleAlerted:      make(map[string]map[string]time.Time),
+		logData:          make(map[string]map[string][]TxRecord),
+		logAlerted:       make(map[string]map[string]time.Time),
+		feeData:          make(map[string][]TxRecord),
+		feeAlerted:       make(map[string]time.Time),
+		failedData:       make(map[string][]TxRecord),
+		failedAlerted:    make(map[string]time.Time),
+		counterparties:   make(map[string]map[string]time.Time),
+		notifier:         notifier,
+		now:              time.Now,
+		ctx:              ctx,
 	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	a.started = a.now()
+	a.trackCounterparties = needsCounterparties(a.rules)
+	a.logger = logging.Component(a.logger, "aggregator")
+	a.tracer = tracing.Tracer(a.tracerProvider, "aggregator")
+	return a
 }
 
 // Process adds a transaction to the aggregation buffer and triggers alert if needed.
-func (a *Aggregator) Process(tx alchemyws.MinedTxEvent, direction Direction) {
+// Alerts are sent with ctx, carrying its trace to the notifier.
+func (a *Aggregator) Process(ctx context.Context, tx alchemyws.MinedTxEvent, direction Direction) {
+	a.process(ctx, tx, direction, false)
+}
+
+// ProcessInternal aggregates a value transfer made by a contract during tx, found by tracing.
+// The transaction's From, To and Value describe the internal transfer rather than the outer call.
+func (a *Aggregator) ProcessInternal(ctx context.Context, tx alchemyws.MinedTxEvent, direction Direction) {
+	a.process(ctx, tx, direction, true)
+}
+
+func (a *Aggregator) process(ctx context.Context, tx alchemyws.MinedTxEvent, direction Direction, internal bool) {
+	ctx, span := a.tracer.Start(ctx, "aggregator.process", trace.WithAttributes(
+		attribute.String("tx_hash", tx.Transaction.Hash),
+		attribute.String("direction", string(direction)),
+		attribute.Bool("internal", internal),
+	))
+	defer span.End()
+
+	flows := a.flows(tx, direction)
+	if len(flows) == 0 {
+		return
+	}
+	if a.balances != nil {
+		for _, f := range flows {
+			a.balances.Observe(ctx, f.wallet)
+		}
+	}
+
+	// Resolve the block timestamp, price and receipt before locking, they may require a network round trip
+	timestamp := a.timestamp(ctx, tx.Transaction.BlockNumber)
+	amount := a.value(tx)
+	usd := amount * a.rate(ctx)
+
+	// Internal transfers come from traces, which already leave out reverted calls
+	var receipt *ethrpc.Receipt
+	if a.receipts != nil && !internal {
+		receipt = a.receipt(ctx, tx)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, f := range flows {
+		a.checkSanctions(ctx, tx, direction, f, amount, usd, timestamp, internal)
+
+		if receipt != nil {
+			if outgoing(direction, f) {
+				a.trackFee(ctx, tx, f.wallet, receipt, timestamp)
+				if a.checkStatus {
+					a.trackFailure(ctx, tx, f.wallet, receipt, timestamp)
+				}
+			}
+			if a.checkStatus && receipt.Failed() {
+				a.logger.Info("Excluding failed transaction from volume", "tx_hash", tx.Transaction.Hash, "wallet", f.wallet, "direction", direction)
+				continue
+			}
+		}
+
+		if a.book.Excluded(f.wallet, f.counterparty) {
+			a.logger.Info("Excluding expected transfer", "tx_hash", tx.Transaction.Hash, "wallet", f.wallet, "direction", direction, "counterparty", f.counterparty)
+			a.excluded[direction][f.wallet], _ = insertRecord(a.excluded[direction][f.wallet], TxRecord{
+				Amount:    amount,
+				USD:       usd,
+				Timestamp: timestamp,
+			}, a.window)
+			continue
+		}
+
+		// Two windows are retained so rules can compare against the previous one
+		records, ok := insertRecord(a.data[direction][f.wallet], TxRecord{
+			Hash:      tx.Transaction.Hash,
+			Amount:    f.sign * amount,
+			USD:       f.sign * usd,
+			Timestamp: timestamp,
+		}, 2*a.window)
+		a.data[direction][f.wallet] = records
+		a.reportWindow(direction)
+		if !ok {
+			a.logger.Warn("Dropping late transaction outside of the aggregation window", "tx_hash", tx.Transaction.Hash, "wallet", f.wallet, "direction", direction)
+			continue
+		}
+
+		stats := windowStats(records, a.window)
+		stats.Tx = tx.Transaction
+		stats.Direction = direction
+		stats.Wallet = f.wallet
+		stats.TxValue = amount
+		stats.NewCounterparty = a.observeCounterparty(f.wallet, f.counterparty)
+
+		a.evaluate(ctx, tx, direction, f.wallet, stats, timestamp, internal)
+		a.evaluateRules(ctx, tx, direction, f.wallet, stats, timestamp, internal)
+	}
+}
+
+// ProcessCall alerts on a call to a watched contract function, including the decoded arguments.
+func (a *Aggregator) ProcessCall(ctx context.Context, tx alchemyws.MinedTxEvent, call contract.Call) {
+	ctx, span := a.tracer.Start(ctx, "aggregator.process_call", trace.WithAttributes(
+		attribute.String("tx_hash", tx.Transaction.Hash),
+		attribute.String("contract", call.Contract),
+		attribute.String("method", call.Method),
+	))
+	defer span.End()
+
+	label := call.Label
+	if label == "" {
+		label = a.book.Label(call.Contract)
+	}
+
+	fields := []notifier.Field{
+		{Name: "Caller", Value: strings.ToLower(tx.Transaction.From)},
+		{Name: "Call", Value: call.String()},
+	}
+	if value := a.value(tx); value > 0 {
+		fields = append(fields, notifier.Field{Name: "Value", Value: fmt.Sprintf("%.4f ETH", value)})
+	}
+
+	a.notify(ctx, notifier.Alert{
+		Type:   notifier.AlertContractCall,
+		Title:  "Contract Call Detected",
+		Wallet: call.Contract,
+		Label:  label,
+		TxID:   tx.Transaction.Hash,
+		Fields: fields,
+	})
+}
+
+// ProcessLog alerts on a decoded contract event. Events with an amount are aggregated
+// per wallet and alert once the window total reaches the spec's threshold.
+func (a *Aggregator) ProcessLog(ev logwatcher.Event) {
+	ctx, span := a.tracer.Start(a.ctx, "aggregator.process_log", trace.WithAttributes(
+		attribute.String("tx_hash", ev.TxHash),
+		attribute.String("event", ev.Name),
+	))
+	defer span.End()
+
+	if !ev.Aggregated {
+		label := ev.Label
+		if label == "" {
+			label = a.book.Label(ev.Contract)
+		}
+		a.notify(ctx, notifier.Alert{
+			Type:   notifier.AlertLogEvent,
+			Title:  "Contract Event Detected",
+			Wallet: ev.Contract,
+			Label:  label,
+			TxID:   ev.TxHash,
+			Fields: []notifier.Field{
+				{Name: "Event", Value: ev.String()},
+			},
+		})
+		return
+	}
+
+	timestamp := a.timestamp(ctx, ev.BlockNumber)
+
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	now := time.Now()
-	amount := ParseValue(tx.Transaction.Value)
-	var wallet string
+	a.pruneLogs(timestamp)
+	wallets := a.logData[ev.Name]
+	if wallets == nil {
+		wallets = make(map[string][]TxRecord)
+		a.logData[ev.Name] = wallets
+		a.logAlerted[ev.Name] = make(map[string]time.Time)
+	}
+	if _, ok := wallets[ev.Wallet]; !ok && len(wallets) >= maxLogWallets {
+		a.logger.Warn("Dropping event of a wallet beyond the log spec's wallet limit", "spec", ev.Name, "wallet", ev.Wallet, "limit", maxLogWallets, "tx_hash", ev.TxHash)
+		return
+	}
+	records, ok := insertRecord(wallets[ev.Wallet], TxRecord{Amount: ev.Amount, Timestamp: timestamp}, a.window)
+	wallets[ev.Wallet] = records
+	if !ok {
+		return
+	}
+
+	stats := windowStats(records, a.window)
+	if stats.Total < ev.Threshold {
+		return
+	}
+
+	if !a.cooledDown(notifier.AlertLogVolume, a.logAlerted[ev.Name], ev.Wallet) {
+		return
+	}
+
+	contract := ev.Contract
+	if ev.Label != "" {
+		contract = fmt.Sprintf("%s (%s)", ev.Contract, ev.Label)
+	}
+
+	a.notify(ctx, notifier.Alert{
+		Type:   notifier.AlertLogVolume,
+		Rule:   ev.Name,
+		Title:  "High Event Volume Detected",
+		Wallet: ev.Wallet,
+		Label:  a.book.Label(ev.Wallet),
+		Amount: stats.Total,
+		TxID:   ev.TxHash,
+		Fields: []notifier.Field{
+			{Name: "Contract", Value: contract},
+			{Name: "Event", Value: ev.String()},
+			{Name: "Window total", Value: strings.TrimSpace(fmt.Sprintf("%.4f %s", stats.Total, ev.Symbol))},
+			{Name: "Events", Value: fmt.Sprintf("%d", stats.Count)},
+		},
+	})
+}
+
+// maxLogWallets caps the wallets aggregated per log spec, so that a spec matching every
+// holder of a token cannot grow the aggregator without bound.
+const maxLogWallets = 10_000
+
+// pruneLogs forgets the wallets of log specs with no event in the window ending at
+// timestamp and no alert within the cooldown, at most once per window. Must be called
+// with a.mu held.
+func (a *Aggregator) pruneLogs(timestamp time.Time) {
+	now := a.now()
+	if now.Sub(a.logPruned) < a.window {
+		return
+	}
+	a.logPruned = now
+
+	for spec, wallets := range a.logData {
+		alerted := a.logAlerted[spec]
+		for wallet, records := range wallets {
+			if timestamp.Sub(records[len(records)-1].Timestamp) <= a.window {
+				continue
+			}
+			if last, ok := alerted[wallet]; ok && now.Sub(last) <= a.cooldown {
+				continue
+			}
+			delete(wallets, wallet)
+			delete(alerted, wallet)
+		}
+		if len(wallets) == 0 {
+			delete(a.logData, spec)
+			delete(a.logAlerted, spec)
+		}
+	}
+}
+
+// flow is the signed contribution of a transaction to one wallet's aggregate.
+type flow struct {
+	wallet       string
+	counterparty string
+	sign         float64
+}
+
+// flows returns the wallets a transaction is aggregated under for the given direction.
+func (a *Aggregator) flows(tx alchemyws.MinedTxEvent, direction Direction) []flow {
+	from := strings.ToLower(tx.Transaction.From)
+	to := strings.ToLower(tx.Transaction.To)
+
 	switch direction {
 	case From:
-		wallet = strings.ToLower(tx.Transaction.From)
+		return []flow{{wallet: from, counterparty: to, sign: 1}}
 	case To:
-		wallet = strings.ToLower(tx.Transaction.To)
+		return []flow{{wallet: to, counterparty: from, sign: 1}}
+	case Net:
+		a.mu.Lock()
+		_, netFrom := a.netWallets[from]
+		_, netTo := a.netWallets[to]
+		a.mu.Unlock()
+
+		var flows []flow
+		if netFrom {
+			flows = append(flows, flow{wallet: from, counterparty: to, sign: -1})
+		}
+		if netTo {
+			flows = append(flows, flow{wallet: to, counterparty: from, sign: 1})
+		}
+		return flows
 	default:
+		return nil
+	}
+}
+
+// evaluate checks the window total against the direction's threshold tiers and cooldown.
+// Must be called with a.mu held.
+func (a *Aggregator) evaluate(ctx context.Context, tx alchemyws.MinedTxEvent, direction Direction, wallet string, stats Stats, timestamp time.Time, internal bool) {
+	severity, ok := a.thresholdSeverity(direction, wallet, stats)
+	if !ok {
 		return
 	}
 
-	// Append transaction
-	a.data[direction][wallet] = append(a.data[direction][wallet], TxRecord{
-		Amount:    amount,
-		Timestamp: now,
-	})
+	alertType := notifier.AlertThreshold
+	if direction == Net {
+		alertType = notifier.AlertNetFlow
+	}
+	escalatedFrom, ok := a.cooledDownAt(alertType, a.alerted[direction], string(alertType)+":"+string(direction), wallet, severity)
+	if !ok {
+		return
+	}
 
-	// Filter transactions in the window
-	var (
-		recent []TxRecord
-		total  float64
-	)
+	alert := a.newAlert(tx, direction, wallet, timestamp, internal)
+	alert.Type = alertType
+	alert.Severity = severity
+	alert.Amount = stats.Total
+	alert.AmountUSD = stats.TotalUSD
+	if direction == Net {
+		alert.Title = "Net Flow Detected"
+		alert.Direction = ""
+		alert.Fields = append([]notifier.Field{{Name: "Net", Value: a.formatAmount(stats.Total, stats.TotalUSD, true)}}, alert.Fields...)
+	} else {
+		alert.Title = "High Volume Detected"
+		alert.Fields = append([]notifier.Field{{Name: "Amount", Value: a.formatAmount(stats.Total, stats.TotalUSD, false)}}, alert.Fields...)
+	}
+	if escalatedFrom != "" {
+		alert.Fields = append(alert.Fields, notifier.Field{Name: "Escalated from", Value: string(escalatedFrom)})
+	}
+
+	a.notify(ctx, alert)
+}
+
+// evaluateRules fires an alert for every configured rule matching the wallet's stats.
+// Each rule has its own cooldown per wallet. Must be called with a.mu held.
+func (a *Aggregator) evaluateRules(ctx context.Context, tx alchemyws.MinedTxEvent, direction Direction, wallet string, stats Stats, timestamp time.Time, internal bool) {
+	for _, rule := range a.rules {
+		if !rule.matches(direction, wallet) {
+			continue
+		}
+		ok, err := rule.eval(stats)
+		if err != nil {
+			a.logger.Error("Rule expression failed", "rule", rule.Name, "tx_hash", tx.Transaction.Hash, "wallet", wallet, "direction", direction, "error", err)
+		}
+		if !ok {
+			continue
+		}
+		severity, ok := rule.severity(stats)
+		if !ok {
+			continue
+		}
+
+		if a.ruleAlerted[rule.Name] == nil {
+			a.ruleAlerted[rule.Name] = make(map[string]time.Time)
+		}
+		escalatedFrom, ok := a.cooledDownAt(notifier.AlertRule, a.ruleAlerted[rule.Name], "rule:"+rule.Name, wallet, severity)
+		if !ok {
+			continue
+		}
 
-	for _, r := range a.data[direction][wallet] {
-		if now.Sub(r.Timestamp) <= a.window {
-			recent = append(recent, r)
-			total += r.Amount
+		alert := a.newAlert(tx, direction, wallet, timestamp, internal)
+		alert.Type = notifier.AlertRule
+		alert.Rule = rule.Name
+		alert.Severity = severity
+		alert.Title = rule.Title
+		if alert.Title == "" {
+			alert.Title = "Rule Triggered: " + rule.Name
+		}
+		alert.Amount = stats.Total
+		alert.AmountUSD = stats.TotalUSD
+		var fields []notifier.Field
+		if rule.Condition.set() > 0 {
+			fields = append(fields, notifier.Field{Name: "Condition", Value: rule.Condition.String()})
+		}
+		if len(rule.Tiers) > 0 {
+			fields = append(fields, notifier.Field{Name: "Tier", Value: rule.tier(severity)})
+		}
+		alert.Fields = append(append(fields,
+			notifier.Field{Name: "Window total", Value: a.formatAmount(stats.Total, stats.TotalUSD, direction == Net)},
+			notifier.Field{Name: "Transactions", Value: fmt.Sprintf("%d", stats.Count)},
+		), alert.Fields...)
+		if escalatedFrom != "" {
+			alert.Fields = append(alert.Fields, notifier.Field{Name: "Escalated from", Value: string(escalatedFrom)})
 		}
+
+		a.notify(ctx, alert)
 	}
-	a.data[direction][wallet] = recent
+}
 
-	if total < a.threshold {
+// checkSanctions raises a critical alert when the counterparty of a flow is flagged.
+// Must be called with a.mu held.
+func (a *Aggregator) checkSanctions(ctx context.Context, tx alchemyws.MinedTxEvent, direction Direction, f flow, amount, usd float64, timestamp time.Time, internal bool) {
+	if a.sanctions == nil || f.counterparty == "" {
 		return
 	}
-
-	lastAlert, alerted := a.alerted[direction][wallet]
-	if alerted && now.Sub(lastAlert) <= a.cooldown {
+	reason, flagged := a.sanctions.Lookup(f.counterparty)
+	if !flagged {
 		return
 	}
 
-	// Check alert condition
-	a.alerted[direction][wallet] = now
+	a.logger.Warn("Wallet interacted with flagged address", "tx_hash", tx.Transaction.Hash, "wallet", f.wallet, "direction", direction, "counterparty", f.counterparty)
 
-	var walletFrom, walletTo string
-	if direction == From {
-		walletFrom = wallet
-	} else {
-		walletTo = wallet
+	alert := a.newAlert(tx, direction, f.wallet, timestamp, internal)
+	alert.Type = notifier.AlertSanctions
+	alert.Severity = notifier.SeverityCritical
+	alert.Title = "Flagged Address Interaction"
+	alert.Amount = amount
+	alert.AmountUSD = usd
+	alert.Fields = append([]notifier.Field{
+		{Name: "Counterparty", Value: f.counterparty},
+		{Name: "Amount", Value: a.formatAmount(amount, usd, false)},
+	}, alert.Fields...)
+	if reason != "" {
+		alert.Fields = append(alert.Fields, notifier.Field{Name: "Listed as", Value: reason})
 	}
 
-	go a.notifier.NotifyThresholdExceeded(a.ctx, tx.Transaction.Hash, walletFrom, walletTo, total)
+	a.notify(ctx, alert)
 }
 
-func ParseValue(raw string) float64 {
-	cleaned := strings.TrimPrefix(strings.ToLower(raw), "0x")
-	bigVal, ok := new(big.Int).SetString(cleaned, 16)
+// newAlert fills the fields shared by every alert about a wallet, including a note on
+// expected transfers that were excluded from its volume. Must be called with a.mu held.
+func (a *Aggregator) newAlert(tx alchemyws.MinedTxEvent, direction Direction, wallet string, timestamp time.Time, internal bool) notifier.Alert {
+	alert := notifier.Alert{
+		Wallet:    wallet,
+		Label:     a.book.Label(wallet),
+		Direction: string(direction),
+		TxID:      tx.Transaction.Hash,
+		TxHashes:  windowHashes(a.data[direction][wallet], a.window),
+	}
+	if internal {
+		alert.Fields = append(alert.Fields, notifier.Field{
+			Name:  "Internal",
+			Value: fmt.Sprintf("%s → %s", tx.Transaction.From, tx.Transaction.To),
+		})
+	}
+
+	var (
+		excluded float64
+		count    int
+	)
+	for _, r := range a.excluded[direction][wallet] {
+		if timestamp.Sub(r.Timestamp) <= a.window {
+			excluded += r.Amount
+			count++
+		}
+	}
+	if count > 0 {
+		alert.Fields = append(alert.Fields, notifier.Field{
+			Name:  "Excluded",
+			Value: fmt.Sprintf("%.4f ETH in %d expected transfer(s)", excluded, count),
+		})
+	}
+	return alert
+}
+
+// reportWindow publishes the number of wallets and records held for a direction.
+// Must be called with a.mu held.
+func (a *Aggregator) reportWindow(direction Direction) {
+	if a.metrics == nil {
+		return
+	}
+	records := 0
+	for _, r := range a.data[direction] {
+		records += len(r)
+	}
+	a.metrics.SetWindow(string(direction), len(a.data[direction]), records)
+}
+
+// notify assigns the alert an ID and sends it without blocking the caller, recording it on
+// the span in ctx.
+func (a *Aggregator) notify(ctx context.Context, alert notifier.Alert) {
+	alert.ID = notifier.NewID()
+	a.logger.Info("Alert fired", "alert_id", alert.ID, "type", alert.Type, "rule", alert.Rule, "wallet", alert.Wallet, "direction", alert.Direction, "tx_hash", alert.TxID)
+	trace.SpanFromContext(ctx).AddEvent("alert", trace.WithAttributes(
+		attribute.String("alert_id", alert.ID),
+		attribute.String("type", string(alert.Type)),
+		attribute.String("wallet", alert.Wallet),
+	))
+	go a.notifier.Notify(ctx, alert)
+}
+
+// cooledDown reports whether the cooldown since the last alert for key has passed,
+// and if so records a new alert. Must be called with a.mu held.
+func (a *Aggregator) cooledDown(alertType notifier.AlertType, alerted map[string]time.Time, key string) bool {
+	now := a.now()
+	if last, ok := alerted[key]; ok && now.Sub(last) <= a.cooldown {
+		a.metrics.AlertSuppressed(string(alertType))
+		return false
+	}
+	alerted[key] = now
+	return true
+}
+
+// maxCounterparties caps the counterparties remembered per wallet. Beyond it the least
+// recently seen one is forgotten, and matches new_counterparty again if it comes back.
+const maxCounterparties = 10_000
+
+// observeCounterparty records an interaction and reports whether it was the first one.
+// Nothing is recorded unless a rule matches new counterparties. Interactions within the
+// warm-up are never reported, since every counterparty is new to a freshly started
+// process. Must be called with a.mu held.
+func (a *Aggregator) observeCounterparty(wallet, counterparty string) bool {
+	if counterparty == "" || !a.trackCounterparties {
+		return false
+	}
+	seen, ok := a.counterparties[wallet]
 	if !ok {
-		log.Printf("Failed to parse value '%s' as hexadecimal", raw)
+		seen = make(map[string]time.Time)
+		a.counterparties[wallet] = seen
+	}
+
+	now := a.now()
+	_, known := seen[counterparty]
+	if !known && len(seen) >= maxCounterparties {
+		forgetOldest(seen)
+	}
+	seen[counterparty] = now
+	return !known && now.Sub(a.started) >= a.counterpartyWarmUp
+}
+
+// forgetOldest removes the least recently seen counterparty.
+func forgetOldest(seen map[string]time.Time) {
+	var oldest string
+	var oldestAt time.Time
+	for counterparty, at := range seen {
+		if oldest == "" || at.Before(oldestAt) {
+			oldest, oldestAt = counterparty, at
+		}
+	}
+	delete(seen, oldest)
+}
+
+// timestamp returns the time a block was mined, falling back to the clock
+// when no block time source is configured or the block cannot be resolved.
+func (a *Aggregator) timestamp(ctx context.Context, blockNumber string) time.Time {
+	if a.blockTimes == nil || blockNumber == "" {
+		return a.now()
+	}
+
+	ctx, span := a.tracer.Start(ctx, "aggregator.block_timestamp", trace.WithAttributes(attribute.String("block", blockNumber)))
+	defer span.End()
+
+	number, err := ethrpc.ParseQuantity(blockNumber)
+	if err != nil {
+		a.logger.Error("Invalid block number", "block", blockNumber, "error", err)
+		return a.now()
+	}
+
+	ts, err := a.blockTimes.BlockTimestamp(ctx, number)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "fetch block timestamp")
+		a.logger.Error("Failed to fetch block timestamp", "block", number, "error", err)
+		return a.now()
+	}
+	return ts
+}
+
+// insertRecord places a record into a time-ordered slice and drops every record that
+// falls outside the window ending at the newest timestamp. Records may arrive out of
+// order; the returned flag is false when the new record itself was already too old.
+func insertRecord(records []TxRecord, record TxRecord, window time.Duration) ([]TxRecord, bool) {
+	i := sort.Search(len(records), func(i int) bool {
+		return records[i].Timestamp.After(record.Timestamp)
+	})
+	records = append(records, TxRecord{})
+	copy(records[i+1:], records[i:])
+	records[i] = record
+
+	end := records[len(records)-1].Timestamp
+	start := sort.Search(len(records), func(i int) bool {
+		return end.Sub(records[i].Timestamp) <= window
+	})
+
+	return append([]TxRecord(nil), records[start:]...), i >= start
+}
+
+// windowStats sums the records in the window ending at the newest record and in the window before it.
+func windowStats(records []TxRecord, window time.Duration) Stats {
+	var stats Stats
+	if len(records) == 0 {
+		return stats
+	}
+
+	end := records[len(records)-1].Timestamp
+	for _, r := range records {
+		switch age := end.Sub(r.Timestamp); {
+		case age <= window:
+			stats.Total += r.Amount
+			stats.TotalUSD += r.USD
+			stats.Count++
+		case age <= 2*window:
+			stats.PreviousTotal += r.Amount
+		}
+	}
+	return stats
+}
+
+// windowHashes returns the hashes of the transactions in the latest window, oldest first.
+func windowHashes(records []TxRecord, window time.Duration) []string {
+	if len(records) == 0 {
+		return nil
+	}
+
+	var hashes []string
+	end := records[len(records)-1].Timestamp
+	for _, r := range records {
+		if end.Sub(r.Timestamp) <= window && r.Hash != "" {
+			hashes = append(hashes, r.Hash)
+		}
+	}
+	return hashes
+}
+
+// value returns the ETH value of a transaction, logging values that cannot be parsed.
+func (a *Aggregator) value(tx alchemyws.MinedTxEvent) float64 {
+	wei, err := ethrpc.ParseBig(tx.Transaction.Value)
+	if err != nil {
+		a.logger.Warn("Invalid transaction value", "tx_hash", tx.Transaction.Hash, "value", tx.Transaction.Value, "error", err)
 		return 0
 	}
+	return ethrpc.WeiToEth(wei)
+}
 
-	wei := new(big.Float).SetInt(bigVal)
-	eth := new(big.Float).Quo(wei, big.NewFloat(1e18))
-	result, _ := eth.Float64()
-	return result
+// ParseValue converts a hex wei quantity to ETH, returning 0 when it cannot be parsed.
+func ParseValue(raw string) float64 {
+	wei, err := ethrpc.ParseBig(raw)
+	if err != nil {
+		return 0
+	}
+	return ethrpc.WeiToEth(wei)
 }
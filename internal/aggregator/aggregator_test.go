@@ -1,13 +1,27 @@
 package aggregator
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/yermakovsa/alchemyws"
+	"github.com/yermakovsa/eth-watcher/internal/addressbook"
+	"github.com/yermakovsa/eth-watcher/internal/contract"
+	"github.com/yermakovsa/eth-watcher/internal/logwatcher"
+	"github.com/yermakovsa/eth-watcher/internal/metrics"
+	"github.com/yermakovsa/eth-watcher/internal/notifier"
 )
 
 type MockNotifier struct {
@@ -19,16 +33,24 @@ type MockNotifier struct {
 		walletTo   string
 		amount     float64
 	}
+	alerts []notifier.Alert
 }
 
-func (m *MockNotifier) NotifyThresholdExceeded(ctx context.Context, txID, walletFrom string, walletTo string, total float64) error {
+func (m *MockNotifier) Notify(ctx context.Context, alert notifier.Alert) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.called = true
-	m.args.hash = txID
-	m.args.walletFrom = walletFrom
-	m.args.walletTo = walletTo
-	m.args.amount = total
+	m.alerts = append(m.alerts, alert)
+	if alert.Type == notifier.AlertThreshold {
+		m.called = true
+		m.args.hash = alert.TxID
+		m.args.walletFrom, m.args.walletTo = "", ""
+		if alert.Direction == string(From) {
+			m.args.walletFrom = alert.Wallet
+		} else {
+			m.args.walletTo = alert.Wallet
+		}
+		m.args.amount = alert.Amount
+	}
 	return nil
 }
 
@@ -46,7 +68,7 @@ func TestAggregator_TriggerAlertWhenThresholdExceededFromWallet(t *testing.T) {
 		},
 	}
 
-	go agg.Process(tx, From)
+	go agg.Process(context.Background(), tx, From)
 
 	time.Sleep(10 * time.Millisecond) // wait for goroutine
 
@@ -73,7 +95,7 @@ func TestAggregator_TriggerAlertWhenThresholdExceededToWallet(t *testing.T) {
 		},
 	}
 
-	go agg.Process(tx, To)
+	go agg.Process(context.Background(), tx, To)
 
 	time.Sleep(10 * time.Millisecond) // wait for goroutine
 
@@ -100,8 +122,8 @@ func TestAggregator_DoesNotTriggerAlertBelowThreshold(t *testing.T) {
 		},
 	}
 
-	go agg.Process(tx, From)
-	go agg.Process(tx, To)
+	go agg.Process(context.Background(), tx, From)
+	go agg.Process(context.Background(), tx, To)
 
 	time.Sleep(10 * time.Millisecond)
 
@@ -125,7 +147,7 @@ func TestAggregator_CooldownPreventsMultipleAlerts(t *testing.T) {
 	}
 
 	// First call - should alert
-	go agg.Process(tx, From)
+	go agg.Process(context.Background(), tx, From)
 	time.Sleep(10 * time.Millisecond)
 
 	// Second call within cooldown - should NOT alert again
@@ -133,7 +155,7 @@ func TestAggregator_CooldownPreventsMultipleAlerts(t *testing.T) {
 	notifier.called = false // reset flag
 	notifier.mu.Unlock()
 
-	go agg.Process(tx, From)
+	go agg.Process(context.Background(), tx, From)
 	time.Sleep(10 * time.Millisecond)
 
 	notifier.mu.Lock()
@@ -151,3 +173,504 @@ func TestParseValue_CorrectConversion(t *testing.T) {
 	invalid := ParseValue("nothex")
 	assert.Equal(t, 0.0, invalid)
 }
+
+type MockBlockTimes struct {
+	times map[uint64]time.Time
+}
+
+func (m *MockBlockTimes) BlockTimestamp(ctx context.Context, number uint64) (time.Time, error) {
+	return m.times[number], nil
+}
+
+func TestAggregator_UsesBlockTimestamps(t *testing.T) {
+	notifier := &MockNotifier{}
+	base := time.Unix(1_700_000_000, 0)
+	blocks := &MockBlockTimes{times: map[uint64]time.Time{
+		1: base,
+		2: base.Add(30 * time.Second),
+	}}
+
+	agg := NewAggregator(context.Background(), notifier, 2.0, 10*time.Second, 5*time.Second,
+		WithBlockTimes(blocks),
+		WithClock(func() time.Time { return base }),
+	)
+
+	tx := func(block string) alchemyws.MinedTxEvent {
+		return alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{
+			Hash:        "0x1",
+			From:        "0xabc",
+			Value:       "0xde0b6b3a7640000", // 1 ETH
+			BlockNumber: block,
+		}}
+	}
+
+	// Both arrive at the same wall-clock time but were mined 30s apart
+	agg.Process(context.Background(), tx("0x1"), From)
+	agg.Process(context.Background(), tx("0x2"), From)
+	time.Sleep(10 * time.Millisecond)
+
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+	assert.False(t, notifier.called)
+	assert.Len(t, agg.data[From]["0xabc"], 1)
+}
+
+func TestAggregator_HandlesOutOfOrderRecords(t *testing.T) {
+	notifier := &MockNotifier{}
+	base := time.Unix(1_700_000_000, 0)
+	blocks := &MockBlockTimes{times: map[uint64]time.Time{
+		1: base,
+		2: base.Add(5 * time.Second),
+		3: base.Add(8 * time.Second),
+		4: base.Add(30 * time.Second),
+	}}
+
+	agg := NewAggregator(context.Background(), notifier, 3.0, 10*time.Second, 5*time.Second,
+		WithBlockTimes(blocks),
+		WithClock(func() time.Time { return base }),
+	)
+
+	tx := func(block string) alchemyws.MinedTxEvent {
+		return alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{
+			Hash:        "0x" + block,
+			From:        "0xabc",
+			Value:       "0xde0b6b3a7640000", // 1 ETH
+			BlockNumber: block,
+		}}
+	}
+
+	agg.Process(context.Background(), tx("0x3"), From)
+	agg.Process(context.Background(), tx("0x1"), From) // late, still inside the window
+	records := agg.data[From]["0xabc"]
+	assert.Len(t, records, 2)
+	assert.True(t, records[0].Timestamp.Before(records[1].Timestamp))
+
+	agg.Process(context.Background(), tx("0x2"), From) // late, completes the 3 ETH window
+	time.Sleep(10 * time.Millisecond)
+
+	notifier.mu.Lock()
+	assert.True(t, notifier.called)
+	assert.InDelta(t, 3.0, notifier.args.amount, 0.0001)
+	notifier.mu.Unlock()
+
+	agg.Process(context.Background(), tx("0x4"), From)
+	agg.Process(context.Background(), tx("0x1"), From) // too old to be counted
+	assert.Len(t, agg.data[From]["0xabc"], 1)
+}
+
+func TestAggregator_NetFlowOffsetsInflowsAndOutflows(t *testing.T) {
+	mock := &MockNotifier{}
+	agg := NewAggregator(context.Background(), mock, 100.0, 10*time.Second, 5*time.Second,
+		WithNetFlow([]string{"0xABC"}, 1.5),
+	)
+
+	in := alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{
+		Hash:  "0x1",
+		From:  "0xdef",
+		To:    "0xabc",
+		Value: "0x1bc16d674ec80000", // 2 ETH
+	}}
+	out := alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{
+		Hash:  "0x2",
+		From:  "0xabc",
+		To:    "0xdef",
+		Value: "0x1bc16d674ec80000", // 2 ETH
+	}}
+
+	agg.Process(context.Background(), in, Net)
+	time.Sleep(10 * time.Millisecond)
+
+	mock.mu.Lock()
+	assert.Len(t, mock.alerts, 1)
+	assert.Equal(t, notifier.AlertNetFlow, mock.alerts[0].Type)
+	assert.Equal(t, "0xabc", mock.alerts[0].Wallet)
+	assert.Equal(t, "+2.0000 ETH", mock.alerts[0].Fields[0].Value)
+	mock.alerts = nil
+	mock.mu.Unlock()
+
+	agg.Process(context.Background(), out, Net)
+	time.Sleep(10 * time.Millisecond)
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	assert.Empty(t, mock.alerts)
+	assert.False(t, mock.called)
+	assert.Len(t, agg.data[Net]["0xabc"], 2)
+	assert.Empty(t, agg.data[Net]["0xdef"], "counterparty must not be tracked")
+}
+
+func TestAggregator_RulesFireTheirOwnAlerts(t *testing.T) {
+	mock := &MockNotifier{}
+	rules := []Rule{
+		{Name: "burst", Direction: From, Condition: Condition{CountAtLeast: ptr(2)}},
+		{Name: "new-counterparty", Condition: Condition{NewCounterparty: true}},
+	}
+	agg := NewAggregator(context.Background(), mock, 100.0, 10*time.Second, 5*time.Second, WithRules(rules))
+
+	tx := alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{
+		Hash:  "0x1",
+		From:  "0xabc",
+		To:    "0xdef",
+		Value: "0xde0b6b3a7640000", // 1 ETH
+	}}
+
+	agg.Process(context.Background(), tx, From)
+	agg.Process(context.Background(), tx, From)
+	time.Sleep(10 * time.Millisecond)
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	assert.False(t, mock.called)
+	require.Len(t, mock.alerts, 2)
+
+	fired := map[string]notifier.Alert{}
+	for _, a := range mock.alerts {
+		assert.Equal(t, notifier.AlertRule, a.Type)
+		fired[a.Rule] = a
+	}
+	assert.Contains(t, fired, "burst")
+	assert.Contains(t, fired, "new-counterparty")
+	assert.Equal(t, "0xabc", fired["burst"].Wallet)
+}
+
+func TestAggregator_CounterpartyWarmUp(t *testing.T) {
+	mock := &MockNotifier{}
+	rules := []Rule{{Name: "new-counterparty", Condition: Condition{NewCounterparty: true}}}
+	agg := NewAggregator(context.Background(), mock, 100.0, 10*time.Second, 5*time.Second, WithRules(rules), WithCounterpartyWarmUp(time.Hour))
+	send := func(hash, to string) {
+		agg.Process(context.Background(), alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{
+			Hash: hash, From: "0xabc", To: to, Value: "0xde0b6b3a7640000", // 1 ETH
+		}}, From)
+	}
+
+	send("0x1", "0xdef")
+	agg.now = func() time.Time { return agg.started.Add(2 * time.Hour) }
+	send("0x2", "0xdef")
+	send("0x3", "0x123")
+	time.Sleep(10 * time.Millisecond)
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	require.Len(t, mock.alerts, 1, "counterparties seen during the warm-up are known afterwards")
+	assert.Equal(t, "0x3", mock.alerts[0].TxID)
+}
+
+func TestAggregator_LearnsCounterpartiesOnlyForRules(t *testing.T) {
+	agg := NewAggregator(context.Background(), &MockNotifier{}, 100.0, 10*time.Second, 5*time.Second)
+	send := func(hash, to string) {
+		agg.Process(context.Background(), alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{
+			Hash: hash, From: "0xabc", To: to, Value: "0xde0b6b3a7640000", // 1 ETH
+		}}, From)
+	}
+
+	send("0x1", "0xdef")
+	assert.Empty(t, agg.counterparties, "no rule matches new counterparties")
+
+	agg.SetRules([]Rule{{Name: "known-counterparty", Condition: Condition{Not: &Condition{NewCounterparty: true}}}})
+	send("0x2", "0xdef")
+	assert.Contains(t, agg.counterparties["0xabc"], "0xdef")
+
+	agg.SetRules(nil)
+	assert.Empty(t, agg.counterparties, "removing the rule forgets them")
+}
+
+func TestAggregator_CapsCounterpartiesPerWallet(t *testing.T) {
+	rules := []Rule{{Name: "new-counterparty", Condition: Condition{NewCounterparty: true}}}
+	agg := NewAggregator(context.Background(), &MockNotifier{}, 100.0, 10*time.Second, 5*time.Second, WithRules(rules))
+	seen := make(map[string]time.Time, maxCounterparties)
+	for i := range maxCounterparties {
+		seen[fmt.Sprintf("0x%d", i)] = agg.started.Add(time.Duration(i+1) * time.Second)
+	}
+	seen["0x42"] = agg.started
+	agg.counterparties["0xabc"] = seen
+
+	agg.Process(context.Background(), alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{
+		Hash: "0x1", From: "0xabc", To: "0xdef", Value: "0xde0b6b3a7640000",
+	}}, From)
+
+	assert.Len(t, agg.counterparties["0xabc"], maxCounterparties)
+	assert.Contains(t, agg.counterparties["0xabc"], "0xdef")
+	assert.NotContains(t, agg.counterparties["0xabc"], "0x42", "the least recently seen counterparty is forgotten")
+}
+
+func TestAggregator_AlertsListWindowTransactions(t *testing.T) {
+	mock := &MockNotifier{}
+	agg := NewAggregator(context.Background(), mock, 2.0, 10*time.Second, 5*time.Second)
+
+	agg.Process(context.Background(), ethTx("0x1", 1), From)
+	agg.Process(context.Background(), ethTx("0x2", 1), From)
+	time.Sleep(10 * time.Millisecond)
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	require.Len(t, mock.alerts, 1)
+	assert.Equal(t, []string{"0x1", "0x2"}, mock.alerts[0].TxHashes)
+}
+
+func TestAggregator_AddressBookExcludesExpectedTransfers(t *testing.T) {
+	mock := &MockNotifier{}
+	book := addressbook.New(map[string]addressbook.Entry{
+		"0xhot": {Label: "Hot wallet", Allow: []string{"0xcold"}},
+	})
+	agg := NewAggregator(context.Background(), mock, 1.5, 10*time.Second, 5*time.Second, WithAddressBook(book))
+
+	sweep := alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{
+		Hash:  "0x1",
+		From:  "0xhot",
+		To:    "0xcold",
+		Value: "0x1bc16d674ec80000", // 2 ETH
+	}}
+	agg.Process(context.Background(), sweep, From)
+	time.Sleep(10 * time.Millisecond)
+
+	mock.mu.Lock()
+	assert.False(t, mock.called, "expected transfers must not count towards the threshold")
+	mock.mu.Unlock()
+
+	withdrawal := alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{
+		Hash:  "0x2",
+		From:  "0xhot",
+		To:    "0xcustomer",
+		Value: "0x1bc16d674ec80000", // 2 ETH
+	}}
+	agg.Process(context.Background(), withdrawal, From)
+	time.Sleep(10 * time.Millisecond)
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	assert.True(t, mock.called)
+	assert.InDelta(t, 2.0, mock.args.amount, 0.0001)
+	require.Len(t, mock.alerts, 1)
+	assert.Equal(t, "Hot wallet", mock.alerts[0].Label)
+	assert.Contains(t, mock.alerts[0].Fields, notifier.Field{Name: "Excluded", Value: "2.0000 ETH in 1 expected transfer(s)"})
+}
+
+type MockSanctions map[string]string
+
+func (m MockSanctions) Lookup(addr string) (string, bool) {
+	reason, ok := m[addr]
+	return reason, ok
+}
+
+func TestAggregator_SanctionedCounterpartyAlertsRegardlessOfAmount(t *testing.T) {
+	mock := &MockNotifier{}
+	book := addressbook.New(map[string]addressbook.Entry{
+		"0xabc": {Allow: []string{"*"}},
+	})
+	agg := NewAggregator(context.Background(), mock, 100.0, 10*time.Second, 5*time.Second,
+		WithSanctions(MockSanctions{"0xbad": "SDN"}),
+		WithAddressBook(book),
+	)
+
+	tx := alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{
+		Hash:  "0x1",
+		From:  "0xBAD",
+		To:    "0xabc",
+		Value: "0x1", // 1 wei
+	}}
+	agg.Process(context.Background(), tx, To)
+	time.Sleep(10 * time.Millisecond)
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	assert.False(t, mock.called)
+	require.Len(t, mock.alerts, 1)
+	assert.Equal(t, notifier.AlertSanctions, mock.alerts[0].Type)
+	assert.Equal(t, notifier.SeverityCritical, mock.alerts[0].Severity)
+	assert.Contains(t, mock.alerts[0].Fields, notifier.Field{Name: "Counterparty", Value: "0xbad"})
+	assert.Contains(t, mock.alerts[0].Fields, notifier.Field{Name: "Listed as", Value: "SDN"})
+}
+
+type MockBalances struct {
+	mu       sync.Mutex
+	observed []string
+}
+
+func (m *MockBalances) Observe(ctx context.Context, wallet string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.observed = append(m.observed, wallet)
+}
+
+func TestAggregator_ObservesBalancesOfMonitoredWallets(t *testing.T) {
+	balances := &MockBalances{}
+	agg := NewAggregator(context.Background(), &MockNotifier{}, 100, 10*time.Second, 5*time.Second,
+		WithNetFlow([]string{"0xabc", "0xdef"}, 100),
+		WithBalances(balances),
+	)
+
+	agg.Process(context.Background(), alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{Hash: "0x1", From: "0xabc", To: "0xdef", Value: "0x1"}}, Net)
+	agg.Process(context.Background(), alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{Hash: "0x2", From: "0x123", To: "0x456", Value: "0x1"}}, To)
+
+	balances.mu.Lock()
+	defer balances.mu.Unlock()
+	assert.Equal(t, []string{"0xabc", "0xdef", "0x456"}, balances.observed)
+}
+
+func TestAggregator_ProcessInternalSharesWindowAndMarksAlert(t *testing.T) {
+	mock := &MockNotifier{}
+	agg := NewAggregator(context.Background(), mock, 1.5, 10*time.Second, 5*time.Second)
+
+	agg.Process(context.Background(), alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{
+		Hash:  "0x1",
+		From:  "0xsafe",
+		To:    "0xbob",
+		Value: "0xde0b6b3a7640000", // 1 ETH
+	}}, From)
+	agg.ProcessInternal(context.Background(), alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{
+		Hash:  "0x2",
+		From:  "0xsafe",
+		To:    "0xcarol",
+		Value: "0xde0b6b3a7640000", // 1 ETH moved by the multisig itself
+	}}, From)
+	time.Sleep(10 * time.Millisecond)
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	require.True(t, mock.called)
+	assert.Equal(t, "0x2", mock.args.hash)
+	assert.InDelta(t, 2.0, mock.args.amount, 1e-9)
+	assert.Contains(t, mock.alerts[0].Fields, notifier.Field{Name: "Internal", Value: "0xsafe → 0xcarol"})
+}
+
+func TestAggregator_ProcessCallAlertsWithDecodedCall(t *testing.T) {
+	mock := &MockNotifier{}
+	agg := NewAggregator(context.Background(), mock, 1.0, 10*time.Second, 5*time.Second)
+
+	tx := alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{
+		Hash: "0x1",
+		From: "0xADMIN",
+		To:   "0xproxy",
+	}}
+	agg.ProcessCall(context.Background(), tx, contract.Call{Contract: "0xproxy", Label: "Bridge proxy", Method: "pause"})
+	time.Sleep(10 * time.Millisecond)
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	assert.False(t, mock.called)
+	require.Len(t, mock.alerts, 1)
+	assert.Equal(t, notifier.AlertContractCall, mock.alerts[0].Type)
+	assert.Equal(t, "Bridge proxy", mock.alerts[0].Label)
+	assert.Equal(t, []notifier.Field{
+		{Name: "Caller", Value: "0xadmin"},
+		{Name: "Call", Value: "pause()"},
+	}, mock.alerts[0].Fields)
+}
+
+func TestAggregator_ProcessLog(t *testing.T) {
+	mock := &MockNotifier{}
+	agg := NewAggregator(context.Background(), mock, 1.0, 10*time.Second, 5*time.Second)
+
+	agg.ProcessLog(logwatcher.Event{Name: "owner", Contract: "0xproxy", Signature: "OwnershipTransferred(address,address)", TxHash: "0x1"})
+
+	transfer := logwatcher.Event{
+		Name:       "usdc",
+		Contract:   "0xtoken",
+		Signature:  "Transfer(address,address,uint256)",
+		TxHash:     "0x2",
+		Aggregated: true,
+		Wallet:     "0xabc",
+		Amount:     600,
+		Symbol:     "USDC",
+		Threshold:  1000,
+	}
+	agg.ProcessLog(transfer)
+	agg.ProcessLog(transfer)
+	time.Sleep(10 * time.Millisecond)
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	require.Len(t, mock.alerts, 2)
+
+	byType := map[notifier.AlertType]notifier.Alert{}
+	for _, a := range mock.alerts {
+		byType[a.Type] = a
+	}
+	assert.Equal(t, "0xproxy", byType[notifier.AlertLogEvent].Wallet)
+	assert.Equal(t, "0xabc", byType[notifier.AlertLogVolume].Wallet)
+	assert.Equal(t, "usdc", byType[notifier.AlertLogVolume].Rule)
+	assert.Contains(t, byType[notifier.AlertLogVolume].Fields, notifier.Field{Name: "Window total", Value: "1200.0000 USDC"})
+}
+
+func TestAggregator_PrunesIdleLogWallets(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	agg := NewAggregator(context.Background(), &MockNotifier{}, 1.0, 10*time.Second, time.Minute, WithClock(func() time.Time { return now }))
+	transfer := func(wallet string, amount float64) logwatcher.Event {
+		return logwatcher.Event{Name: "usdc", Aggregated: true, Wallet: wallet, Amount: amount, Threshold: 1000}
+	}
+
+	agg.ProcessLog(transfer("0xquiet", 10))
+	agg.ProcessLog(transfer("0xalerted", 5000))
+	now = now.Add(30 * time.Second)
+	agg.ProcessLog(transfer("0xnew", 10))
+
+	agg.mu.Lock()
+	assert.NotContains(t, agg.logData["usdc"], "0xquiet", "an empty window is forgotten")
+	assert.Contains(t, agg.logData["usdc"], "0xalerted", "a wallet in its cooldown is kept")
+	assert.Contains(t, agg.logAlerted["usdc"], "0xalerted")
+	agg.mu.Unlock()
+
+	now = now.Add(2 * time.Minute)
+	agg.ProcessLog(logwatcher.Event{Name: "dai", Aggregated: true, Wallet: "0xabc", Amount: 1, Threshold: 1000})
+
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+	assert.NotContains(t, agg.logData, "usdc", "specs without wallets are forgotten")
+	assert.NotContains(t, agg.logAlerted, "usdc")
+}
+
+func TestAggregator_CapsLogWalletsPerSpec(t *testing.T) {
+	agg := NewAggregator(context.Background(), &MockNotifier{}, 1.0, time.Hour, time.Minute, WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	for i := range maxLogWallets + 1 {
+		agg.ProcessLog(logwatcher.Event{Name: "usdc", Aggregated: true, Wallet: fmt.Sprintf("0x%x", i), Amount: 1, Threshold: 1000})
+	}
+	agg.ProcessLog(logwatcher.Event{Name: "usdc", Aggregated: true, Wallet: "0x0", Amount: 1, Threshold: 1000})
+
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+	assert.Len(t, agg.logData["usdc"], maxLogWallets)
+	assert.Len(t, agg.logData["usdc"]["0x0"], 2, "known wallets are still aggregated at the limit")
+}
+
+func TestAggregator_RecordsAlertMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	agg := NewAggregator(context.Background(), &MockNotifier{}, 1.0, 10*time.Second, 5*time.Second, WithMetrics(metrics.New(reg)))
+
+	for _, hash := range []string{"0x1", "0x2"} {
+		agg.Process(context.Background(), alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{
+			Hash:  hash,
+			From:  "0xabc",
+			Value: "0xde0b6b3a7640000", // 1 ETH
+		}}, From)
+	}
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP eth_watcher_alerts_suppressed_total Alerts not sent because the cooldown had not passed, by alert type.
+# TYPE eth_watcher_alerts_suppressed_total counter
+eth_watcher_alerts_suppressed_total{type="threshold"} 1
+# HELP eth_watcher_window_records Transaction records held for the aggregation windows, by direction.
+# TYPE eth_watcher_window_records gauge
+eth_watcher_window_records{direction="from"} 2
+`), "eth_watcher_alerts_suppressed_total", "eth_watcher_window_records"))
+}
+
+func TestAggregator_LogsWithInjectedLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	agg := NewAggregator(context.Background(), &MockNotifier{}, 1.0, 10*time.Second, 5*time.Second, WithLogger(logger))
+
+	agg.Process(context.Background(), alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{
+		Hash:  "0x1",
+		From:  "0xabc",
+		Value: "0xde0b6b3a7640000", // 1 ETH
+	}}, From)
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "Alert fired", record["msg"])
+	assert.Equal(t, "aggregator", record["component"])
+	assert.Equal(t, "threshold", record["type"])
+	assert.Equal(t, "0xabc", record["wallet"])
+	assert.Equal(t, "from", record["direction"])
+	assert.Equal(t, "0x1", record["tx_hash"])
+}
@@ -0,0 +1,82 @@
+package aggregator
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// PriceFeed provides the current price of ETH in USD.
+type PriceFeed interface {
+	ETHUSD(ctx context.Context) (float64, error)
+}
+
+// WithPrices values every transaction in USD at the time it is observed, so window totals
+// reflect the price of each transfer, and shows USD values next to ETH amounts in alerts.
+// A positive thresholdUSD or netThresholdUSD replaces the ETH threshold of the matching directions.
+func WithPrices(feed PriceFeed, thresholdUSD, netThresholdUSD float64) Option {
+	return func(a *Aggregator) {
+		a.prices = feed
+		for _, direction := range []Direction{From, To} {
+			t := a.thresholds[direction]
+			t.USD = thresholdUSD
+			a.thresholds[direction] = t
+		}
+		t := a.thresholds[Net]
+		t.USD = netThresholdUSD
+		a.thresholds[Net] = t
+	}
+}
+
+// rate returns the current ETH price in USD, or 0 when prices are disabled or unavailable.
+func (a *Aggregator) rate(ctx context.Context) float64 {
+	if a.prices == nil {
+		return 0
+	}
+	price, err := a.prices.ETHUSD(ctx)
+	if err != nil {
+		a.logger.Error("Failed to fetch ETH price", "error", err)
+		return 0
+	}
+	return price
+}
+
+// measure returns a window total and the threshold it is compared against: that of the
+// wallet, or else of its direction, in USD when a USD threshold is configured. Net totals
+// are measured by their absolute value. Must be called with a.mu held.
+func (a *Aggregator) measure(direction Direction, wallet string, stats Stats) (total, threshold float64) {
+	total, totalUSD := stats.Total, stats.TotalUSD
+	if direction == Net {
+		total, totalUSD = math.Abs(total), math.Abs(totalUSD)
+	}
+	if eth, ok := a.walletThresholds[wallet]; ok {
+		return total, eth
+	}
+	t := a.thresholds[direction]
+	if t.USD > 0 {
+		return totalUSD, t.USD
+	}
+	return total, t.ETH
+}
+
+// formatAmount renders an ETH amount followed by its USD value when prices are enabled.
+// Signed amounts, such as net flows, always carry their sign.
+func (a *Aggregator) formatAmount(eth, usd float64, signed bool) string {
+	format := "%.4f ETH"
+	if signed {
+		format = "%+.4f ETH"
+	}
+	amount := fmt.Sprintf(format, eth)
+	if a.prices == nil {
+		return amount
+	}
+
+	sign := ""
+	switch {
+	case usd < 0:
+		sign = "-"
+	case signed:
+		sign = "+"
+	}
+	return fmt.Sprintf("%s (%s$%.2f)", amount, sign, math.Abs(usd))
+}
@@ -0,0 +1,74 @@
+package aggregator
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yermakovsa/alchemyws"
+	"github.com/yermakovsa/eth-watcher/internal/notifier"
+)
+
+type MockPrices struct {
+	mu    sync.Mutex
+	price float64
+}
+
+func (m *MockPrices) set(price float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.price = price
+}
+
+func (m *MockPrices) ETHUSD(ctx context.Context) (float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.price, nil
+}
+
+func TestAggregator_USDThresholdUsesPriceAtObservation(t *testing.T) {
+	mock := &MockNotifier{}
+	prices := &MockPrices{price: 2000}
+	// The ETH threshold is ignored once a USD threshold is set
+	agg := NewAggregator(context.Background(), mock, 1000, 10*time.Second, 5*time.Second,
+		WithPrices(prices, 5000, 0),
+	)
+
+	oneETH := func(hash string) alchemyws.MinedTxEvent {
+		return alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{Hash: hash, From: "0xabc", Value: "0xde0b6b3a7640000"}}
+	}
+
+	agg.Process(context.Background(), oneETH("0x1"), From)
+	agg.Process(context.Background(), oneETH("0x2"), From)
+	time.Sleep(10 * time.Millisecond)
+
+	mock.mu.Lock()
+	assert.False(t, mock.called)
+	mock.mu.Unlock()
+
+	// Earlier transfers keep the value they had when observed
+	prices.set(2500)
+	agg.Process(context.Background(), oneETH("0x3"), From)
+	time.Sleep(10 * time.Millisecond)
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	require.True(t, mock.called)
+	require.Len(t, mock.alerts, 1)
+	assert.InDelta(t, 3.0, mock.alerts[0].Amount, 1e-9)
+	assert.InDelta(t, 6500.0, mock.alerts[0].AmountUSD, 1e-6)
+	assert.Equal(t, notifier.Field{Name: "Amount", Value: "3.0000 ETH ($6500.00)"}, mock.alerts[0].Fields[0])
+}
+
+func TestAggregator_FormatAmount(t *testing.T) {
+	agg := NewAggregator(context.Background(), &MockNotifier{}, 1, time.Second, time.Second)
+	assert.Equal(t, "-1.5000 ETH", agg.formatAmount(-1.5, 0, true))
+
+	agg = NewAggregator(context.Background(), &MockNotifier{}, 1, time.Second, time.Second, WithPrices(&MockPrices{}, 0, 0))
+	assert.Equal(t, "-1.5000 ETH (-$4500.00)", agg.formatAmount(-1.5, -4500, true))
+	assert.Equal(t, "+1.5000 ETH (+$4500.00)", agg.formatAmount(1.5, 4500, true))
+	assert.Equal(t, "1.5000 ETH ($4500.00)", agg.formatAmount(1.5, 4500, false))
+}
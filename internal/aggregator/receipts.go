@@ -0,0 +1,144 @@
+package aggregator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yermakovsa/alchemyws"
+	"github.com/yermakovsa/eth-watcher/internal/ethrpc"
+	"github.com/yermakovsa/eth-watcher/internal/notifier"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ReceiptSource loads the receipt of a mined transaction.
+type ReceiptSource interface {
+	TransactionReceipt(ctx context.Context, hash string) (*ethrpc.Receipt, error)
+}
+
+// WithReceipts checks the receipt of every processed transaction. Reverted transactions
+// move no ETH, so they are left out of the aggregated volume.
+func WithReceipts(receipts ReceiptSource) Option {
+	return func(a *Aggregator) {
+		a.receipts = receipts
+		a.checkStatus = true
+	}
+}
+
+// WithFeeSpend tracks the gas fees monitored wallets pay for their outgoing transactions
+// and alerts when a wallet's spend over window reaches threshold ETH. Receipts loaded for
+// fees leave reverted transactions in the volume unless WithReceipts is set too.
+func WithFeeSpend(receipts ReceiptSource, threshold float64, window time.Duration) Option {
+	return func(a *Aggregator) {
+		a.receipts = receipts
+		a.feeThreshold = threshold
+		a.feeWindow = window
+	}
+}
+
+// WithFailedTxAlert alerts when a monitored wallet sends count or more transactions
+// that revert within window. It requires receipts, see WithReceipts.
+func WithFailedTxAlert(count int, window time.Duration) Option {
+	return func(a *Aggregator) {
+		a.failedCount = count
+		a.failedWindow = window
+	}
+}
+
+// outgoing reports whether the flow's wallet sent the transaction and therefore paid its fee.
+func outgoing(direction Direction, f flow) bool {
+	return direction == From || direction == Net && f.sign < 0
+}
+
+// receipt fetches the receipt of tx, returning nil if it cannot be loaded.
+func (a *Aggregator) receipt(ctx context.Context, tx alchemyws.MinedTxEvent) *ethrpc.Receipt {
+	ctx, span := a.tracer.Start(ctx, "aggregator.receipt", trace.WithAttributes(attribute.String("tx_hash", tx.Transaction.Hash)))
+	defer span.End()
+
+	receipt, err := a.receipts.TransactionReceipt(ctx, tx.Transaction.Hash)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "fetch receipt")
+		a.logger.Error("Failed to fetch receipt", "tx_hash", tx.Transaction.Hash, "error", err)
+		return nil
+	}
+	return receipt
+}
+
+// trackFee adds the fee paid by wallet to its spend window and alerts when the total
+// reaches the fee threshold. Failed transactions are charged too. Must be called with a.mu held.
+func (a *Aggregator) trackFee(ctx context.Context, tx alchemyws.MinedTxEvent, wallet string, receipt *ethrpc.Receipt, timestamp time.Time) {
+	if a.feeThreshold <= 0 {
+		return
+	}
+	wei, err := receipt.Fee()
+	if err != nil {
+		a.logger.Error("Invalid receipt", "tx_hash", tx.Transaction.Hash, "wallet", wallet, "error", err)
+		return
+	}
+
+	records, stats, ok := addOnce(a.feeData[wallet], tx.Transaction.Hash, ethrpc.WeiToEth(wei), timestamp, a.feeWindow)
+	a.feeData[wallet] = records
+	if !ok || stats.Total < a.feeThreshold || !a.cooledDown(notifier.AlertFeeSpend, a.feeAlerted, wallet) {
+		return
+	}
+
+	a.notify(ctx, notifier.Alert{
+		Type:      notifier.AlertFeeSpend,
+		Title:     "High Fee Spend Detected",
+		Wallet:    wallet,
+		Label:     a.book.Label(wallet),
+		Direction: string(From),
+		Amount:    stats.Total,
+		TxID:      tx.Transaction.Hash,
+		Fields: []notifier.Field{
+			{Name: "Fees", Value: fmt.Sprintf("%.6f ETH", stats.Total)},
+			{Name: "Transactions", Value: fmt.Sprintf("%d", stats.Count)},
+			{Name: "Window", Value: a.feeWindow.String()},
+		},
+	})
+}
+
+// trackFailure counts reverted transactions sent by wallet and alerts when they reach
+// the configured count within the window. Must be called with a.mu held.
+func (a *Aggregator) trackFailure(ctx context.Context, tx alchemyws.MinedTxEvent, wallet string, receipt *ethrpc.Receipt, timestamp time.Time) {
+	if a.failedCount <= 0 || !receipt.Failed() {
+		return
+	}
+
+	records, stats, ok := addOnce(a.failedData[wallet], tx.Transaction.Hash, 1, timestamp, a.failedWindow)
+	a.failedData[wallet] = records
+	if !ok || stats.Count < a.failedCount || !a.cooledDown(notifier.AlertFailedTx, a.failedAlerted, wallet) {
+		return
+	}
+
+	a.notify(ctx, notifier.Alert{
+		Type:      notifier.AlertFailedTx,
+		Title:     "Repeated Failed Transactions",
+		Wallet:    wallet,
+		Label:     a.book.Label(wallet),
+		Direction: string(From),
+		TxID:      tx.Transaction.Hash,
+		Fields: []notifier.Field{
+			{Name: "Failed", Value: fmt.Sprintf("%d", stats.Count)},
+			{Name: "Window", Value: a.failedWindow.String()},
+		},
+	})
+}
+
+// addOnce inserts a record unless the transaction is already in the window, which happens
+// when it is processed for several directions, and returns the updated window stats.
+func addOnce(records []TxRecord, hash string, amount float64, timestamp time.Time, window time.Duration) ([]TxRecord, Stats, bool) {
+	for _, r := range records {
+		if r.Hash == hash {
+			return records, Stats{}, false
+		}
+	}
+	records, ok := insertRecord(records, TxRecord{Hash: hash, Amount: amount, Timestamp: timestamp}, window)
+	if !ok {
+		return records, Stats{}, false
+	}
+	return records, windowStats(records, window), true
+}
@@ -0,0 +1,133 @@
+package aggregator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yermakovsa/alchemyws"
+	"github.com/yermakovsa/eth-watcher/internal/ethrpc"
+	"github.com/yermakovsa/eth-watcher/internal/notifier"
+)
+
+type MockReceipts struct {
+	mu       sync.Mutex
+	receipts map[string]*ethrpc.Receipt
+	calls    int
+}
+
+func (m *MockReceipts) TransactionReceipt(ctx context.Context, hash string) (*ethrpc.Receipt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls++
+	r, ok := m.receipts[hash]
+	if !ok {
+		return nil, fmt.Errorf("receipt for %s not found", hash)
+	}
+	return r, nil
+}
+
+func TestAggregator_FeeSpendAlert(t *testing.T) {
+	mock := &MockNotifier{}
+	receipts := &MockReceipts{receipts: map[string]*ethrpc.Receipt{
+		// 0.01 ETH each: 1,000,000 gas at 10 gwei
+		"0x1": {Status: "0x1", GasUsed: "0xf4240", EffectiveGasPrice: "0x2540be400"},
+		"0x2": {Status: "0x1", GasUsed: "0xf4240", EffectiveGasPrice: "0x2540be400"},
+		"0x3": {Status: "0x1", GasUsed: "0xf4240", EffectiveGasPrice: "0x2540be400"},
+	}}
+	agg := NewAggregator(context.Background(), mock, 100, 10*time.Second, 5*time.Second,
+		WithNetFlow([]string{"0xabc"}, 100),
+		WithFeeSpend(receipts, 0.02, time.Hour),
+	)
+
+	first := alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{Hash: "0x1", From: "0xabc", To: "0xdef", Value: "0x0"}}
+	agg.Process(context.Background(), first, From)
+	agg.Process(context.Background(), first, Net) // the same transaction must not be charged twice
+	// Incoming transactions are paid for by the sender
+	agg.Process(context.Background(), alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{Hash: "0x3", From: "0xdef", To: "0xabc", Value: "0x0"}}, Net)
+	time.Sleep(10 * time.Millisecond)
+
+	mock.mu.Lock()
+	assert.Empty(t, mock.alerts)
+	mock.mu.Unlock()
+
+	agg.Process(context.Background(), alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{Hash: "0x2", From: "0xabc", To: "0xdef", Value: "0x0"}}, From)
+	time.Sleep(10 * time.Millisecond)
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	require.Len(t, mock.alerts, 1)
+	assert.Equal(t, notifier.AlertFeeSpend, mock.alerts[0].Type)
+	assert.Equal(t, "0xabc", mock.alerts[0].Wallet)
+	assert.InDelta(t, 0.02, mock.alerts[0].Amount, 1e-12)
+	assert.Contains(t, mock.alerts[0].Fields, notifier.Field{Name: "Transactions", Value: "2"})
+}
+
+func TestAggregator_FeeSpendWithoutStatusCheck(t *testing.T) {
+	mock := &MockNotifier{}
+	receipts := &MockReceipts{receipts: map[string]*ethrpc.Receipt{
+		// Reverted, but the 0.01 ETH fee is paid anyway
+		"0x1": {Status: "0x0", GasUsed: "0xf4240", EffectiveGasPrice: "0x2540be400"},
+	}}
+	agg := NewAggregator(context.Background(), mock, 1, 10*time.Second, 5*time.Second,
+		WithFeeSpend(receipts, 0.01, time.Hour),
+	)
+
+	agg.Process(context.Background(), alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{
+		Hash:  "0x1",
+		From:  "0xabc",
+		To:    "0xdef",
+		Value: "0xde0b6b3a7640000", // 1 ETH
+	}}, From)
+	time.Sleep(10 * time.Millisecond)
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	types := []notifier.AlertType{}
+	for _, alert := range mock.alerts {
+		types = append(types, alert.Type)
+	}
+	assert.ElementsMatch(t, []notifier.AlertType{notifier.AlertFeeSpend, notifier.AlertThreshold}, types,
+		"without status checks a reverted transaction still counts toward the volume")
+}
+
+func TestAggregator_FailedTransactions(t *testing.T) {
+	mock := &MockNotifier{}
+	receipts := &MockReceipts{receipts: map[string]*ethrpc.Receipt{
+		"0x1": {Status: "0x0"},
+		"0x2": {Status: "0x0"},
+		"0x3": {Status: "0x1"},
+	}}
+	agg := NewAggregator(context.Background(), mock, 1.5, 10*time.Second, 5*time.Second,
+		WithReceipts(receipts),
+		WithFailedTxAlert(2, time.Minute),
+	)
+
+	for _, hash := range []string{"0x1", "0x2", "0x3"} {
+		agg.Process(context.Background(), alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{
+			Hash:  hash,
+			From:  "0xabc",
+			To:    "0xdef",
+			Value: "0xde0b6b3a7640000", // 1 ETH
+		}}, From)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+
+	// Only the successful transaction counts towards the volume, which stays under the threshold
+	assert.False(t, mock.called)
+	require.Len(t, mock.alerts, 1)
+	assert.Equal(t, notifier.AlertFailedTx, mock.alerts[0].Type)
+	assert.Equal(t, "0x2", mock.alerts[0].TxID)
+	assert.Contains(t, mock.alerts[0].Fields, notifier.Field{Name: "Failed", Value: "2"})
+
+	receipts.mu.Lock()
+	defer receipts.mu.Unlock()
+	assert.Equal(t, 3, receipts.calls)
+}
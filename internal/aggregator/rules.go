@@ -0,0 +1,323 @@
+package aggregator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/yermakovsa/alchemyws"
+	"github.com/yermakovsa/eth-watcher/internal/notifier"
+	"github.com/yermakovsa/eth-watcher/internal/txexpr"
+)
+
+// Rule is a named alert condition evaluated on every processed transaction.
+type Rule struct {
+	Name      string    `json:"name"`
+	Title     string    `json:"title,omitempty"`
+	Direction Direction `json:"direction,omitempty"` // empty matches every direction
+	Wallets   []string  `json:"wallets,omitempty"`   // empty matches every monitored wallet
+	// Condition may be omitted when the rule has tiers
+	Condition Condition `json:"condition"`
+	// Severity of the rule's alerts, warning when empty. Rules with tiers take the
+	// severity of the highest tier reached instead.
+	Severity notifier.Severity `json:"severity,omitempty"`
+	// Tiers grade the rule's alerts by window total, in ascending order.
+	Tiers []Tier `json:"tiers,omitempty"`
+}
+
+// Condition is a predicate over the wallet's window statistics.
+// Exactly one of its fields must be set.
+type Condition struct {
+	All []Condition `json:"all,omitempty"`
+	Any []Condition `json:"any,omitempty"`
+	Not *Condition  `json:"not,omitempty"`
+
+	// VolumeAtLeast matches when the window total reaches the amount in ETH.
+	VolumeAtLeast *float64 `json:"volume_at_least,omitempty"`
+	// CountAtLeast matches when the window holds at least this many transactions.
+	CountAtLeast *int `json:"count_at_least,omitempty"`
+	// TxValueAtLeast matches when the current transaction alone carries at least this much ETH.
+	TxValueAtLeast *float64 `json:"tx_value_at_least,omitempty"`
+	// GrowthAtLeast matches when the window total is at least this multiple of the
+	// previous window's total. It never matches when the previous window was empty.
+	GrowthAtLeast *float64 `json:"growth_at_least,omitempty"`
+	// NewCounterparty matches the first transaction between the wallet and a counterparty.
+	NewCounterparty bool `json:"new_counterparty,omitempty"`
+	// Expr matches when the expression evaluates to true, see the txexpr package.
+	Expr string `json:"expr,omitempty"`
+
+	program *txexpr.Program
+}
+
+// Stats is the view of a wallet's activity a Condition is evaluated against.
+type Stats struct {
+	Tx              alchemyws.Transaction
+	Direction       Direction
+	Wallet          string
+	TxValue         float64
+	Total           float64
+	TotalUSD        float64 // zero unless prices are enabled
+	Count           int
+	PreviousTotal   float64
+	NewCounterparty bool
+}
+
+// volume returns the window totals compared against volume and growth conditions. Net
+// totals are compared by magnitude, so large outflows count as much as large inflows.
+func (s Stats) volume() (total, previous float64) {
+	if s.Direction == Net {
+		return math.Abs(s.Total), math.Abs(s.PreviousTotal)
+	}
+	return s.Total, s.PreviousTotal
+}
+
+// Match reports whether the condition holds for the given stats.
+// Expressions that fail to evaluate do not match.
+func (c Condition) Match(s Stats) bool {
+	ok, _ := c.eval(s)
+	return ok
+}
+
+// eval reports whether the condition holds, along with the errors of any expression
+// that failed to evaluate along the way.
+func (c Condition) eval(s Stats) (bool, error) {
+	switch {
+	case c.All != nil:
+		var errs error
+		for _, sub := range c.All {
+			ok, err := sub.eval(s)
+			errs = errors.Join(errs, err)
+			if !ok {
+				return false, errs
+			}
+		}
+		return true, errs
+	case c.Any != nil:
+		var errs error
+		for _, sub := range c.Any {
+			ok, err := sub.eval(s)
+			errs = errors.Join(errs, err)
+			if ok {
+				return true, errs
+			}
+		}
+		return false, errs
+	case c.Not != nil:
+		ok, err := c.Not.eval(s)
+		return !ok, err
+	case c.VolumeAtLeast != nil:
+		total, _ := s.volume()
+		return total >= *c.VolumeAtLeast, nil
+	case c.CountAtLeast != nil:
+		return s.Count >= *c.CountAtLeast, nil
+	case c.TxValueAtLeast != nil:
+		return s.TxValue >= *c.TxValueAtLeast, nil
+	case c.GrowthAtLeast != nil:
+		total, previous := s.volume()
+		return previous > 0 && total >= previous*(*c.GrowthAtLeast), nil
+	case c.program != nil:
+		return c.program.Eval(txexpr.Env{
+			Tx:            txexpr.NewTx(s.Tx),
+			Direction:     string(s.Direction),
+			Wallet:        s.Wallet,
+			Total:         s.Total,
+			Count:         s.Count,
+			PreviousTotal: s.PreviousTotal,
+		})
+	default:
+		return c.NewCounterparty && s.NewCounterparty, nil
+	}
+}
+
+// String renders the condition in a human readable form for alert messages.
+func (c Condition) String() string {
+	join := func(conds []Condition, sep string) string {
+		parts := make([]string, len(conds))
+		for i, sub := range conds {
+			parts[i] = sub.String()
+		}
+		return "(" + strings.Join(parts, sep) + ")"
+	}
+
+	switch {
+	case c.All != nil:
+		return join(c.All, " AND ")
+	case c.Any != nil:
+		return join(c.Any, " OR ")
+	case c.Not != nil:
+		return "NOT " + c.Not.String()
+	case c.VolumeAtLeast != nil:
+		return fmt.Sprintf("volume >= %g ETH", *c.VolumeAtLeast)
+	case c.CountAtLeast != nil:
+		return fmt.Sprintf("count >= %d", *c.CountAtLeast)
+	case c.TxValueAtLeast != nil:
+		return fmt.Sprintf("tx value >= %g ETH", *c.TxValueAtLeast)
+	case c.GrowthAtLeast != nil:
+		return fmt.Sprintf("growth >= %gx previous window", *c.GrowthAtLeast)
+	case c.Expr != "":
+		return c.Expr
+	default:
+		return "new counterparty"
+	}
+}
+
+// set counts the fields of the condition that are set.
+func (c *Condition) set() int {
+	set := 0
+	for _, ok := range []bool{
+		c.All != nil, c.Any != nil, c.Not != nil,
+		c.VolumeAtLeast != nil, c.CountAtLeast != nil, c.TxValueAtLeast != nil,
+		c.GrowthAtLeast != nil, c.NewCounterparty, c.Expr != "",
+	} {
+		if ok {
+			set++
+		}
+	}
+	return set
+}
+
+// needsCounterparty reports whether the condition matches new counterparties anywhere in its tree.
+func (c Condition) needsCounterparty() bool {
+	switch {
+	case c.All != nil:
+		return slices.ContainsFunc(c.All, Condition.needsCounterparty)
+	case c.Any != nil:
+		return slices.ContainsFunc(c.Any, Condition.needsCounterparty)
+	case c.Not != nil:
+		return c.Not.needsCounterparty()
+	default:
+		return c.NewCounterparty
+	}
+}
+
+// needsCounterparties reports whether any rule matches new counterparties.
+func needsCounterparties(rules []Rule) bool {
+	return slices.ContainsFunc(rules, func(r Rule) bool { return r.Condition.needsCounterparty() })
+}
+
+// compile validates the condition tree and compiles its expressions in place.
+func (c *Condition) compile(watchlist []string) error {
+	if set := c.set(); set != 1 {
+		return fmt.Errorf("condition must set exactly one field, got %d", set)
+	}
+
+	for i := range c.All {
+		if err := c.All[i].compile(watchlist); err != nil {
+			return err
+		}
+	}
+	for i := range c.Any {
+		if err := c.Any[i].compile(watchlist); err != nil {
+			return err
+		}
+	}
+	if c.Not != nil {
+		return c.Not.compile(watchlist)
+	}
+
+	if c.Expr != "" {
+		program, err := txexpr.Compile(c.Expr, watchlist)
+		if err != nil {
+			return err
+		}
+		c.program = program
+	}
+	return nil
+}
+
+// eval reports whether the rule's condition holds, which a rule graded only by tiers always does.
+func (r Rule) eval(s Stats) (bool, error) {
+	if len(r.Tiers) > 0 && r.Condition.set() == 0 {
+		return true, nil
+	}
+	return r.Condition.eval(s)
+}
+
+// matches reports whether the rule applies to the wallet in the given direction.
+func (r Rule) matches(direction Direction, wallet string) bool {
+	if r.Direction != "" && r.Direction != direction {
+		return false
+	}
+	if len(r.Wallets) == 0 {
+		return true
+	}
+	for _, w := range r.Wallets {
+		if strings.EqualFold(w, wallet) {
+			return true
+		}
+	}
+	return false
+}
+
+// CompileRules checks rule names are unique and every condition is well formed,
+// compiling expression conditions against the given watchlist.
+func CompileRules(rules []Rule, watchlist []string) error {
+	seen := make(map[string]struct{}, len(rules))
+	for i := range rules {
+		r := &rules[i]
+		if r.Name == "" {
+			return errors.New("rule name is required")
+		}
+		if _, dup := seen[r.Name]; dup {
+			return fmt.Errorf("duplicate rule %q", r.Name)
+		}
+		seen[r.Name] = struct{}{}
+
+		switch r.Direction {
+		case "", From, To, Net:
+		default:
+			return fmt.Errorf("rule %q: unknown direction %q", r.Name, r.Direction)
+		}
+		if err := compileTiers(r.Severity, r.Tiers); err != nil {
+			return fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+		if len(r.Tiers) > 0 && r.Condition.set() == 0 {
+			continue
+		}
+		if err := r.Condition.compile(watchlist); err != nil {
+			return fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+	}
+	return nil
+}
+
+// compileTiers checks a rule has either a known severity or tiers ascending in both
+// severity and volume.
+func compileTiers(severity notifier.Severity, tiers []Tier) error {
+	if severity != "" && !severity.Valid() {
+		return fmt.Errorf("unknown severity %q", severity)
+	}
+	if severity != "" && len(tiers) > 0 {
+		return errors.New("severity and tiers are mutually exclusive")
+	}
+	for i, t := range tiers {
+		if !t.Severity.Valid() {
+			return fmt.Errorf("tier %d: unknown severity %q", i+1, t.Severity)
+		}
+		if i > 0 && (t.Severity.Rank() <= tiers[i-1].Severity.Rank() || t.VolumeAtLeast <= tiers[i-1].VolumeAtLeast) {
+			return fmt.Errorf("tier %d: tiers must ascend in severity and volume", i+1)
+		}
+	}
+	return nil
+}
+
+// LoadRules reads a JSON array of rules from path and compiles them.
+func LoadRules(path string, watchlist []string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("parse rules: %w", err)
+	}
+	if err := CompileRules(rules, watchlist); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
@@ -0,0 +1,121 @@
+package aggregator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yermakovsa/alchemyws"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestCondition_Match(t *testing.T) {
+	stats := Stats{TxValue: 2, Total: 12, Count: 4, PreviousTotal: 3, NewCounterparty: true}
+
+	tests := []struct {
+		name string
+		cond Condition
+		want bool
+	}{
+		{"volume", Condition{VolumeAtLeast: ptr(10.0)}, true},
+		{"count", Condition{CountAtLeast: ptr(5)}, false},
+		{"tx value", Condition{TxValueAtLeast: ptr(2.0)}, true},
+		{"growth", Condition{GrowthAtLeast: ptr(4.0)}, true},
+		{"growth too small", Condition{GrowthAtLeast: ptr(5.0)}, false},
+		{"new counterparty", Condition{NewCounterparty: true}, true},
+		{"all", Condition{All: []Condition{{VolumeAtLeast: ptr(10.0)}, {CountAtLeast: ptr(5)}}}, false},
+		{"any", Condition{Any: []Condition{{VolumeAtLeast: ptr(10.0)}, {CountAtLeast: ptr(5)}}}, true},
+		{"not", Condition{Not: &Condition{CountAtLeast: ptr(5)}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.cond.Match(stats))
+		})
+	}
+}
+
+func TestCondition_NetVolumeByMagnitude(t *testing.T) {
+	outflow := Stats{Direction: Net, Total: -12, PreviousTotal: -3}
+	assert.True(t, Condition{VolumeAtLeast: ptr(10.0)}.Match(outflow))
+	assert.True(t, Condition{GrowthAtLeast: ptr(4.0)}.Match(outflow))
+	assert.False(t, Condition{VolumeAtLeast: ptr(10.0)}.Match(Stats{Direction: From, Total: -12}))
+}
+
+func TestCondition_GrowthRequiresPreviousWindow(t *testing.T) {
+	cond := Condition{GrowthAtLeast: ptr(2.0)}
+	assert.False(t, cond.Match(Stats{Total: 100}))
+}
+
+func TestCondition_String(t *testing.T) {
+	cond := Condition{All: []Condition{
+		{VolumeAtLeast: ptr(10.0)},
+		{Not: &Condition{NewCounterparty: true}},
+	}}
+	assert.Equal(t, "(volume >= 10 ETH AND NOT new counterparty)", cond.String())
+}
+
+func TestCondition_Expr(t *testing.T) {
+	rules := []Rule{{Name: "expr", Condition: Condition{All: []Condition{
+		{Expr: `tx.to in watchlist && count >= 2`},
+		{VolumeAtLeast: ptr(1.0)},
+	}}}}
+	require.NoError(t, CompileRules(rules, []string{"0xDEF"}))
+
+	stats := Stats{Tx: alchemyws.Transaction{To: "0xdef"}, Total: 2, Count: 2}
+	assert.True(t, rules[0].Condition.Match(stats))
+
+	stats.Tx.To = "0x123"
+	assert.False(t, rules[0].Condition.Match(stats))
+	assert.Equal(t, "(tx.to in watchlist && count >= 2 AND volume >= 1 ETH)", rules[0].Condition.String())
+}
+
+func TestLoadRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[
+		{"name": "burst", "direction": "from", "condition": {"count_at_least": 3}},
+		{"name": "new-large", "condition": {"all": [{"new_counterparty": true}, {"tx_value_at_least": 5}]}}
+	]`), 0o600))
+
+	rules, err := LoadRules(path, nil)
+	require.NoError(t, err)
+	assert.Len(t, rules, 2)
+	assert.Equal(t, From, rules[0].Direction)
+	assert.Equal(t, 3, *rules[0].Condition.CountAtLeast)
+}
+
+func TestValidateRules_Errors(t *testing.T) {
+	assert.ErrorContains(t, CompileRules([]Rule{{Condition: Condition{NewCounterparty: true}}}, nil), "name is required")
+	assert.ErrorContains(t, CompileRules([]Rule{
+		{Name: "a", Condition: Condition{NewCounterparty: true}},
+		{Name: "a", Condition: Condition{NewCounterparty: true}},
+	}, nil), "duplicate rule")
+	assert.ErrorContains(t, CompileRules([]Rule{{Name: "a", Direction: "sideways", Condition: Condition{NewCounterparty: true}}}, nil), "unknown direction")
+	assert.ErrorContains(t, CompileRules([]Rule{{Name: "a", Condition: Condition{
+		VolumeAtLeast: ptr(1.0), CountAtLeast: ptr(1),
+	}}}, nil), "exactly one field")
+	assert.ErrorContains(t, CompileRules([]Rule{{Name: "a", Condition: Condition{All: []Condition{{}}}}}, nil), "exactly one field")
+	assert.ErrorContains(t, CompileRules([]Rule{{Name: "a", Condition: Condition{Expr: "tx.value"}}}, nil), "compile")
+}
+
+func TestCondition_ExprErrorDoesNotMatch(t *testing.T) {
+	rules := []Rule{{Name: "expr", Condition: Condition{Any: []Condition{
+		{Expr: `int(wallet) > 0`},
+		{VolumeAtLeast: ptr(10.0)},
+	}}}}
+	require.NoError(t, CompileRules(rules, nil))
+
+	ok, err := rules[0].Condition.eval(Stats{Wallet: "0xabc", Total: 1})
+	assert.False(t, ok)
+	assert.ErrorContains(t, err, "int(wallet)")
+	assert.False(t, rules[0].Condition.Match(Stats{Wallet: "0xabc", Total: 1}))
+
+	ok, err = rules[0].Condition.eval(Stats{Wallet: "0xabc", Total: 10})
+	assert.True(t, ok, "the other branch still matches")
+	assert.Error(t, err)
+}
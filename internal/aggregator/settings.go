@@ -0,0 +1,98 @@
+package aggregator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Thresholds are the volume alert thresholds of a direction. A configured USD threshold
+// replaces the ETH one.
+type Thresholds struct {
+	ETH float64
+	USD float64
+}
+
+// SetThresholds replaces the volume alert thresholds of a direction. Transactions already
+// in the window are evaluated against the new thresholds when the wallet's next one arrives.
+func (a *Aggregator) SetThresholds(direction Direction, t Thresholds) error {
+	switch direction {
+	case From, To, Net:
+	default:
+		return fmt.Errorf("unknown direction %q", direction)
+	}
+	if t.ETH < 0 || t.USD < 0 {
+		return errors.New("thresholds must not be negative")
+	}
+	if t.USD > 0 && a.prices == nil {
+		return errors.New("USD thresholds require a price feed")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.thresholds[direction] = t
+	return nil
+}
+
+// SetWalletThreshold replaces the thresholds of every direction with an ETH threshold for one wallet.
+func (a *Aggregator) SetWalletThreshold(wallet string, eth float64) error {
+	if eth < 0 {
+		return errors.New("thresholds must not be negative")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.walletThresholds[strings.ToLower(wallet)] = eth
+	return nil
+}
+
+// ClearWalletThreshold restores the direction thresholds for a wallet.
+func (a *Aggregator) ClearWalletThreshold(wallet string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.walletThresholds, strings.ToLower(wallet))
+}
+
+// AddNetWallet enables the net direction for a wallet.
+func (a *Aggregator) AddNetWallet(wallet string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.netWallets[strings.ToLower(wallet)] = struct{}{}
+}
+
+// RemoveNetWallet stops aggregating the net flow of a wallet. Its window is kept, so a
+// wallet removed by mistake resumes where it left off when added back.
+func (a *Aggregator) RemoveNetWallet(wallet string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.netWallets, strings.ToLower(wallet))
+}
+
+// SetRules replaces the alert rules, which must have been compiled with CompileRules.
+// Rules that keep their name keep their cooldowns and severities, those removed forget them. Counterparties
+// are learned from the first rule matching new counterparties on, after a new warm-up.
+func (a *Aggregator) SetRules(rules []Rule) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rules = rules
+
+	track := needsCounterparties(rules)
+	switch {
+	case track && !a.trackCounterparties:
+		a.started = a.now()
+	case !track:
+		clear(a.counterparties)
+	}
+	a.trackCounterparties = track
+
+	names := make(map[string]struct{}, len(rules))
+	for _, r := range rules {
+		names[r.Name] = struct{}{}
+	}
+	for name := range a.ruleAlerted {
+		if _, ok := names[name]; !ok {
+			delete(a.ruleAlerted, name)
+			delete(a.severities, "rule:"+name)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package aggregator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yermakovsa/alchemyws"
+	"github.com/yermakovsa/eth-watcher/internal/notifier"
+)
+
+func TestAggregator_SetThresholds(t *testing.T) {
+	mock := &MockNotifier{}
+	agg := NewAggregator(context.Background(), mock, 100, time.Minute, time.Minute)
+	tx := alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{
+		Hash:  "0x1",
+		From:  "0xabc",
+		To:    "0xdef",
+		Value: "0xde0b6b3a7640000", // 1 ETH
+	}}
+
+	agg.Process(context.Background(), tx, To)
+	require.NoError(t, agg.SetThresholds(From, Thresholds{ETH: 1}))
+	agg.Process(context.Background(), tx, From)
+	time.Sleep(10 * time.Millisecond)
+
+	mock.mu.Lock()
+	require.Len(t, mock.alerts, 1, "only the edited direction uses the new threshold")
+	assert.Equal(t, string(From), mock.alerts[0].Direction)
+	mock.mu.Unlock()
+
+	snap := agg.Snapshot()
+	assert.Equal(t, Thresholds{ETH: 1}, snap.Thresholds[From])
+	assert.Equal(t, Thresholds{ETH: 100}, snap.Thresholds[To])
+}
+
+func TestAggregator_SetThresholds_Invalid(t *testing.T) {
+	agg := NewAggregator(context.Background(), &MockNotifier{}, 1, time.Minute, time.Minute)
+
+	assert.EqualError(t, agg.SetThresholds("sideways", Thresholds{ETH: 1}), `unknown direction "sideways"`)
+	assert.EqualError(t, agg.SetThresholds(From, Thresholds{ETH: -1}), "thresholds must not be negative")
+	assert.EqualError(t, agg.SetThresholds(From, Thresholds{USD: 1000}), "USD thresholds require a price feed")
+	assert.Equal(t, Thresholds{ETH: 1}, agg.Snapshot().Thresholds[From])
+}
+
+func TestAggregator_WalletThreshold(t *testing.T) {
+	mock := &MockNotifier{}
+	agg := NewAggregator(context.Background(), mock, 100, time.Minute, 0)
+	tx := func(hash, from string) alchemyws.MinedTxEvent {
+		return alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{
+			Hash:  hash,
+			From:  from,
+			Value: "0xde0b6b3a7640000", // 1 ETH
+		}}
+	}
+
+	require.NoError(t, agg.SetWalletThreshold("0xABC", 1))
+	assert.Error(t, agg.SetWalletThreshold("0xabc", -1))
+	agg.Process(context.Background(), tx("0x1", "0xabc"), From)
+	agg.Process(context.Background(), tx("0x2", "0xdef"), From)
+	assert.Equal(t, map[string]float64{"0xabc": 1}, agg.Snapshot().WalletThresholds)
+
+	agg.ClearWalletThreshold("0xabc")
+	agg.Process(context.Background(), tx("0x3", "0xabc"), From)
+	time.Sleep(10 * time.Millisecond)
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	require.Len(t, mock.alerts, 1, "only the wallet with its own threshold alerts")
+	assert.Equal(t, "0x1", mock.alerts[0].TxID)
+	assert.Empty(t, agg.Snapshot().WalletThresholds)
+}
+
+func TestAggregator_NetWallets(t *testing.T) {
+	mock := &MockNotifier{}
+	agg := NewAggregator(context.Background(), mock, 100, time.Minute, 0, WithNetFlow(nil, 1))
+	tx := func(hash string) alchemyws.MinedTxEvent {
+		return alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{
+			Hash:  hash,
+			From:  "0xdef",
+			To:    "0xabc",
+			Value: "0xde0b6b3a7640000", // 1 ETH
+		}}
+	}
+
+	agg.Process(context.Background(), tx("0x1"), Net)
+	agg.AddNetWallet("0xABC")
+	agg.Process(context.Background(), tx("0x2"), Net)
+	agg.RemoveNetWallet("0xabc")
+	agg.Process(context.Background(), tx("0x3"), Net)
+	time.Sleep(10 * time.Millisecond)
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	require.Len(t, mock.alerts, 1)
+	assert.Equal(t, notifier.AlertNetFlow, mock.alerts[0].Type)
+	assert.Equal(t, "0x2", mock.alerts[0].TxID)
+}
+
+func TestAggregator_SetRules(t *testing.T) {
+	mock := &MockNotifier{}
+	agg := NewAggregator(context.Background(), mock, 100, time.Minute, time.Hour)
+	tx := func(hash string) alchemyws.MinedTxEvent {
+		return alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{
+			Hash:  hash,
+			From:  "0xabc",
+			Value: "0xde0b6b3a7640000", // 1 ETH
+		}}
+	}
+	rules := func(names ...string) []Rule {
+		var res []Rule
+		for _, name := range names {
+			res = append(res, Rule{Name: name, Condition: Condition{TxValueAtLeast: ptr(1.0)}})
+		}
+		require.NoError(t, CompileRules(res, nil))
+		return res
+	}
+
+	agg.Process(context.Background(), tx("0x1"), From)
+	agg.SetRules(rules("large"))
+	agg.Process(context.Background(), tx("0x2"), From)
+	agg.SetRules(rules("large", "other"))
+	agg.Process(context.Background(), tx("0x3"), From)
+	time.Sleep(10 * time.Millisecond)
+
+	mock.mu.Lock()
+	keys := []string{}
+	for _, alert := range mock.alerts {
+		keys = append(keys, alert.Key())
+	}
+	mock.mu.Unlock()
+	assert.ElementsMatch(t, []string{"large", "other"}, keys, "a kept rule keeps its cooldown")
+	assert.Equal(t, []string{"large", "other"}, []string{agg.Snapshot().Rules[0].Name, agg.Snapshot().Rules[1].Name})
+
+	agg.SetRules(nil)
+	agg.mu.Lock()
+	assert.Empty(t, agg.ruleAlerted, "removed rules forget their cooldowns")
+	assert.NotContains(t, agg.severities, "rule:large", "removed rules forget their severities")
+	agg.mu.Unlock()
+
+	agg.SetRules(rules("large"))
+	agg.Process(context.Background(), tx("0x4"), From)
+	time.Sleep(10 * time.Millisecond)
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	require.Len(t, mock.alerts, 3, "a rule added back fires again")
+	assert.Equal(t, "0x4", mock.alerts[2].TxID)
+}
@@ -0,0 +1,108 @@
+package aggregator
+
+import (
+	"sort"
+	"time"
+)
+
+// Snapshot is a copy of the aggregation state taken at a single point in time.
+type Snapshot struct {
+	Time     time.Time
+	Window   time.Duration
+	Cooldown time.Duration
+	// Thresholds holds the volume alert thresholds of each direction.
+	Thresholds map[Direction]Thresholds
+	// WalletThresholds holds the ETH thresholds that replace those of the directions for single wallets.
+	WalletThresholds map[string]float64
+	// Rules holds the alert rules in effect, in evaluation order.
+	Rules []Rule
+	// Wallets holds every wallet with records in the current window or a past alert,
+	// sorted by direction and address.
+	Wallets []WalletSnapshot
+}
+
+// WalletSnapshot is the current aggregation window of a wallet in one direction.
+type WalletSnapshot struct {
+	Wallet    string
+	Label     string
+	Direction Direction
+	Total     float64 // net flows are signed
+	TotalUSD  float64
+	Count     int
+	// Records lists the transactions in the current window, oldest first.
+	Records []TxRecord
+	// LastAlert is the time of the last volume alert, zero if there was none.
+	LastAlert         time.Time
+	CooldownRemaining time.Duration
+}
+
+// Snapshot returns the current window of every active wallet. The window ends now rather
+// than at the newest record, so totals shrink as records age out between transactions.
+// It is safe to call concurrently with Process.
+func (a *Aggregator) Snapshot() Snapshot {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	snap := Snapshot{
+		Time:       now,
+		Window:     a.window,
+		Cooldown:   a.cooldown,
+		Thresholds: make(map[Direction]Thresholds, len(a.thresholds)),
+	}
+	for direction, t := range a.thresholds {
+		snap.Thresholds[direction] = t
+	}
+	snap.WalletThresholds = make(map[string]float64, len(a.walletThresholds))
+	for wallet, eth := range a.walletThresholds {
+		snap.WalletThresholds[wallet] = eth
+	}
+	snap.Rules = append([]Rule(nil), a.rules...)
+
+	for _, direction := range []Direction{From, To, Net} {
+		wallets := make(map[string]struct{})
+		for wallet := range a.data[direction] {
+			wallets[wallet] = struct{}{}
+		}
+		for wallet := range a.alerted[direction] {
+			wallets[wallet] = struct{}{}
+		}
+
+		for wallet := range wallets {
+			ws := WalletSnapshot{
+				Wallet:    wallet,
+				Label:     a.book.Label(wallet),
+				Direction: direction,
+			}
+
+			for _, r := range a.data[direction][wallet] {
+				if now.Sub(r.Timestamp) > a.window {
+					continue
+				}
+				ws.Records = append(ws.Records, r)
+				ws.Total += r.Amount
+				ws.TotalUSD += r.USD
+				ws.Count++
+			}
+
+			if last, ok := a.alerted[direction][wallet]; ok {
+				ws.LastAlert = last
+				ws.CooldownRemaining = max(a.cooldown-now.Sub(last), 0)
+			}
+
+			if ws.Count > 0 || !ws.LastAlert.IsZero() {
+				snap.Wallets = append(snap.Wallets, ws)
+			}
+		}
+	}
+
+	order := map[Direction]int{From: 0, To: 1, Net: 2}
+	sort.Slice(snap.Wallets, func(i, j int) bool {
+		wi, wj := snap.Wallets[i], snap.Wallets[j]
+		if wi.Direction != wj.Direction {
+			return order[wi.Direction] < order[wj.Direction]
+		}
+		return wi.Wallet < wj.Wallet
+	})
+	return snap
+}
@@ -0,0 +1,66 @@
+package aggregator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yermakovsa/alchemyws"
+	"github.com/yermakovsa/eth-watcher/internal/addressbook"
+)
+
+func TestAggregator_Snapshot(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	book := addressbook.New(map[string]addressbook.Entry{"0xabc": {Label: "Hot wallet"}})
+	agg := NewAggregator(context.Background(), &MockNotifier{}, 1.5, time.Minute, 30*time.Second,
+		WithClock(func() time.Time { return now }),
+		WithAddressBook(book),
+	)
+
+	tx := func(hash, from, to string) alchemyws.MinedTxEvent {
+		return alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{
+			Hash:  hash,
+			From:  from,
+			To:    to,
+			Value: "0xde0b6b3a7640000", // 1 ETH
+		}}
+	}
+	agg.Process(context.Background(), tx("0x1", "0xabc", "0xdef"), From)
+	now = now.Add(10 * time.Second)
+	agg.Process(context.Background(), tx("0x2", "0xabc", "0xdef"), From)
+	agg.Process(context.Background(), tx("0x3", "0x123", "0xdef"), To)
+	now = now.Add(10 * time.Second)
+
+	snap := agg.Snapshot()
+	assert.Equal(t, time.Minute, snap.Window)
+	assert.Equal(t, 30*time.Second, snap.Cooldown)
+	assert.Equal(t, Thresholds{ETH: 1.5}, snap.Thresholds[From])
+	require.Len(t, snap.Wallets, 2)
+
+	from := snap.Wallets[0]
+	assert.Equal(t, "0xabc", from.Wallet)
+	assert.Equal(t, "Hot wallet", from.Label)
+	assert.Equal(t, From, from.Direction)
+	assert.InDelta(t, 2.0, from.Total, 1e-9)
+	assert.Equal(t, 2, from.Count)
+	require.Len(t, from.Records, 2)
+	assert.Equal(t, "0x1", from.Records[0].Hash)
+	assert.Equal(t, now.Add(-10*time.Second), from.LastAlert)
+	assert.Equal(t, 20*time.Second, from.CooldownRemaining)
+
+	to := snap.Wallets[1]
+	assert.Equal(t, "0xdef", to.Wallet)
+	assert.Equal(t, To, to.Direction)
+	assert.Equal(t, 1, to.Count)
+	assert.True(t, to.LastAlert.IsZero())
+
+	// Once the records age out only the alerted wallet remains, with an empty window
+	now = now.Add(2 * time.Minute)
+	snap = agg.Snapshot()
+	require.Len(t, snap.Wallets, 1)
+	assert.Equal(t, "0xabc", snap.Wallets[0].Wallet)
+	assert.Zero(t, snap.Wallets[0].Count)
+	assert.Zero(t, snap.Wallets[0].CooldownRemaining)
+}
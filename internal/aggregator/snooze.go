@@ -0,0 +1,110 @@
+package aggregator
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yermakovsa/eth-watcher/internal/notifier"
+)
+
+// Snooze holds back the alerts about a wallet until a given time. An empty Rule snoozes
+// every alert about the wallet, otherwise only those whose key is Rule.
+type Snooze struct {
+	Wallet string
+	Rule   string
+	Until  time.Time
+}
+
+type snoozeKey struct {
+	wallet string
+	rule   string
+}
+
+// Snoozes holds the wallets and rules whose alerts operators have snoozed. Rules are named
+// by alert key: the rule or log spec name, or the alert type for built-in conditions such
+// as threshold or net_flow. The alerts are held back by a notifier.Muter, so snoozes apply
+// to every alert source. It is safe for concurrent use.
+type Snoozes struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	snoozes map[snoozeKey]time.Time
+}
+
+// NewSnoozes creates an empty set of snoozes.
+func NewSnoozes() *Snoozes {
+	return &Snoozes{
+		now:     time.Now,
+		snoozes: make(map[snoozeKey]time.Time),
+	}
+}
+
+// Snooze holds back the alerts about a wallet produced by rule, or by every rule when rule
+// is empty, until the given time.
+func (s *Snoozes) Snooze(wallet, rule string, until time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snoozes[snoozeKey{wallet: strings.ToLower(wallet), rule: rule}] = until
+}
+
+// Unsnooze resumes the alerts held back by a snooze, reporting whether it was active.
+func (s *Snoozes) Unsnooze(wallet, rule string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+
+	key := snoozeKey{wallet: strings.ToLower(wallet), rule: rule}
+	_, ok := s.snoozes[key]
+	delete(s.snoozes, key)
+	return ok
+}
+
+// Active returns the snoozes that have not ended, sorted by wallet and rule.
+func (s *Snoozes) Active() []Snooze {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+
+	snoozes := make([]Snooze, 0, len(s.snoozes))
+	for key, until := range s.snoozes {
+		snoozes = append(snoozes, Snooze{Wallet: key.wallet, Rule: key.rule, Until: until})
+	}
+	sort.Slice(snoozes, func(i, j int) bool {
+		if snoozes[i].Wallet != snoozes[j].Wallet {
+			return snoozes[i].Wallet < snoozes[j].Wallet
+		}
+		return snoozes[i].Rule < snoozes[j].Rule
+	})
+	return snoozes
+}
+
+// Snoozed reports whether the alert's wallet is snoozed for every rule or for the alert's own.
+// A nil *Snoozes snoozes nothing.
+func (s *Snoozes) Snoozed(alert notifier.Alert) bool {
+	if s == nil || alert.Wallet == "" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	wallet := strings.ToLower(alert.Wallet)
+	for _, rule := range []string{"", alert.Key()} {
+		if until, ok := s.snoozes[snoozeKey{wallet: wallet, rule: rule}]; ok && now.Before(until) {
+			return true
+		}
+	}
+	return false
+}
+
+// prune forgets the snoozes that have ended. Must be called with s.mu held.
+func (s *Snoozes) prune() {
+	now := s.now()
+	for key, until := range s.snoozes {
+		if !now.Before(until) {
+			delete(s.snoozes, key)
+		}
+	}
+}
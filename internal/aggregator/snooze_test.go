@@ -0,0 +1,46 @@
+package aggregator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yermakovsa/eth-watcher/internal/notifier"
+)
+
+func TestSnoozes_Rule(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	snoozes := NewSnoozes()
+	snoozes.now = func() time.Time { return now }
+
+	snoozes.Snooze("0xABC", "threshold", now.Add(time.Hour))
+	snoozes.Snooze("0xdef", "net_flow", now.Add(time.Hour))
+	assert.True(t, snoozes.Snoozed(notifier.Alert{Type: notifier.AlertThreshold, Wallet: "0xabc"}))
+	assert.False(t, snoozes.Snoozed(notifier.Alert{Type: notifier.AlertThreshold, Wallet: "0xdef"}), "only the snoozed rule is held back")
+	assert.True(t, snoozes.Snoozed(notifier.Alert{Type: notifier.AlertNetFlow, Wallet: "0xdef"}))
+
+	assert.Equal(t, []Snooze{
+		{Wallet: "0xabc", Rule: "threshold", Until: now.Add(time.Hour)},
+		{Wallet: "0xdef", Rule: "net_flow", Until: now.Add(time.Hour)},
+	}, snoozes.Active())
+
+	now = now.Add(time.Hour)
+	assert.Empty(t, snoozes.Active(), "snoozes end on time")
+	assert.False(t, snoozes.Snoozed(notifier.Alert{Type: notifier.AlertThreshold, Wallet: "0xabc"}))
+}
+
+func TestSnoozes_EveryRule(t *testing.T) {
+	snoozes := NewSnoozes()
+	snoozes.Snooze("0xabc", "", time.Now().Add(time.Hour))
+
+	assert.True(t, snoozes.Snoozed(notifier.Alert{Type: notifier.AlertThreshold, Wallet: "0xabc"}))
+	assert.True(t, snoozes.Snoozed(notifier.Alert{Type: notifier.AlertRule, Rule: "large", Wallet: "0xABC"}))
+	assert.False(t, snoozes.Snoozed(notifier.Alert{Type: notifier.AlertThreshold, Wallet: "0xdef"}))
+
+	assert.True(t, snoozes.Unsnooze("0xabc", ""))
+	assert.False(t, snoozes.Unsnooze("0xabc", ""))
+	assert.False(t, snoozes.Snoozed(notifier.Alert{Type: notifier.AlertThreshold, Wallet: "0xabc"}))
+
+	var none *Snoozes
+	assert.False(t, none.Snoozed(notifier.Alert{Type: notifier.AlertThreshold, Wallet: "0xabc"}))
+}
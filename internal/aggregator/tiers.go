@@ -0,0 +1,97 @@
+package aggregator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yermakovsa/eth-watcher/internal/notifier"
+)
+
+// WithThresholdTiers grades volume alerts by how far the window total reaches past the
+// threshold. The threshold itself raises warnings, info times the threshold raises info
+// alerts and critical times the threshold raises critical ones. A zero factor disables its tier.
+// Without a positive threshold every total reaches it, so such alerts stay warnings.
+func WithThresholdTiers(info, critical float64) Option {
+	return func(a *Aggregator) {
+		a.infoFactor = info
+		a.criticalFactor = critical
+	}
+}
+
+// Tier is a severity level of a rule, reached when the window total is at least VolumeAtLeast ETH.
+type Tier struct {
+	Severity      notifier.Severity `json:"severity"`
+	VolumeAtLeast float64           `json:"volume_at_least"`
+}
+
+// thresholdSeverity returns the severity of the highest volume tier the window total
+// reaches, reporting false when it reaches none. Must be called with a.mu held.
+func (a *Aggregator) thresholdSeverity(direction Direction, wallet string, stats Stats) (notifier.Severity, bool) {
+	total, threshold := a.measure(direction, wallet, stats)
+	switch {
+	case threshold <= 0:
+		return notifier.SeverityWarning, total >= threshold
+	case a.criticalFactor > 0 && total >= threshold*a.criticalFactor:
+		return notifier.SeverityCritical, true
+	case total >= threshold:
+		return notifier.SeverityWarning, true
+	case a.infoFactor > 0 && total >= threshold*a.infoFactor:
+		return notifier.SeverityInfo, true
+	default:
+		return "", false
+	}
+}
+
+// severity returns the severity of the highest tier the window total reaches, or the rule's
+// own when it has no tiers. It reports false when the rule has tiers and none is reached.
+func (r Rule) severity(s Stats) (notifier.Severity, bool) {
+	if len(r.Tiers) == 0 {
+		return r.Severity, true
+	}
+	total, _ := s.volume()
+	for i := len(r.Tiers) - 1; i >= 0; i-- {
+		if total >= r.Tiers[i].VolumeAtLeast {
+			return r.Tiers[i].Severity, true
+		}
+	}
+	return "", false
+}
+
+// tier renders the tier of the given severity for alert messages.
+func (r Rule) tier(severity notifier.Severity) string {
+	for _, t := range r.Tiers {
+		if t.Severity == severity {
+			return fmt.Sprintf("%s (volume >= %g ETH)", severity, t.VolumeAtLeast)
+		}
+	}
+	return string(severity)
+}
+
+// cooledDownAt is cooledDown for graded alerts. An alert more severe than the last one
+// recorded for key in scope fires within the cooldown, so a window that keeps growing
+// escalates from warning to critical. It returns the severity escalated from, if any.
+// Must be called with a.mu held.
+func (a *Aggregator) cooledDownAt(alertType notifier.AlertType, alerted map[string]time.Time, scope, key string, severity notifier.Severity) (notifier.Severity, bool) {
+	if a.severities == nil {
+		a.severities = make(map[string]map[string]notifier.Severity)
+	}
+	severities := a.severities[scope]
+	if severities == nil {
+		severities = make(map[string]notifier.Severity)
+		a.severities[scope] = severities
+	}
+
+	now := a.now()
+	last, ok := alerted[key]
+	previous := severities[key]
+	if ok && now.Sub(last) <= a.cooldown && severity.Rank() > previous.Rank() {
+		alerted[key] = now
+		severities[key] = severity
+		return previous, true
+	}
+	if !a.cooledDown(alertType, alerted, key) {
+		return "", false
+	}
+	severities[key] = severity
+	return "", true
+}
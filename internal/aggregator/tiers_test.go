@@ -0,0 +1,147 @@
+package aggregator
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yermakovsa/alchemyws"
+	"github.com/yermakovsa/eth-watcher/internal/notifier"
+)
+
+// ethTx sends the given whole amount of ETH from 0xabc.
+func ethTx(hash string, eth int64) alchemyws.MinedTxEvent {
+	return alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{
+		Hash:  hash,
+		From:  "0xabc",
+		Value: "0x" + new(big.Int).Mul(big.NewInt(eth), big.NewInt(1e18)).Text(16),
+	}}
+}
+
+func TestAggregator_ThresholdTiersEscalateWithinCooldown(t *testing.T) {
+	mock := &MockNotifier{}
+	agg := NewAggregator(context.Background(), mock, 10, time.Minute, time.Minute, WithThresholdTiers(0.5, 5))
+
+	agg.Process(context.Background(), ethTx("0x1", 6), From)  // 6 ETH: info
+	agg.Process(context.Background(), ethTx("0x2", 1), From)  // 7 ETH: info again, cooling down
+	agg.Process(context.Background(), ethTx("0x3", 5), From)  // 12 ETH: warning
+	agg.Process(context.Background(), ethTx("0x4", 40), From) // 52 ETH: critical
+	agg.Process(context.Background(), ethTx("0x5", 50), From) // 102 ETH: critical again, cooling down
+	time.Sleep(10 * time.Millisecond)
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	require.Len(t, mock.alerts, 3)
+	var severities []notifier.Severity
+	for _, alert := range mock.alerts {
+		severities = append(severities, alert.Severity)
+	}
+	assert.ElementsMatch(t, []notifier.Severity{notifier.SeverityInfo, notifier.SeverityWarning, notifier.SeverityCritical}, severities)
+	for _, alert := range mock.alerts {
+		if alert.Severity == notifier.SeverityCritical {
+			assert.Contains(t, alert.Fields, notifier.Field{Name: "Escalated from", Value: "warning"})
+		}
+	}
+}
+
+func TestAggregator_ThresholdWithoutTiersIsWarning(t *testing.T) {
+	mock := &MockNotifier{}
+	agg := NewAggregator(context.Background(), mock, 10, time.Minute, time.Minute)
+
+	agg.Process(context.Background(), ethTx("0x1", 6), From)
+	agg.Process(context.Background(), ethTx("0x2", 500), From)
+	agg.Process(context.Background(), ethTx("0x3", 500), From)
+	time.Sleep(10 * time.Millisecond)
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	require.Len(t, mock.alerts, 1, "without tiers the cooldown holds back every later alert")
+	assert.Equal(t, notifier.SeverityWarning, mock.alerts[0].Severity)
+}
+
+func TestAggregator_ThresholdTiersNeedThreshold(t *testing.T) {
+	mock := &MockNotifier{}
+	agg := NewAggregator(context.Background(), mock, 0, time.Minute, 0, WithThresholdTiers(0.5, 5))
+
+	agg.Process(context.Background(), ethTx("0x1", 1), From)
+	agg.Process(context.Background(), ethTx("0x2", 500), From)
+	time.Sleep(10 * time.Millisecond)
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	require.Len(t, mock.alerts, 2)
+	for _, alert := range mock.alerts {
+		assert.Equal(t, notifier.SeverityWarning, alert.Severity, "a zero threshold is not graded critical")
+	}
+}
+
+func TestAggregator_RuleTiers(t *testing.T) {
+	mock := &MockNotifier{}
+	rules := []Rule{{Name: "outflow", Direction: From, Tiers: []Tier{
+		{Severity: notifier.SeverityWarning, VolumeAtLeast: 100},
+		{Severity: notifier.SeverityCritical, VolumeAtLeast: 1000},
+	}}}
+	require.NoError(t, CompileRules(rules, nil))
+	agg := NewAggregator(context.Background(), mock, 1_000_000, time.Minute, time.Minute, WithRules(rules))
+
+	agg.Process(context.Background(), ethTx("0x1", 50), From)
+	agg.Process(context.Background(), ethTx("0x2", 100), From)
+	agg.Process(context.Background(), ethTx("0x3", 100), From)
+	agg.Process(context.Background(), ethTx("0x4", 900), From)
+	time.Sleep(10 * time.Millisecond)
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	require.Len(t, mock.alerts, 2)
+	byTx := map[string]notifier.Alert{}
+	for _, alert := range mock.alerts {
+		byTx[alert.TxID] = alert
+	}
+	assert.Equal(t, notifier.SeverityWarning, byTx["0x2"].Severity)
+	assert.Equal(t, notifier.SeverityCritical, byTx["0x4"].Severity)
+	assert.Equal(t, notifier.Field{Name: "Tier", Value: "critical (volume >= 1000 ETH)"}, byTx["0x4"].Fields[0])
+	assert.Contains(t, byTx["0x4"].Fields, notifier.Field{Name: "Escalated from", Value: "warning"})
+}
+
+func TestRule_Severity(t *testing.T) {
+	rule := Rule{Severity: notifier.SeverityInfo}
+	severity, ok := rule.severity(Stats{})
+	assert.True(t, ok)
+	assert.Equal(t, notifier.SeverityInfo, severity)
+
+	rule = Rule{Tiers: []Tier{
+		{Severity: notifier.SeverityInfo, VolumeAtLeast: 10},
+		{Severity: notifier.SeverityCritical, VolumeAtLeast: 100},
+	}}
+	_, ok = rule.severity(Stats{Total: 5})
+	assert.False(t, ok)
+	severity, _ = rule.severity(Stats{Total: 50})
+	assert.Equal(t, notifier.SeverityInfo, severity)
+	severity, _ = rule.severity(Stats{Total: 100})
+	assert.Equal(t, notifier.SeverityCritical, severity)
+	severity, _ = rule.severity(Stats{Direction: Net, Total: -100})
+	assert.Equal(t, notifier.SeverityCritical, severity, "net outflows reach tiers by magnitude")
+	_, ok = rule.severity(Stats{Direction: From, Total: -100})
+	assert.False(t, ok)
+}
+
+func TestCompileRules_TierErrors(t *testing.T) {
+	cond := Condition{NewCounterparty: true}
+	assert.ErrorContains(t, CompileRules([]Rule{{Name: "a", Condition: cond, Severity: "urgent"}}, nil), `unknown severity "urgent"`)
+	assert.ErrorContains(t, CompileRules([]Rule{{Name: "a", Condition: cond, Severity: notifier.SeverityInfo, Tiers: []Tier{
+		{Severity: notifier.SeverityWarning, VolumeAtLeast: 1},
+	}}}, nil), "mutually exclusive")
+	assert.ErrorContains(t, CompileRules([]Rule{{Name: "a", Tiers: []Tier{
+		{Severity: notifier.SeverityWarning, VolumeAtLeast: 10},
+		{Severity: notifier.SeverityCritical, VolumeAtLeast: 5},
+	}}}, nil), "tier 2: tiers must ascend")
+	assert.ErrorContains(t, CompileRules([]Rule{{Name: "a", Tiers: []Tier{
+		{Severity: notifier.SeverityCritical, VolumeAtLeast: 10},
+		{Severity: notifier.SeverityWarning, VolumeAtLeast: 50},
+	}}}, nil), "tier 2: tiers must ascend")
+	assert.ErrorContains(t, CompileRules([]Rule{{Name: "a"}}, nil), "exactly one field", "rules without tiers need a condition")
+	assert.NoError(t, CompileRules([]Rule{{Name: "a", Tiers: []Tier{{Severity: notifier.SeverityWarning, VolumeAtLeast: 10}}}}, nil))
+}
@@ -0,0 +1,262 @@
+package alerting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yermakovsa/eth-watcher/internal/logging"
+	"github.com/yermakovsa/eth-watcher/internal/metrics"
+	"github.com/yermakovsa/eth-watcher/internal/notifier"
+)
+
+// maxRecords bounds how many recent alerts are kept for acknowledgement.
+const maxRecords = 1000
+
+// ErrUnknownAlert is returned for an alert ID that was never sent or is no longer kept.
+var ErrUnknownAlert = errors.New("unknown alert")
+
+// Snoozer holds back the alerts about a wallet produced by a rule, or by every rule when rule is empty.
+type Snoozer interface {
+	Snooze(wallet, rule string, until time.Time)
+}
+
+// Option configures optional Tracker behaviour.
+type Option func(*Tracker)
+
+// WithEscalation sends alerts nobody acknowledged or snoozed within after to n as well.
+func WithEscalation(n notifier.Notifier, after time.Duration) Option {
+	return func(t *Tracker) {
+		t.escalation = n
+		t.escalateAfter = after
+	}
+}
+
+// WithMetrics counts escalated alerts.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(t *Tracker) {
+		t.metrics = m
+	}
+}
+
+// WithLogger sets the logger used instead of the default one.
+func WithLogger(logger *slog.Logger) Option {
+	return func(t *Tracker) {
+		t.logger = logger
+	}
+}
+
+// Record is a sent alert and what operators did about it.
+type Record struct {
+	Alert   notifier.Alert
+	FiredAt time.Time
+	// AckedAt and AckedBy are set once the alert is acknowledged or snoozed
+	AckedAt time.Time
+	AckedBy string
+	// SnoozedUntil is the end of the snooze of the alert's wallet and rule, if one was set from it
+	SnoozedUntil time.Time
+	EscalatedAt  time.Time
+}
+
+// Status summarizes the record as open, escalated or acknowledged.
+func (r Record) Status() string {
+	switch {
+	case !r.AckedAt.IsZero():
+		return "acknowledged"
+	case !r.EscalatedAt.IsZero():
+		return "escalated"
+	default:
+		return "open"
+	}
+}
+
+// Tracker gives every alert an ID, keeps the recent ones so operators can acknowledge or
+// snooze them, and escalates those left unacknowledged. It is safe for concurrent use.
+type Tracker struct {
+	next          notifier.Notifier
+	snoozer       Snoozer
+	escalation    notifier.Notifier
+	escalateAfter time.Duration
+	metrics       *metrics.Metrics
+	logger        *slog.Logger
+	now           func() time.Time
+
+	mu      sync.Mutex
+	records map[string]*record
+	order   []string // IDs oldest first
+}
+
+type record struct {
+	Record
+	timer *time.Timer
+}
+
+// New wraps next. Snoozes set from an alert are applied through snoozer.
+func New(next notifier.Notifier, snoozer Snoozer, opts ...Option) *Tracker {
+	t := &Tracker{
+		next:    next,
+		snoozer: snoozer,
+		now:     time.Now,
+		records: make(map[string]*record),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	t.logger = logging.Component(t.logger, "alerting")
+	return t
+}
+
+// Notify records the alert, assigning an ID if it has none, and forwards it. With
+// escalation enabled, the alert is escalated unless acknowledged in time.
+func (t *Tracker) Notify(ctx context.Context, alert notifier.Alert) error {
+	if alert.ID == "" {
+		alert.ID = notifier.NewID()
+	}
+
+	t.mu.Lock()
+	r := &record{Record: Record{Alert: alert, FiredAt: t.now()}}
+	if t.escalation != nil {
+		// The escalation outlives the delivery, but keeps its trace
+		escalateCtx := context.WithoutCancel(ctx)
+		r.timer = time.AfterFunc(t.escalateAfter, func() { t.escalate(escalateCtx, alert) })
+	}
+	t.records[alert.ID] = r
+	t.order = append(t.order, alert.ID)
+	if len(t.order) > maxRecords {
+		t.evict()
+	}
+	t.mu.Unlock()
+
+	return t.next.Notify(ctx, alert)
+}
+
+// Ack acknowledges an alert on behalf of by, cancelling its escalation.
+func (t *Tracker) Ack(id, by string) (Record, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r, ok := t.records[id]
+	if !ok {
+		return Record{}, fmt.Errorf("%w %q", ErrUnknownAlert, id)
+	}
+	t.ack(r, by)
+	t.logger.Info("Alert acknowledged", "alert_id", id, "by", by)
+	return r.Record, nil
+}
+
+// Snooze holds back further alerts about the alert's wallet from the same rule for d,
+// acknowledging the alert on behalf of by. Critical alerts are never held back, so they
+// cannot be snoozed.
+func (t *Tracker) Snooze(id string, d time.Duration, by string) (Record, error) {
+	if d <= 0 {
+		return Record{}, errors.New("snooze duration must be positive")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r, ok := t.records[id]
+	if !ok {
+		return Record{}, fmt.Errorf("%w %q", ErrUnknownAlert, id)
+	}
+	if r.Alert.Wallet == "" {
+		return Record{}, errors.New("alert is not about a wallet")
+	}
+	if r.Alert.Severity == notifier.SeverityCritical {
+		return Record{}, errors.New("critical alerts cannot be snoozed, acknowledge them instead")
+	}
+	r.SnoozedUntil = t.now().Add(d)
+	t.snoozer.Snooze(r.Alert.Wallet, r.Alert.Key(), r.SnoozedUntil)
+	t.ack(r, by)
+	t.logger.Info("Alert snoozed", "alert_id", id, "wallet", r.Alert.Wallet, "rule", r.Alert.Key(), "until", r.SnoozedUntil, "by", by)
+	return r.Record, nil
+}
+
+// Get returns a recent alert by ID.
+func (t *Tracker) Get(id string) (Record, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r, ok := t.records[id]
+	if !ok {
+		return Record{}, false
+	}
+	return r.Record, true
+}
+
+// Recent returns the kept alerts, newest first.
+func (t *Tracker) Recent() []Record {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	records := make([]Record, 0, len(t.order))
+	for i := len(t.order) - 1; i >= 0; i-- {
+		records = append(records, t.records[t.order[i]].Record)
+	}
+	return records
+}
+
+// escalate sends an alert nobody acknowledged to the escalation notifier. Alerts no longer
+// kept are escalated too, since nobody can acknowledge them anymore.
+func (t *Tracker) escalate(ctx context.Context, alert notifier.Alert) {
+	id := alert.ID
+	t.mu.Lock()
+	if r, ok := t.records[id]; ok {
+		if !r.AckedAt.IsZero() || !r.EscalatedAt.IsZero() {
+			t.mu.Unlock()
+			return
+		}
+		r.EscalatedAt = t.now()
+	}
+	t.mu.Unlock()
+
+	t.logger.Warn("Alert escalated", "alert_id", id, "type", alert.Type, "wallet", alert.Wallet, "after", t.escalateAfter)
+	t.metrics.AlertEscalated(string(alert.Type))
+	alert.Title = fmt.Sprintf("%s (unacknowledged for %s)", alert.Title, formatDuration(t.escalateAfter))
+	if err := t.escalation.Notify(ctx, alert); err != nil {
+		t.logger.Error("Failed to escalate alert", "alert_id", id, "error", err)
+	}
+}
+
+// ack marks a record acknowledged unless it already is. Must be called with t.mu held.
+func (t *Tracker) ack(r *record, by string) {
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+	if r.AckedAt.IsZero() {
+		r.AckedAt = t.now()
+		r.AckedBy = by
+	}
+}
+
+// evict drops the oldest acknowledged or escalated record, or the oldest record when none
+// is. A dropped record's pending escalation still fires. Must be called with t.mu held.
+func (t *Tracker) evict() {
+	i := slices.IndexFunc(t.order, func(id string) bool {
+		r := t.records[id]
+		return !r.AckedAt.IsZero() || !r.EscalatedAt.IsZero()
+	})
+	if i < 0 {
+		i = 0
+		r := t.records[t.order[0]]
+		t.logger.Warn("Dropping unacknowledged alert, it can no longer be acknowledged",
+			"alert_id", r.Alert.ID, "type", r.Alert.Type, "wallet", r.Alert.Wallet, "limit", maxRecords)
+	}
+	delete(t.records, t.order[i])
+	t.order = slices.Delete(t.order, i, i+1)
+}
+
+// formatDuration renders a duration without trailing zero units, such as 15m rather than 15m0s.
+func formatDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
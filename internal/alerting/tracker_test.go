@@ -0,0 +1,194 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yermakovsa/eth-watcher/internal/notifier"
+)
+
+type MockNotifier struct {
+	mu     sync.Mutex
+	alerts []notifier.Alert
+}
+
+func (m *MockNotifier) Notify(ctx context.Context, alert notifier.Alert) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.alerts = append(m.alerts, alert)
+	return nil
+}
+
+func (m *MockNotifier) Alerts() []notifier.Alert {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]notifier.Alert(nil), m.alerts...)
+}
+
+type MockSnoozer struct {
+	wallet string
+	rule   string
+	until  time.Time
+}
+
+func (m *MockSnoozer) Snooze(wallet, rule string, until time.Time) {
+	m.wallet, m.rule, m.until = wallet, rule, until
+}
+
+func TestTracker_AssignsIDs(t *testing.T) {
+	next := &MockNotifier{}
+	tracker := New(next, &MockSnoozer{})
+
+	require.NoError(t, tracker.Notify(context.Background(), notifier.Alert{ID: "abc", Title: "first"}))
+	require.NoError(t, tracker.Notify(context.Background(), notifier.Alert{Title: "second"}))
+
+	sent := next.Alerts()
+	require.Len(t, sent, 2)
+	assert.Equal(t, "abc", sent[0].ID, "existing IDs are kept")
+	assert.NotEmpty(t, sent[1].ID)
+
+	recent := tracker.Recent()
+	require.Len(t, recent, 2)
+	assert.Equal(t, "second", recent[0].Alert.Title, "newest first")
+	assert.Equal(t, "open", recent[0].Status())
+
+	r, ok := tracker.Get("abc")
+	require.True(t, ok)
+	assert.Equal(t, "first", r.Alert.Title)
+	_, ok = tracker.Get("missing")
+	assert.False(t, ok)
+}
+
+func TestTracker_Ack(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tracker := New(&MockNotifier{}, &MockSnoozer{})
+	tracker.now = func() time.Time { return now }
+	require.NoError(t, tracker.Notify(context.Background(), notifier.Alert{ID: "abc"}))
+
+	r, err := tracker.Ack("abc", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, "acknowledged", r.Status())
+	assert.Equal(t, "alice", r.AckedBy)
+	assert.Equal(t, now, r.AckedAt)
+
+	r, err = tracker.Ack("abc", "bob")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", r.AckedBy, "the first acknowledgement is kept")
+
+	_, err = tracker.Ack("missing", "alice")
+	assert.ErrorIs(t, err, ErrUnknownAlert)
+}
+
+func TestTracker_Snooze(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	snoozer := &MockSnoozer{}
+	tracker := New(&MockNotifier{}, snoozer)
+	tracker.now = func() time.Time { return now }
+	require.NoError(t, tracker.Notify(context.Background(), notifier.Alert{ID: "abc", Type: notifier.AlertRule, Rule: "large", Wallet: "0xabc"}))
+	require.NoError(t, tracker.Notify(context.Background(), notifier.Alert{ID: "def", Type: notifier.AlertThreshold, Wallet: "0xdef"}))
+	require.NoError(t, tracker.Notify(context.Background(), notifier.Alert{ID: "ghi", Type: notifier.AlertLogEvent}))
+
+	r, err := tracker.Snooze("abc", time.Hour, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, MockSnoozer{wallet: "0xabc", rule: "large", until: now.Add(time.Hour)}, *snoozer)
+	assert.Equal(t, now.Add(time.Hour), r.SnoozedUntil)
+	assert.Equal(t, "acknowledged", r.Status(), "snoozing acknowledges the alert")
+
+	_, err = tracker.Snooze("def", 30*time.Minute, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, "threshold", snoozer.rule, "built-in conditions are snoozed by alert type")
+
+	_, err = tracker.Snooze("ghi", time.Hour, "alice")
+	assert.EqualError(t, err, "alert is not about a wallet")
+	require.NoError(t, tracker.Notify(context.Background(), notifier.Alert{ID: "jkl", Type: notifier.AlertSanctions, Severity: notifier.SeverityCritical, Wallet: "0xabc"}))
+	_, err = tracker.Snooze("jkl", time.Hour, "alice")
+	assert.ErrorContains(t, err, "critical alerts cannot be snoozed")
+	_, err = tracker.Snooze("abc", 0, "alice")
+	assert.EqualError(t, err, "snooze duration must be positive")
+	_, err = tracker.Snooze("missing", time.Hour, "alice")
+	assert.ErrorIs(t, err, ErrUnknownAlert)
+}
+
+func TestTracker_Escalation(t *testing.T) {
+	escalation := &MockNotifier{}
+	tracker := New(&MockNotifier{}, &MockSnoozer{}, WithEscalation(escalation, 20*time.Millisecond))
+
+	require.NoError(t, tracker.Notify(context.Background(), notifier.Alert{ID: "acked", Title: "High Volume Detected"}))
+	require.NoError(t, tracker.Notify(context.Background(), notifier.Alert{ID: "ignored", Title: "High Volume Detected"}))
+	_, err := tracker.Ack("acked", "alice")
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return len(escalation.Alerts()) == 1 }, time.Second, 5*time.Millisecond)
+	sent := escalation.Alerts()[0]
+	assert.Equal(t, "ignored", sent.ID)
+	assert.Equal(t, "High Volume Detected (unacknowledged for 20ms)", sent.Title)
+
+	r, _ := tracker.Get("ignored")
+	assert.Equal(t, "escalated", r.Status())
+	assert.False(t, r.EscalatedAt.IsZero())
+
+	time.Sleep(40 * time.Millisecond)
+	assert.Len(t, escalation.Alerts(), 1, "acknowledged alerts are not escalated")
+}
+
+func TestTracker_KeepsRecentAlerts(t *testing.T) {
+	tracker := New(&MockNotifier{}, &MockSnoozer{})
+	for range maxRecords + 1 {
+		require.NoError(t, tracker.Notify(context.Background(), notifier.Alert{}))
+	}
+	assert.Len(t, tracker.Recent(), maxRecords)
+	assert.Len(t, tracker.records, maxRecords)
+}
+
+func TestTracker_DropsAcknowledgedAlertsFirst(t *testing.T) {
+	tracker := New(&MockNotifier{}, &MockSnoozer{})
+	require.NoError(t, tracker.Notify(context.Background(), notifier.Alert{ID: "open"}))
+	for i := range maxRecords - 1 {
+		id := fmt.Sprintf("acked-%d", i)
+		require.NoError(t, tracker.Notify(context.Background(), notifier.Alert{ID: id}))
+		_, err := tracker.Ack(id, "alice")
+		require.NoError(t, err)
+	}
+	require.NoError(t, tracker.Notify(context.Background(), notifier.Alert{ID: "new"}))
+
+	_, ok := tracker.Get("open")
+	assert.True(t, ok, "unacknowledged alerts are kept")
+	_, ok = tracker.Get("acked-0")
+	assert.False(t, ok)
+	assert.Len(t, tracker.records, maxRecords)
+}
+
+func TestTracker_EscalatesDroppedAlerts(t *testing.T) {
+	var logs bytes.Buffer
+	escalation := &MockNotifier{}
+	tracker := New(&MockNotifier{}, &MockSnoozer{},
+		WithEscalation(escalation, 50*time.Millisecond),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+	)
+	for i := range maxRecords + 1 {
+		require.NoError(t, tracker.Notify(context.Background(), notifier.Alert{ID: fmt.Sprintf("a%d", i)}))
+	}
+	_, ok := tracker.Get("a0")
+	assert.False(t, ok)
+
+	require.Eventually(t, func() bool { return len(escalation.Alerts()) == maxRecords+1 }, 5*time.Second, 10*time.Millisecond)
+	assert.True(t, slices.ContainsFunc(escalation.Alerts(), func(a notifier.Alert) bool { return a.ID == "a0" }),
+		"the dropped alert is still escalated")
+	assert.Contains(t, logs.String(), "Dropping unacknowledged alert")
+	assert.Contains(t, logs.String(), "alert_id=a0")
+}
+
+func TestFormatDuration(t *testing.T) {
+	assert.Equal(t, "15m", formatDuration(15*time.Minute))
+	assert.Equal(t, "2h", formatDuration(2*time.Hour))
+	assert.Equal(t, "1h30m", formatDuration(90*time.Minute))
+	assert.Equal(t, "45s", formatDuration(45*time.Second))
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yermakovsa/eth-watcher/internal/aggregator"
+	"github.com/yermakovsa/eth-watcher/internal/alerting"
+	"github.com/yermakovsa/eth-watcher/internal/ethrpc"
+)
+
+// Alerts keeps the recent alerts and acknowledges or snoozes them by ID.
+type Alerts interface {
+	Recent() []alerting.Record
+	Get(id string) (alerting.Record, bool)
+	Ack(id, by string) (alerting.Record, error)
+	Snooze(id string, d time.Duration, by string) (alerting.Record, error)
+}
+
+// Snoozes holds back the alerts about a wallet from one rule, or from every rule when rule is empty.
+type Snoozes interface {
+	Snooze(wallet, rule string, until time.Time)
+	Unsnooze(wallet, rule string) bool
+	Active() []aggregator.Snooze
+}
+
+// apiActor is recorded as the operator for alerts acknowledged or snoozed through the API.
+const apiActor = "api"
+
+// WithAlerts enables the endpoints listing recent alerts and snoozes. With management
+// enabled, alerts can also be acknowledged and snoozed.
+func WithAlerts(alerts Alerts, snoozes Snoozes) Option {
+	return func(s *Server) {
+		s.alerts = alerts
+		s.snoozes = snoozes
+	}
+}
+
+// alertResponse is a sent alert and what operators did about it.
+type alertResponse struct {
+	ID           string     `json:"id"`
+	Type         string     `json:"type"`
+	Rule         string     `json:"rule,omitempty"`
+	Severity     string     `json:"severity,omitempty"`
+	Title        string     `json:"title"`
+	Wallet       string     `json:"wallet,omitempty"`
+	Label        string     `json:"label,omitempty"`
+	Direction    string     `json:"direction,omitempty"`
+	AmountETH    float64    `json:"amount_eth,omitempty"`
+	AmountUSD    float64    `json:"amount_usd,omitempty"`
+	TxHash       string     `json:"tx_hash,omitempty"`
+	TxHashes     []string   `json:"tx_hashes,omitempty"`
+	Fields       []field    `json:"fields,omitempty"`
+	Status       string     `json:"status"`
+	FiredAt      time.Time  `json:"fired_at"`
+	AckedAt      *time.Time `json:"acked_at,omitempty"`
+	AckedBy      string     `json:"acked_by,omitempty"`
+	SnoozedUntil *time.Time `json:"snoozed_until,omitempty"`
+	EscalatedAt  *time.Time `json:"escalated_at,omitempty"`
+}
+
+// field is one named value of an alert.
+type field struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// snoozeBody snoozes an alert's wallet and rule, or reports or sets a snooze directly.
+type snoozeBody struct {
+	Wallet   string     `json:"wallet,omitempty"`
+	Rule     string     `json:"rule,omitempty"`
+	Duration string     `json:"duration,omitempty"`
+	Until    *time.Time `json:"until,omitempty"`
+}
+
+func (s *Server) listAlerts(rw http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	res := []alertResponse{}
+	for _, rec := range s.alerts.Recent() {
+		if status == "" || rec.Status() == status {
+			res = append(res, newAlertResponse(rec))
+		}
+	}
+	writeJSON(rw, http.StatusOK, res)
+}
+
+func (s *Server) getAlert(rw http.ResponseWriter, r *http.Request) {
+	rec, ok := s.alerts.Get(r.PathValue("id"))
+	if !ok {
+		writeJSON(rw, http.StatusNotFound, errorResponse{Error: "unknown alert"})
+		return
+	}
+	writeJSON(rw, http.StatusOK, newAlertResponse(rec))
+}
+
+func (s *Server) ackAlert(rw http.ResponseWriter, r *http.Request) {
+	rec, err := s.alerts.Ack(r.PathValue("id"), apiActor)
+	if err != nil {
+		writeAlertError(rw, err)
+		return
+	}
+	writeJSON(rw, http.StatusOK, newAlertResponse(rec))
+}
+
+func (s *Server) snoozeAlert(rw http.ResponseWriter, r *http.Request) {
+	var req snoozeBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(rw, http.StatusBadRequest, errorResponse{Error: "invalid request body"})
+		return
+	}
+	d, ok := parseDuration(req.Duration)
+	if !ok {
+		writeJSON(rw, http.StatusBadRequest, errorResponse{Error: "duration must be positive, such as 30m or 2h"})
+		return
+	}
+
+	rec, err := s.alerts.Snooze(r.PathValue("id"), d, apiActor)
+	if err != nil {
+		writeAlertError(rw, err)
+		return
+	}
+	writeJSON(rw, http.StatusOK, newAlertResponse(rec))
+}
+
+func (s *Server) listSnoozes(rw http.ResponseWriter, r *http.Request) {
+	res := []snoozeBody{}
+	for _, snooze := range s.snoozes.Active() {
+		res = append(res, snoozeBody{Wallet: snooze.Wallet, Rule: snooze.Rule, Until: &snooze.Until})
+	}
+	writeJSON(rw, http.StatusOK, res)
+}
+
+func (s *Server) addSnooze(rw http.ResponseWriter, r *http.Request) {
+	var req snoozeBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(rw, http.StatusBadRequest, errorResponse{Error: "invalid request body"})
+		return
+	}
+	wallet := strings.ToLower(strings.TrimSpace(req.Wallet))
+	if !ethrpc.IsAddress(wallet) {
+		writeJSON(rw, http.StatusBadRequest, errorResponse{Error: "invalid wallet"})
+		return
+	}
+	d, ok := parseDuration(req.Duration)
+	if !ok {
+		writeJSON(rw, http.StatusBadRequest, errorResponse{Error: "duration must be positive, such as 30m or 2h"})
+		return
+	}
+
+	until := time.Now().Add(d)
+	s.snoozes.Snooze(wallet, req.Rule, until)
+	writeJSON(rw, http.StatusOK, snoozeBody{Wallet: wallet, Rule: req.Rule, Until: &until})
+}
+
+func (s *Server) removeSnooze(rw http.ResponseWriter, r *http.Request) {
+	if !s.snoozes.Unsnooze(r.PathValue("wallet"), r.URL.Query().Get("rule")) {
+		writeJSON(rw, http.StatusNotFound, errorResponse{Error: "no such snooze"})
+		return
+	}
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func newAlertResponse(rec alerting.Record) alertResponse {
+	alert := rec.Alert
+	res := alertResponse{
+		ID:        alert.ID,
+		Type:      string(alert.Type),
+		Rule:      alert.Rule,
+		Severity:  string(alert.Severity),
+		Title:     alert.Title,
+		Wallet:    alert.Wallet,
+		Label:     alert.Label,
+		Direction: alert.Direction,
+		AmountETH: alert.Amount,
+		AmountUSD: alert.AmountUSD,
+		TxHash:    alert.TxID,
+		TxHashes:  alert.TxHashes,
+		Status:    rec.Status(),
+		FiredAt:   rec.FiredAt,
+		AckedBy:   rec.AckedBy,
+	}
+	for _, f := range alert.Fields {
+		res.Fields = append(res.Fields, field{Name: f.Name, Value: f.Value})
+	}
+	if !rec.AckedAt.IsZero() {
+		res.AckedAt = &rec.AckedAt
+	}
+	if !rec.SnoozedUntil.IsZero() {
+		res.SnoozedUntil = &rec.SnoozedUntil
+	}
+	if !rec.EscalatedAt.IsZero() {
+		res.EscalatedAt = &rec.EscalatedAt
+	}
+	return res
+}
+
+// writeAlertError reports a failed acknowledgement or snooze.
+func writeAlertError(rw http.ResponseWriter, err error) {
+	code := http.StatusBadRequest
+	if errors.Is(err, alerting.ErrUnknownAlert) {
+		code = http.StatusNotFound
+	}
+	writeJSON(rw, code, errorResponse{Error: err.Error()})
+}
+
+// parseDuration converts a duration such as 2h, reporting whether it is valid and positive.
+func parseDuration(raw string) (time.Duration, bool) {
+	d, err := time.ParseDuration(raw)
+	return d, err == nil && d > 0
+}
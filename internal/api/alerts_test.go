@@ -0,0 +1,187 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yermakovsa/eth-watcher/internal/aggregator"
+	"github.com/yermakovsa/eth-watcher/internal/alerting"
+	"github.com/yermakovsa/eth-watcher/internal/notifier"
+)
+
+var firedAt = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+type MockAlerts struct {
+	records []alerting.Record
+}
+
+func (m *MockAlerts) Recent() []alerting.Record {
+	return m.records
+}
+
+func (m *MockAlerts) Get(id string) (alerting.Record, bool) {
+	for _, r := range m.records {
+		if r.Alert.ID == id {
+			return r, true
+		}
+	}
+	return alerting.Record{}, false
+}
+
+func (m *MockAlerts) Ack(id, by string) (alerting.Record, error) {
+	for i, r := range m.records {
+		if r.Alert.ID == id {
+			m.records[i].AckedAt, m.records[i].AckedBy = firedAt.Add(time.Minute), by
+			return m.records[i], nil
+		}
+	}
+	return alerting.Record{}, fmt.Errorf("%w %q", alerting.ErrUnknownAlert, id)
+}
+
+func (m *MockAlerts) Snooze(id string, d time.Duration, by string) (alerting.Record, error) {
+	if _, err := m.Ack(id, by); err != nil {
+		return alerting.Record{}, err
+	}
+	for i, r := range m.records {
+		if r.Alert.ID == id {
+			m.records[i].SnoozedUntil = firedAt.Add(d)
+		}
+	}
+	r, _ := m.Get(id)
+	return r, nil
+}
+
+type MockSnoozes struct {
+	snoozes map[string]time.Time
+}
+
+func (m *MockSnoozes) Snooze(wallet, rule string, until time.Time) {
+	m.snoozes[wallet+"|"+rule] = until
+}
+
+func (m *MockSnoozes) Unsnooze(wallet, rule string) bool {
+	_, ok := m.snoozes[wallet+"|"+rule]
+	delete(m.snoozes, wallet+"|"+rule)
+	return ok
+}
+
+func (m *MockSnoozes) Active() []aggregator.Snooze {
+	var snoozes []aggregator.Snooze
+	for key, until := range m.snoozes {
+		snoozes = append(snoozes, aggregator.Snooze{Wallet: key[:42], Rule: key[43:], Until: until})
+	}
+	return snoozes
+}
+
+func newAlertServer(opts ...Option) (*Server, *MockAlerts, *MockSnoozes) {
+	alerts := &MockAlerts{records: []alerting.Record{
+		{Alert: notifier.Alert{
+			ID:        "1a2b3c",
+			Type:      notifier.AlertThreshold,
+			Title:     "High Volume Detected",
+			Wallet:    wallet,
+			Direction: "from",
+			Amount:    12.5,
+			TxID:      "0x1",
+			Fields:    []notifier.Field{{Name: "Amount", Value: "12.5000 ETH"}},
+		}, FiredAt: firedAt},
+		{Alert: notifier.Alert{ID: "4d5e6f", Type: notifier.AlertLogEvent, Title: "Contract Event Detected"}, FiredAt: firedAt, EscalatedAt: firedAt.Add(15 * time.Minute)},
+	}}
+	snoozes := &MockSnoozes{snoozes: map[string]time.Time{}}
+	agg := &MockAggregator{}
+	w := &MockWatcher{}
+	return New(agg, w, nil, append(opts, WithAlerts(alerts, snoozes))...), alerts, snoozes
+}
+
+func TestServer_ListAlerts(t *testing.T) {
+	s, _, _ := newAlertServer()
+
+	rec := send(s, http.MethodGet, "/api/v1/alerts", "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[
+		{"id":"1a2b3c","type":"threshold","title":"High Volume Detected","wallet":"`+wallet+`","direction":"from",
+		 "amount_eth":12.5,"tx_hash":"0x1","fields":[{"name":"Amount","value":"12.5000 ETH"}],
+		 "status":"open","fired_at":"2025-06-01T12:00:00Z"},
+		{"id":"4d5e6f","type":"log_event","title":"Contract Event Detected","status":"escalated",
+		 "fired_at":"2025-06-01T12:00:00Z","escalated_at":"2025-06-01T12:15:00Z"}
+	]`, rec.Body.String())
+
+	rec = send(s, http.MethodGet, "/api/v1/alerts?status=escalated", "", "")
+	var res []alertResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	require.Len(t, res, 1)
+	assert.Equal(t, "4d5e6f", res[0].ID)
+
+	rec = send(s, http.MethodGet, "/api/v1/alerts/1a2b3c", "", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = send(s, http.MethodGet, "/api/v1/alerts/ffffff", "", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServer_AlertActionsRequireManagement(t *testing.T) {
+	s, _, _ := newAlertServer()
+	rec := send(s, http.MethodPost, "/api/v1/alerts/1a2b3c/ack", "", token)
+	assert.Equal(t, http.StatusNotFound, rec.Code, "alerts are read-only without a management token")
+
+	s, alerts, _ := newAlertServer(WithManagement(token, &MockManager{}))
+	rec = send(s, http.MethodPost, "/api/v1/alerts/1a2b3c/ack", "", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Empty(t, alerts.records[0].AckedBy)
+}
+
+func TestServer_AckAlert(t *testing.T) {
+	s, _, _ := newAlertServer(WithManagement(token, &MockManager{}))
+
+	rec := send(s, http.MethodPost, "/api/v1/alerts/1a2b3c/ack", "", token)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var res alertResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	assert.Equal(t, "acknowledged", res.Status)
+	assert.Equal(t, "api", res.AckedBy)
+
+	rec = send(s, http.MethodPost, "/api/v1/alerts/ffffff/ack", "", token)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServer_SnoozeAlert(t *testing.T) {
+	s, _, _ := newAlertServer(WithManagement(token, &MockManager{}))
+
+	rec := send(s, http.MethodPost, "/api/v1/alerts/1a2b3c/snooze", `{"duration":"2h"}`, token)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var res alertResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	require.NotNil(t, res.SnoozedUntil)
+	assert.Equal(t, firedAt.Add(2*time.Hour), *res.SnoozedUntil)
+
+	rec = send(s, http.MethodPost, "/api/v1/alerts/1a2b3c/snooze", `{"duration":"-1h"}`, token)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = send(s, http.MethodPost, "/api/v1/alerts/ffffff/snooze", `{"duration":"1h"}`, token)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServer_Snoozes(t *testing.T) {
+	s, _, snoozes := newAlertServer(WithManagement(token, &MockManager{}))
+
+	rec := send(s, http.MethodPost, "/api/v1/snoozes", `{"wallet":"`+wallet+`","rule":"large","duration":"30m"}`, token)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, snoozes.snoozes, wallet+"|large")
+
+	rec = send(s, http.MethodPost, "/api/v1/snoozes", `{"wallet":"0xabc","duration":"30m"}`, token)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = send(s, http.MethodGet, "/api/v1/snoozes", "", "")
+	var res []snoozeBody
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	require.Len(t, res, 1)
+	assert.Equal(t, "large", res[0].Rule)
+
+	rec = send(s, http.MethodDelete, "/api/v1/snoozes/"+wallet+"?rule=large", "", token)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = send(s, http.MethodDelete, "/api/v1/snoozes/"+wallet+"?rule=large", "", token)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
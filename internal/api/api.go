@@ -0,0 +1,220 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yermakovsa/eth-watcher/internal/addressbook"
+	"github.com/yermakovsa/eth-watcher/internal/aggregator"
+)
+
+// Aggregator provides a consistent copy of the aggregation state.
+type Aggregator interface {
+	Snapshot() aggregator.Snapshot
+}
+
+// Watcher lists the monitored wallets of each direction.
+type Watcher interface {
+	Wallets() map[aggregator.Direction][]string
+}
+
+// directions orders the wallets of a response.
+var directions = []aggregator.Direction{aggregator.From, aggregator.To, aggregator.Net}
+
+// Server is a JSON API over the current aggregation state of the monitored wallets.
+// It is read-only unless management is enabled.
+type Server struct {
+	aggregator Aggregator
+	watcher    Watcher
+	book       *addressbook.Book
+	mux        *http.ServeMux
+
+	// token and manager enable the management endpoints
+	token   string
+	manager Manager
+	// alerts and snoozes enable the alert endpoints
+	alerts  Alerts
+	snoozes Snoozes
+	// history enables the alert history endpoints
+	history History
+}
+
+// New creates the API. The address book labels wallets without activity and may be nil.
+func New(agg Aggregator, w Watcher, book *addressbook.Book, opts ...Option) *Server {
+	s := &Server{aggregator: agg, watcher: w, book: book, mux: http.NewServeMux()}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.mux.HandleFunc("GET /api/v1/wallets", s.listWallets)
+	s.mux.HandleFunc("GET /api/v1/wallets/{address}", s.getWallet)
+	s.mux.HandleFunc("GET /api/v1/rules", s.listRules)
+	if s.token != "" {
+		s.mux.HandleFunc("POST /api/v1/wallets", s.authorized(s.addWallet))
+		s.mux.HandleFunc("DELETE /api/v1/wallets/{address}", s.authorized(s.removeWallet))
+		s.mux.HandleFunc("PUT /api/v1/thresholds/{direction}", s.authorized(s.setThresholds))
+		s.mux.HandleFunc("PUT /api/v1/rules", s.authorized(s.replaceRules))
+	}
+	if s.alerts != nil {
+		s.mux.HandleFunc("GET /api/v1/alerts", s.listAlerts)
+		s.mux.HandleFunc("GET /api/v1/alerts/{id}", s.getAlert)
+		s.mux.HandleFunc("GET /api/v1/snoozes", s.listSnoozes)
+	}
+	if s.history != nil {
+		s.mux.HandleFunc("GET /api/v1/history", s.listHistory)
+		s.mux.HandleFunc("GET /api/v1/history/{id}", s.getHistory)
+	}
+	if s.alerts != nil && s.token != "" {
+		s.mux.HandleFunc("POST /api/v1/alerts/{id}/ack", s.authorized(s.ackAlert))
+		s.mux.HandleFunc("POST /api/v1/alerts/{id}/snooze", s.authorized(s.snoozeAlert))
+		s.mux.HandleFunc("POST /api/v1/snoozes", s.authorized(s.addSnooze))
+		s.mux.HandleFunc("DELETE /api/v1/snoozes/{wallet}", s.authorized(s.removeSnooze))
+	}
+	return s
+}
+
+// ServeHTTP routes requests under /api/v1.
+func (s *Server) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(rw, r)
+}
+
+// walletsResponse lists the current window of every monitored wallet.
+type walletsResponse struct {
+	Time            time.Time     `json:"time"`
+	WindowSeconds   float64       `json:"window_seconds"`
+	CooldownSeconds float64       `json:"cooldown_seconds"`
+	Wallets         []walletState `json:"wallets"`
+}
+
+// walletResponse is the state of one wallet in each direction it is monitored in.
+type walletResponse struct {
+	Time          time.Time     `json:"time"`
+	WindowSeconds float64       `json:"window_seconds"`
+	Address       string        `json:"address"`
+	Label         string        `json:"label,omitempty"`
+	Directions    []walletState `json:"directions"`
+}
+
+// walletState is the current window of a wallet in one direction.
+type walletState struct {
+	Address                  string     `json:"address"`
+	Label                    string     `json:"label,omitempty"`
+	Direction                string     `json:"direction"`
+	TotalETH                 float64    `json:"total_eth"`
+	TotalUSD                 float64    `json:"total_usd,omitempty"`
+	Count                    int        `json:"count"`
+	ThresholdETH             float64    `json:"threshold_eth"`
+	ThresholdUSD             float64    `json:"threshold_usd,omitempty"`
+	LastAlert                *time.Time `json:"last_alert,omitempty"`
+	CooldownRemainingSeconds float64    `json:"cooldown_remaining_seconds"`
+	Records                  []record   `json:"records,omitempty"`
+}
+
+// record is one transaction in a wallet's current window.
+type record struct {
+	TxHash    string    `json:"tx_hash"`
+	AmountETH float64   `json:"amount_eth"`
+	AmountUSD float64   `json:"amount_usd,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func (s *Server) listWallets(rw http.ResponseWriter, r *http.Request) {
+	snap := s.aggregator.Snapshot()
+	res := walletsResponse{
+		Time:            snap.Time,
+		WindowSeconds:   snap.Window.Seconds(),
+		CooldownSeconds: snap.Cooldown.Seconds(),
+		Wallets:         []walletState{},
+	}
+	monitored := s.watcher.Wallets()
+	for _, direction := range directions {
+		for _, wallet := range monitored[direction] {
+			res.Wallets = append(res.Wallets, s.state(snap, direction, wallet, false))
+		}
+	}
+	writeJSON(rw, http.StatusOK, res)
+}
+
+func (s *Server) getWallet(rw http.ResponseWriter, r *http.Request) {
+	res, ok := s.wallet(strings.ToLower(r.PathValue("address")))
+	if !ok {
+		writeJSON(rw, http.StatusNotFound, errorResponse{Error: "wallet is not monitored"})
+		return
+	}
+	writeJSON(rw, http.StatusOK, res)
+}
+
+// wallet renders the state of a wallet in every direction it is monitored in,
+// reporting false when it is not monitored at all.
+func (s *Server) wallet(address string) (walletResponse, bool) {
+	snap := s.aggregator.Snapshot()
+	res := walletResponse{
+		Time:          snap.Time,
+		WindowSeconds: snap.Window.Seconds(),
+		Address:       address,
+		Label:         s.book.Label(address),
+		Directions:    []walletState{},
+	}
+	monitored := s.watcher.Wallets()
+	for _, direction := range directions {
+		for _, wallet := range monitored[direction] {
+			if wallet == address {
+				res.Directions = append(res.Directions, s.state(snap, direction, wallet, true))
+			}
+		}
+	}
+	return res, len(res.Directions) > 0
+}
+
+// state renders the snapshot of a monitored wallet, which is empty when it had no recent activity.
+func (s *Server) state(snap aggregator.Snapshot, direction aggregator.Direction, wallet string, records bool) walletState {
+	ws := aggregator.WalletSnapshot{Wallet: wallet, Label: s.book.Label(wallet), Direction: direction}
+	for _, w := range snap.Wallets {
+		if w.Direction == direction && w.Wallet == wallet {
+			ws = w
+			break
+		}
+	}
+
+	thresholds := snap.Thresholds[direction]
+	if eth, ok := snap.WalletThresholds[wallet]; ok {
+		thresholds = aggregator.Thresholds{ETH: eth}
+	}
+	state := walletState{
+		Address:                  ws.Wallet,
+		Label:                    ws.Label,
+		Direction:                string(direction),
+		TotalETH:                 ws.Total,
+		TotalUSD:                 ws.TotalUSD,
+		Count:                    ws.Count,
+		ThresholdETH:             thresholds.ETH,
+		ThresholdUSD:             thresholds.USD,
+		CooldownRemainingSeconds: ws.CooldownRemaining.Seconds(),
+	}
+	if !ws.LastAlert.IsZero() {
+		state.LastAlert = &ws.LastAlert
+	}
+	if records {
+		for _, r := range ws.Records {
+			state.Records = append(state.Records, record{
+				TxHash:    r.Hash,
+				AmountETH: r.Amount,
+				AmountUSD: r.USD,
+				Timestamp: r.Timestamp,
+			})
+		}
+	}
+	return state
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(rw http.ResponseWriter, code int, body any) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+	_ = json.NewEncoder(rw).Encode(body)
+}
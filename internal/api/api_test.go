@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yermakovsa/eth-watcher/internal/addressbook"
+	"github.com/yermakovsa/eth-watcher/internal/aggregator"
+)
+
+type MockAggregator struct {
+	snapshot aggregator.Snapshot
+}
+
+func (m *MockAggregator) Snapshot() aggregator.Snapshot {
+	return m.snapshot
+}
+
+type MockWatcher struct {
+	wallets map[aggregator.Direction][]string
+}
+
+func (m *MockWatcher) Wallets() map[aggregator.Direction][]string {
+	return m.wallets
+}
+
+func newTestServer() *Server {
+	now := time.Unix(1_700_000_000, 0).UTC()
+	agg := &MockAggregator{snapshot: aggregator.Snapshot{
+		Time:     now,
+		Window:   5 * time.Minute,
+		Cooldown: time.Minute,
+		Thresholds: map[aggregator.Direction]aggregator.Thresholds{
+			aggregator.From: {ETH: 10},
+			aggregator.To:   {ETH: 10},
+			aggregator.Net:  {ETH: 20, USD: 50000},
+		},
+		WalletThresholds: map[string]float64{"0xdef": 30},
+		Wallets: []aggregator.WalletSnapshot{{
+			Wallet:    "0xabc",
+			Label:     "Hot wallet",
+			Direction: aggregator.From,
+			Total:     12.5,
+			Count:     2,
+			Records: []aggregator.TxRecord{
+				{Hash: "0x1", Amount: 10, Timestamp: now.Add(-time.Minute)},
+				{Hash: "0x2", Amount: 2.5, Timestamp: now.Add(-30 * time.Second)},
+			},
+			LastAlert:         now.Add(-30 * time.Second),
+			CooldownRemaining: 30 * time.Second,
+		}},
+	}}
+	w := &MockWatcher{wallets: map[aggregator.Direction][]string{
+		aggregator.From: {"0xabc"},
+		aggregator.Net:  {"0xabc", "0xdef"},
+	}}
+	book := addressbook.New(map[string]addressbook.Entry{"0xabc": {Label: "Hot wallet"}, "0xdef": {Label: "Treasury"}})
+	return New(agg, w, book)
+}
+
+func get(t *testing.T, s *Server, path string, out any) int {
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), out))
+	return rec.Code
+}
+
+func TestServer_ListWallets(t *testing.T) {
+	var res walletsResponse
+	require.Equal(t, http.StatusOK, get(t, newTestServer(), "/api/v1/wallets", &res))
+
+	assert.Equal(t, 300.0, res.WindowSeconds)
+	assert.Equal(t, 60.0, res.CooldownSeconds)
+	require.Len(t, res.Wallets, 3)
+
+	active := res.Wallets[0]
+	assert.Equal(t, "0xabc", active.Address)
+	assert.Equal(t, "from", active.Direction)
+	assert.Equal(t, 12.5, active.TotalETH)
+	assert.Equal(t, 2, active.Count)
+	assert.Equal(t, 10.0, active.ThresholdETH)
+	assert.Equal(t, 30.0, active.CooldownRemainingSeconds)
+	require.NotNil(t, active.LastAlert)
+	assert.Empty(t, active.Records, "records are only listed per wallet")
+
+	idle := res.Wallets[2]
+	assert.Equal(t, "0xdef", idle.Address)
+	assert.Equal(t, "Treasury", idle.Label)
+	assert.Equal(t, "net", idle.Direction)
+	assert.Zero(t, idle.Count)
+	assert.Equal(t, 30.0, idle.ThresholdETH, "the wallet's own threshold replaces the direction's")
+	assert.Zero(t, idle.ThresholdUSD)
+	assert.Nil(t, idle.LastAlert)
+}
+
+func TestServer_GetWallet(t *testing.T) {
+	var res walletResponse
+	require.Equal(t, http.StatusOK, get(t, newTestServer(), "/api/v1/wallets/0xABC", &res))
+
+	assert.Equal(t, "0xabc", res.Address)
+	assert.Equal(t, "Hot wallet", res.Label)
+	require.Len(t, res.Directions, 2)
+	assert.Equal(t, "from", res.Directions[0].Direction)
+	require.Len(t, res.Directions[0].Records, 2)
+	assert.Equal(t, record{TxHash: "0x1", AmountETH: 10, Timestamp: time.Unix(1_699_999_940, 0).UTC()}, res.Directions[0].Records[0])
+	assert.Equal(t, "net", res.Directions[1].Direction)
+	assert.Zero(t, res.Directions[1].Count)
+}
+
+func TestServer_GetWallet_NotMonitored(t *testing.T) {
+	var res errorResponse
+	assert.Equal(t, http.StatusNotFound, get(t, newTestServer(), "/api/v1/wallets/0x999", &res))
+	assert.Equal(t, "wallet is not monitored", res.Error)
+}
+
+func TestServer_RejectsWrites(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestServer().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/wallets", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yermakovsa/eth-watcher/internal/ethrpc"
+	"github.com/yermakovsa/eth-watcher/internal/history"
+	"github.com/yermakovsa/eth-watcher/internal/notifier"
+)
+
+// History looks up stored alerts.
+type History interface {
+	Get(id string) (history.Entry, bool, error)
+	Find(q history.Query) ([]history.Entry, error)
+}
+
+// defaultHistoryLimit caps the alerts returned by a history query without a limit.
+const defaultHistoryLimit = 1000
+
+// WithHistory enables the endpoints querying and exporting every stored alert.
+func WithHistory(h History) Option {
+	return func(s *Server) {
+		s.history = h
+	}
+}
+
+func (s *Server) listHistory(rw http.ResponseWriter, r *http.Request) {
+	q, format, msg := historyQuery(r, time.Now())
+	if msg != "" {
+		writeJSON(rw, http.StatusBadRequest, errorResponse{Error: msg})
+		return
+	}
+	entries, err := s.history.Find(q)
+	if err != nil {
+		writeJSON(rw, http.StatusInternalServerError, errorResponse{Error: "history lookup failed"})
+		return
+	}
+
+	if format == history.FormatCSV {
+		rw.Header().Set("Content-Type", "text/csv")
+		rw.Header().Set("Content-Disposition", `attachment; filename="alerts.csv"`)
+		_ = history.WriteCSV(rw, entries)
+		return
+	}
+	writeJSON(rw, http.StatusOK, entries)
+}
+
+func (s *Server) getHistory(rw http.ResponseWriter, r *http.Request) {
+	e, ok, err := s.history.Get(r.PathValue("id"))
+	switch {
+	case err != nil:
+		writeJSON(rw, http.StatusInternalServerError, errorResponse{Error: "history lookup failed"})
+	case !ok:
+		writeJSON(rw, http.StatusNotFound, errorResponse{Error: "unknown alert"})
+	default:
+		writeJSON(rw, http.StatusOK, e)
+	}
+}
+
+// historyQuery reads the filters and export format of a history request, returning a
+// message for the client when one is invalid.
+func historyQuery(r *http.Request, now time.Time) (history.Query, string, string) {
+	params := r.URL.Query()
+	q := history.Query{Limit: defaultHistoryLimit}
+
+	if wallet := strings.ToLower(params.Get("wallet")); wallet != "" {
+		if !ethrpc.IsAddress(wallet) {
+			return q, "", "invalid wallet"
+		}
+		q.Wallet = wallet
+	}
+	if severity := notifier.Severity(params.Get("severity")); severity != "" {
+		if !severity.Valid() {
+			return q, "", "severity must be info, warning or critical"
+		}
+		q.Severity = severity
+	}
+	for name, bound := range map[string]*time.Time{"since": &q.Since, "until": &q.Until} {
+		if raw := params.Get(name); raw != "" {
+			t, err := history.ParseTime(raw, now)
+			if err != nil {
+				return q, "", name + ": " + err.Error()
+			}
+			*bound = t
+		}
+	}
+	if raw := params.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return q, "", "limit must be a positive number"
+		}
+		q.Limit = limit
+	}
+
+	format := params.Get("format")
+	switch format {
+	case "":
+		format = history.FormatJSON
+	case history.FormatJSON, history.FormatCSV:
+	default:
+		return q, "", "format must be json or csv"
+	}
+	return q, format, ""
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yermakovsa/eth-watcher/internal/history"
+	"github.com/yermakovsa/eth-watcher/internal/notifier"
+)
+
+type MockHistory struct {
+	entries []history.Entry
+	query   history.Query
+}
+
+func (m *MockHistory) Get(id string) (history.Entry, bool, error) {
+	for _, e := range m.entries {
+		if e.ID == id {
+			return e, true, nil
+		}
+	}
+	return history.Entry{}, false, nil
+}
+
+func (m *MockHistory) Find(q history.Query) ([]history.Entry, error) {
+	m.query = q
+	return m.entries, nil
+}
+
+func newHistoryServer() (*Server, *MockHistory) {
+	h := &MockHistory{entries: []history.Entry{{
+		ID:         "1a2b3c",
+		FiredAt:    firedAt,
+		Type:       "threshold",
+		Severity:   "critical",
+		Title:      "High Volume Detected",
+		Wallet:     wallet,
+		AmountETH:  12.5,
+		TxHash:     "0x2",
+		TxHashes:   []string{"0x1", "0x2"},
+		Deliveries: []history.Delivery{{Notifier: "telegram", Status: history.StatusSent, At: firedAt}},
+	}}}
+	return New(&MockAggregator{}, &MockWatcher{}, nil, WithHistory(h)), h
+}
+
+func TestServer_ListHistory(t *testing.T) {
+	s, h := newHistoryServer()
+
+	rec := send(s, http.MethodGet, "/api/v1/history?wallet="+wallet+"&severity=critical&since=2025-06-01&until=2025-06-02T00:00:00Z&limit=10", "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[
+		{"id":"1a2b3c","fired_at":"2025-06-01T12:00:00Z","type":"threshold","severity":"critical",
+		 "title":"High Volume Detected","wallet":"`+wallet+`","amount_eth":12.5,"tx_hash":"0x2","tx_hashes":["0x1","0x2"],
+		 "deliveries":[{"notifier":"telegram","status":"sent","at":"2025-06-01T12:00:00Z"}]}
+	]`, rec.Body.String())
+	assert.Equal(t, history.Query{
+		Wallet:   wallet,
+		Severity: notifier.SeverityCritical,
+		Since:    time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+		Until:    time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC),
+		Limit:    10,
+	}, h.query)
+
+	rec = send(s, http.MethodGet, "/api/v1/history", "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, history.Query{Limit: defaultHistoryLimit}, h.query)
+}
+
+func TestServer_ExportHistoryAsCSV(t *testing.T) {
+	s, _ := newHistoryServer()
+
+	rec := send(s, http.MethodGet, "/api/v1/history?format=csv", "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv", rec.Header().Get("Content-Type"))
+	assert.Equal(t,
+		"id,fired_at,type,rule,severity,title,wallet,label,direction,amount_eth,amount_usd,tx_hash,tx_hashes,deliveries\n"+
+			"1a2b3c,2025-06-01T12:00:00Z,threshold,,critical,High Volume Detected,"+wallet+",,,12.5,0,0x2,0x1 0x2,telegram:sent\n",
+		rec.Body.String())
+}
+
+func TestServer_ListHistoryRejectsInvalidFilters(t *testing.T) {
+	s, _ := newHistoryServer()
+
+	for _, query := range []string{
+		"wallet=0xabc",
+		"severity=urgent",
+		"since=yesterday",
+		"limit=0",
+		"format=xml",
+	} {
+		rec := send(s, http.MethodGet, "/api/v1/history?"+query, "", "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestServer_GetHistory(t *testing.T) {
+	s, _ := newHistoryServer()
+
+	rec := send(s, http.MethodGet, "/api/v1/history/1a2b3c", "", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = send(s, http.MethodGet, "/api/v1/history/ffffff", "", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/yermakovsa/eth-watcher/internal/aggregator"
+	"github.com/yermakovsa/eth-watcher/internal/ethrpc"
+	"github.com/yermakovsa/eth-watcher/internal/settings"
+)
+
+// Manager applies and stores changes to the monitored wallets, thresholds and rules.
+type Manager interface {
+	AddWallet(direction aggregator.Direction, wallet string) error
+	RemoveWallet(wallet string, directions ...aggregator.Direction) error
+	SetThresholds(direction aggregator.Direction, t aggregator.Thresholds) error
+	ReplaceRules(rules []aggregator.Rule) error
+}
+
+// Option configures optional Server behaviour.
+type Option func(*Server)
+
+// WithManagement enables the endpoints that change the monitored wallets, thresholds and
+// rules through m. Requests must carry token as a bearer token.
+func WithManagement(token string, m Manager) Option {
+	return func(s *Server) {
+		s.token = token
+		s.manager = m
+	}
+}
+
+// addWalletRequest is the body of a request to monitor a wallet.
+type addWalletRequest struct {
+	Address   string `json:"address"`
+	Direction string `json:"direction"`
+}
+
+// thresholdsBody sets or reports the volume alert thresholds of a direction.
+type thresholdsBody struct {
+	Direction string  `json:"direction"`
+	ETH       float64 `json:"eth"`
+	USD       float64 `json:"usd,omitempty"`
+}
+
+// authorized rejects requests without the management token.
+func (s *Server) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			rw.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(rw, http.StatusUnauthorized, errorResponse{Error: "invalid or missing token"})
+			return
+		}
+		next(rw, r)
+	}
+}
+
+func (s *Server) addWallet(rw http.ResponseWriter, r *http.Request) {
+	var req addWalletRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(rw, http.StatusBadRequest, errorResponse{Error: "invalid request body"})
+		return
+	}
+	address := strings.ToLower(strings.TrimSpace(req.Address))
+	if !ethrpc.IsAddress(address) {
+		writeJSON(rw, http.StatusBadRequest, errorResponse{Error: "invalid address"})
+		return
+	}
+	direction, ok := parseDirection(req.Direction)
+	if !ok {
+		writeJSON(rw, http.StatusBadRequest, errorResponse{Error: "direction must be from, to or net"})
+		return
+	}
+
+	if err := s.manager.AddWallet(direction, address); err != nil {
+		writeManagementError(rw, http.StatusServiceUnavailable, err)
+		return
+	}
+	res, _ := s.wallet(address)
+	writeJSON(rw, http.StatusOK, res)
+}
+
+func (s *Server) removeWallet(rw http.ResponseWriter, r *http.Request) {
+	var remove []aggregator.Direction
+	if raw := r.URL.Query().Get("direction"); raw != "" {
+		direction, ok := parseDirection(raw)
+		if !ok {
+			writeJSON(rw, http.StatusBadRequest, errorResponse{Error: "direction must be from, to or net"})
+			return
+		}
+		remove = append(remove, direction)
+	}
+
+	if err := s.manager.RemoveWallet(r.PathValue("address"), remove...); err != nil {
+		writeManagementError(rw, http.StatusServiceUnavailable, err)
+		return
+	}
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) setThresholds(rw http.ResponseWriter, r *http.Request) {
+	direction, ok := parseDirection(r.PathValue("direction"))
+	if !ok {
+		writeJSON(rw, http.StatusNotFound, errorResponse{Error: "direction must be from, to or net"})
+		return
+	}
+	var req thresholdsBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(rw, http.StatusBadRequest, errorResponse{Error: "invalid request body"})
+		return
+	}
+
+	if err := s.manager.SetThresholds(direction, aggregator.Thresholds{ETH: req.ETH, USD: req.USD}); err != nil {
+		writeManagementError(rw, http.StatusBadRequest, err)
+		return
+	}
+	t := s.aggregator.Snapshot().Thresholds[direction]
+	writeJSON(rw, http.StatusOK, thresholdsBody{Direction: string(direction), ETH: t.ETH, USD: t.USD})
+}
+
+func (s *Server) listRules(rw http.ResponseWriter, r *http.Request) {
+	writeJSON(rw, http.StatusOK, rulesResponse(s.aggregator.Snapshot().Rules))
+}
+
+func (s *Server) replaceRules(rw http.ResponseWriter, r *http.Request) {
+	var rules []aggregator.Rule
+	if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+		writeJSON(rw, http.StatusBadRequest, errorResponse{Error: "invalid request body"})
+		return
+	}
+
+	if err := s.manager.ReplaceRules(rules); err != nil {
+		writeManagementError(rw, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(rw, http.StatusOK, rulesResponse(s.aggregator.Snapshot().Rules))
+}
+
+// rulesResponse lists rules in the format of the rules file, as an empty array when there are none.
+func rulesResponse(rules []aggregator.Rule) []aggregator.Rule {
+	if rules == nil {
+		return []aggregator.Rule{}
+	}
+	return rules
+}
+
+// writeManagementError reports a failed change with code, unless the change was applied
+// but not saved, or the wallet was not monitored.
+func writeManagementError(rw http.ResponseWriter, code int, err error) {
+	var saveErr *settings.SaveError
+	switch {
+	case errors.As(err, &saveErr):
+		code = http.StatusInternalServerError
+	case errors.Is(err, settings.ErrNotMonitored):
+		code = http.StatusNotFound
+	}
+	writeJSON(rw, code, errorResponse{Error: err.Error()})
+}
+
+// parseDirection converts a direction name, reporting whether it is known.
+func parseDirection(raw string) (aggregator.Direction, bool) {
+	direction := aggregator.Direction(strings.ToLower(raw))
+	return direction, slices.Contains(directions, direction)
+}
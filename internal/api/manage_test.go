@@ -0,0 +1,184 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yermakovsa/eth-watcher/internal/aggregator"
+	"github.com/yermakovsa/eth-watcher/internal/settings"
+)
+
+const (
+	token  = "secret"
+	wallet = "0x00000000000000000000000000000000000000aa"
+)
+
+type MockManager struct {
+	watcher    *MockWatcher
+	aggregator *MockAggregator
+	err        error
+	removed    []aggregator.Direction
+}
+
+func (m *MockManager) AddWallet(direction aggregator.Direction, wallet string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.watcher.wallets[direction] = append(m.watcher.wallets[direction], wallet)
+	return nil
+}
+
+func (m *MockManager) RemoveWallet(wallet string, directions ...aggregator.Direction) error {
+	m.removed = directions
+	return m.err
+}
+
+func (m *MockManager) SetThresholds(direction aggregator.Direction, t aggregator.Thresholds) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.aggregator.snapshot.Thresholds[direction] = t
+	return nil
+}
+
+func (m *MockManager) ReplaceRules(rules []aggregator.Rule) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.aggregator.snapshot.Rules = rules
+	return nil
+}
+
+func newManagedServer() (*Server, *MockManager) {
+	agg := &MockAggregator{snapshot: aggregator.Snapshot{Thresholds: map[aggregator.Direction]aggregator.Thresholds{
+		aggregator.From: {ETH: 10},
+		aggregator.To:   {ETH: 10},
+		aggregator.Net:  {ETH: 20},
+	}}}
+	w := &MockWatcher{wallets: map[aggregator.Direction][]string{aggregator.From: {"0xabc"}}}
+	m := &MockManager{watcher: w, aggregator: agg}
+	return New(agg, w, nil, WithManagement(token, m)), m
+}
+
+func send(s *Server, method, path, body, auth string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if auth != "" {
+		req.Header.Set("Authorization", "Bearer "+auth)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServer_ManagementRequiresToken(t *testing.T) {
+	s, m := newManagedServer()
+
+	for _, auth := range []string{"", "wrong"} {
+		rec := send(s, http.MethodPost, "/api/v1/wallets", `{"address":"`+wallet+`","direction":"to"}`, auth)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+	}
+	assert.Empty(t, m.watcher.wallets[aggregator.To])
+
+	// Without a token the API stays read-only
+	rec := send(New(&MockAggregator{}, &MockWatcher{}, nil), http.MethodPost, "/api/v1/wallets", "{}", token)
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestServer_AddWallet(t *testing.T) {
+	s, m := newManagedServer()
+
+	rec := send(s, http.MethodPost, "/api/v1/wallets", `{"address":"`+wallet[2:]+`","direction":"net"}`, token)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "addresses need the 0x prefix")
+	rec = send(s, http.MethodPost, "/api/v1/wallets", `{"address":"`+wallet+`","direction":"up"}`, token)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = send(s, http.MethodPost, "/api/v1/wallets", `{"address":"0x00000000000000000000000000000000000000AA","direction":"NET"}`, token)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"direction":"net"`)
+	assert.Equal(t, []string{wallet}, m.watcher.wallets[aggregator.Net])
+}
+
+func TestServer_AddWallet_Fails(t *testing.T) {
+	s, m := newManagedServer()
+
+	m.err = errors.New("resubscribe: dial client: connection refused")
+	rec := send(s, http.MethodPost, "/api/v1/wallets", `{"address":"`+wallet+`","direction":"net"}`, token)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), "connection refused")
+
+	m.err = &settings.SaveError{Err: errors.New("read-only file system")}
+	rec = send(s, http.MethodPost, "/api/v1/wallets", `{"address":"`+wallet+`","direction":"net"}`, token)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "change applied but not saved: read-only file system")
+}
+
+func TestServer_RemoveWallet(t *testing.T) {
+	s, m := newManagedServer()
+
+	rec := send(s, http.MethodDelete, "/api/v1/wallets/0xabc?direction=net", "", token)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, []aggregator.Direction{aggregator.Net}, m.removed)
+
+	rec = send(s, http.MethodDelete, "/api/v1/wallets/0xabc", "", token)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, m.removed, "every direction is removed")
+
+	rec = send(s, http.MethodDelete, "/api/v1/wallets/0xabc?direction=up", "", token)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	m.err = settings.ErrNotMonitored
+	rec = send(s, http.MethodDelete, "/api/v1/wallets/0xabc", "", token)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServer_SetThresholds(t *testing.T) {
+	s, m := newManagedServer()
+
+	rec := send(s, http.MethodPut, "/api/v1/thresholds/to", `{"eth":2.5}`, token)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"direction":"to","eth":2.5}`, rec.Body.String())
+
+	rec = send(s, http.MethodPut, "/api/v1/thresholds/up", `{"eth":1}`, token)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	m.err = errors.New("thresholds must not be negative")
+	rec = send(s, http.MethodPut, "/api/v1/thresholds/to", `{"eth":-1}`, token)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestServer_Rules(t *testing.T) {
+	s, m := newManagedServer()
+
+	rec := send(s, http.MethodGet, "/api/v1/rules", "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[]`, rec.Body.String())
+
+	body := `[{"name":"large","direction":"from","condition":{"volume_at_least":50},"severity":"critical"}]`
+	rec = send(s, http.MethodPut, "/api/v1/rules", body, "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = send(s, http.MethodPut, "/api/v1/rules", body, token)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, body, rec.Body.String())
+
+	rec = send(s, http.MethodGet, "/api/v1/rules", "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, body, rec.Body.String())
+
+	rec = send(s, http.MethodPut, "/api/v1/rules", `{"name":"large"}`, token)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	m.err = errors.New(`rule "large": condition must set exactly one field, got 0`)
+	rec = send(s, http.MethodPut, "/api/v1/rules", `[{"name":"large"}]`, token)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	m.err = &settings.SaveError{Err: errors.New("disk full")}
+	rec = send(s, http.MethodPut, "/api/v1/rules", body, token)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
@@ -0,0 +1,219 @@
+package balance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yermakovsa/eth-watcher/internal/addressbook"
+	"github.com/yermakovsa/eth-watcher/internal/ethrpc"
+	"github.com/yermakovsa/eth-watcher/internal/logging"
+	"github.com/yermakovsa/eth-watcher/internal/notifier"
+)
+
+// Client queries the current balance of an address in wei.
+type Client interface {
+	Balance(ctx context.Context, address string) (*big.Int, error)
+}
+
+// Threshold configures the balance alerts of one wallet. A zero value disables the check.
+type Threshold struct {
+	// Floor alerts once when the balance drops below this many ETH, and again only
+	// after it has recovered above it.
+	Floor float64 `json:"floor,omitempty"`
+	// ChangePercent alerts when the balance moved by at least this percentage since
+	// monitoring started or since the previous change alert.
+	ChangePercent float64 `json:"change_percent,omitempty"`
+}
+
+// Balance is the latest known balance of a wallet.
+type Balance struct {
+	Wei     *big.Int
+	ETH     float64
+	Updated time.Time
+}
+
+// Monitor keeps the latest balance of each configured wallet and alerts on low balances
+// and large changes. It is safe for concurrent use.
+type Monitor struct {
+	client     Client
+	notifier   notifier.Notifier
+	book       *addressbook.Book
+	thresholds map[string]Threshold
+	logger     *slog.Logger
+	now        func() time.Time
+
+	mu        sync.Mutex
+	balances  map[string]Balance
+	low       map[string]bool
+	reference map[string]float64
+}
+
+// New creates a monitor for the wallets in thresholds. The address book is used for labels and may be nil.
+// A nil logger uses the default one.
+func New(client Client, notif notifier.Notifier, thresholds map[string]Threshold, book *addressbook.Book, logger *slog.Logger) *Monitor {
+	normalized := make(map[string]Threshold, len(thresholds))
+	for wallet, t := range thresholds {
+		normalized[strings.ToLower(wallet)] = t
+	}
+	return &Monitor{
+		client:     client,
+		notifier:   notif,
+		book:       book,
+		thresholds: normalized,
+		logger:     logging.Component(logger, "balance"),
+		now:        time.Now,
+		balances:   make(map[string]Balance),
+		low:        make(map[string]bool),
+		reference:  make(map[string]float64),
+	}
+}
+
+// Load reads per-wallet thresholds from a JSON object keyed by address.
+func Load(path string) (map[string]Threshold, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var thresholds map[string]Threshold
+	if err := json.Unmarshal(data, &thresholds); err != nil {
+		return nil, fmt.Errorf("parse balance thresholds: %w", err)
+	}
+	for wallet, t := range thresholds {
+		if t.Floor < 0 || t.ChangePercent < 0 {
+			return nil, fmt.Errorf("wallet %s: thresholds must not be negative", wallet)
+		}
+	}
+	return thresholds, nil
+}
+
+// Latest returns the most recent balance observed for a wallet.
+func (m *Monitor) Latest(wallet string) (Balance, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.balances[strings.ToLower(wallet)]
+	return b, ok
+}
+
+// Observe schedules a balance check after a transaction involving wallet.
+// Wallets without thresholds are ignored.
+func (m *Monitor) Observe(ctx context.Context, wallet string) {
+	if _, ok := m.thresholds[strings.ToLower(wallet)]; !ok {
+		return
+	}
+	go func() {
+		if err := m.Check(ctx, wallet); err != nil {
+			m.logger.Error("Balance check failed", "wallet", wallet, "error", err)
+		}
+	}()
+}
+
+// Check queries the balance of a wallet, stores it and sends any alert it causes.
+func (m *Monitor) Check(ctx context.Context, wallet string) error {
+	wallet = strings.ToLower(wallet)
+	threshold, ok := m.thresholds[wallet]
+	if !ok {
+		return nil
+	}
+
+	requested := m.now()
+	wei, err := m.client.Balance(ctx, wallet)
+	if err != nil {
+		return fmt.Errorf("fetch balance of %s: %w", wallet, err)
+	}
+	eth := ethrpc.WeiToEth(wei)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Checks run concurrently; never let a slow response overwrite a newer balance
+	if prev, ok := m.balances[wallet]; ok && prev.Updated.After(requested) {
+		return nil
+	}
+	m.balances[wallet] = Balance{Wei: wei, ETH: eth, Updated: requested}
+
+	if threshold.Floor > 0 {
+		if eth < threshold.Floor {
+			if !m.low[wallet] {
+				m.low[wallet] = true
+				m.notify(ctx, notifier.Alert{
+					Type:   notifier.AlertLowBalance,
+					Title:  "Low Balance",
+					Wallet: wallet,
+					Amount: eth,
+					Fields: []notifier.Field{
+						{Name: "Balance", Value: fmt.Sprintf("%.4f ETH", eth)},
+						{Name: "Floor", Value: fmt.Sprintf("%.4f ETH", threshold.Floor)},
+					},
+				})
+			}
+		} else {
+			delete(m.low, wallet)
+		}
+	}
+
+	if threshold.ChangePercent > 0 {
+		ref, ok := m.reference[wallet]
+		if !ok || ref == 0 {
+			m.reference[wallet] = eth
+			return nil
+		}
+		change := (eth - ref) / ref * 100
+		if math.Abs(change) >= threshold.ChangePercent {
+			m.reference[wallet] = eth
+			m.notify(ctx, notifier.Alert{
+				Type:   notifier.AlertBalanceChange,
+				Title:  "Balance Change Detected",
+				Wallet: wallet,
+				Amount: eth,
+				Fields: []notifier.Field{
+					{Name: "Balance", Value: fmt.Sprintf("%.4f ETH", eth)},
+					{Name: "Previous", Value: fmt.Sprintf("%.4f ETH", ref)},
+					{Name: "Change", Value: fmt.Sprintf("%+.2f%% (%+.4f ETH)", change, eth-ref)},
+				},
+			})
+		}
+	}
+	return nil
+}
+
+// Refresh checks every configured wallet once.
+func (m *Monitor) Refresh(ctx context.Context) {
+	for wallet := range m.thresholds {
+		if err := m.Check(ctx, wallet); err != nil {
+			m.logger.Error("Balance check failed", "wallet", wallet, "error", err)
+		}
+	}
+}
+
+// Run refreshes every wallet immediately and then every interval until ctx is cancelled.
+func (m *Monitor) Run(ctx context.Context, interval time.Duration) {
+	m.Refresh(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Refresh(ctx)
+		}
+	}
+}
+
+// notify labels the alert and sends it without blocking the caller.
+func (m *Monitor) notify(ctx context.Context, alert notifier.Alert) {
+	alert.Label = m.book.Label(alert.Wallet)
+	m.logger.Info("Alert fired", "type", alert.Type, "wallet", alert.Wallet)
+	go m.notifier.Notify(ctx, alert)
+}
@@ -0,0 +1,144 @@
+package balance
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yermakovsa/eth-watcher/internal/addressbook"
+	"github.com/yermakovsa/eth-watcher/internal/notifier"
+)
+
+type MockClient struct {
+	mu       sync.Mutex
+	balances map[string]float64
+}
+
+func (m *MockClient) set(wallet string, eth float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.balances[wallet] = eth
+}
+
+func (m *MockClient) Balance(ctx context.Context, address string) (*big.Int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	wei, _ := new(big.Float).Mul(big.NewFloat(m.balances[address]), big.NewFloat(1e18)).Int(nil)
+	return wei, nil
+}
+
+type MockNotifier struct {
+	alerts chan notifier.Alert
+}
+
+func (m *MockNotifier) Notify(ctx context.Context, alert notifier.Alert) error {
+	m.alerts <- alert
+	return nil
+}
+
+func (m *MockNotifier) next(t *testing.T) notifier.Alert {
+	t.Helper()
+	select {
+	case alert := <-m.alerts:
+		return alert
+	case <-time.After(1 * time.Second):
+		t.Fatal("expected alert not received")
+		return notifier.Alert{}
+	}
+}
+
+func (m *MockNotifier) none(t *testing.T) {
+	t.Helper()
+	select {
+	case alert := <-m.alerts:
+		t.Fatalf("unexpected alert %q", alert.Title)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestMonitor_LowBalanceAlertsOncePerDip(t *testing.T) {
+	client := &MockClient{balances: map[string]float64{"0xhot": 10}}
+	notif := &MockNotifier{alerts: make(chan notifier.Alert, 4)}
+	book := addressbook.New(map[string]addressbook.Entry{"0xhot": {Label: "Hot wallet"}})
+	m := New(client, notif, map[string]Threshold{"0xHOT": {Floor: 5}}, book, nil)
+	ctx := context.Background()
+
+	require.NoError(t, m.Check(ctx, "0xhot"))
+	notif.none(t)
+
+	client.set("0xhot", 4)
+	require.NoError(t, m.Check(ctx, "0xhot"))
+	alert := notif.next(t)
+	assert.Equal(t, notifier.AlertLowBalance, alert.Type)
+	assert.Equal(t, "Hot wallet", alert.Label)
+	assert.Equal(t, []notifier.Field{
+		{Name: "Balance", Value: "4.0000 ETH"},
+		{Name: "Floor", Value: "5.0000 ETH"},
+	}, alert.Fields)
+
+	client.set("0xhot", 3)
+	require.NoError(t, m.Check(ctx, "0xhot"))
+	notif.none(t)
+
+	client.set("0xhot", 6)
+	require.NoError(t, m.Check(ctx, "0xhot"))
+	client.set("0xhot", 2)
+	require.NoError(t, m.Check(ctx, "0xhot"))
+	assert.Equal(t, notifier.AlertLowBalance, notif.next(t).Type)
+
+	latest, ok := m.Latest("0xhot")
+	require.True(t, ok)
+	assert.InDelta(t, 2.0, latest.ETH, 1e-9)
+}
+
+func TestMonitor_ChangePercent(t *testing.T) {
+	client := &MockClient{balances: map[string]float64{"0xtreasury": 100}}
+	notif := &MockNotifier{alerts: make(chan notifier.Alert, 4)}
+	m := New(client, notif, map[string]Threshold{"0xtreasury": {ChangePercent: 20}}, nil, nil)
+	ctx := context.Background()
+
+	require.NoError(t, m.Check(ctx, "0xtreasury"))
+
+	// Small moves accumulate against the reference balance
+	client.set("0xtreasury", 90)
+	require.NoError(t, m.Check(ctx, "0xtreasury"))
+	notif.none(t)
+
+	client.set("0xtreasury", 75)
+	require.NoError(t, m.Check(ctx, "0xtreasury"))
+	alert := notif.next(t)
+	assert.Equal(t, notifier.AlertBalanceChange, alert.Type)
+	assert.Contains(t, alert.Fields, notifier.Field{Name: "Change", Value: "-25.00% (-25.0000 ETH)"})
+
+	// The reference moves to the alerted balance
+	client.set("0xtreasury", 80)
+	require.NoError(t, m.Check(ctx, "0xtreasury"))
+	notif.none(t)
+}
+
+func TestMonitor_IgnoresUnknownWallets(t *testing.T) {
+	m := New(&MockClient{balances: map[string]float64{}}, &MockNotifier{}, map[string]Threshold{"0xhot": {Floor: 1}}, nil, nil)
+
+	require.NoError(t, m.Check(context.Background(), "0xother"))
+	_, ok := m.Latest("0xother")
+	assert.False(t, ok)
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "balances.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"0xhot": {"floor": 5, "change_percent": 30}}`), 0o600))
+
+	thresholds, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Threshold{"0xhot": {Floor: 5, ChangePercent: 30}}, thresholds)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"0xhot": {"floor": -1}}`), 0o600))
+	_, err = Load(path)
+	assert.ErrorContains(t, err, "must not be negative")
+}
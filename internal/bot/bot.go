@@ -0,0 +1,534 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mymmrac/telego"
+	"github.com/yermakovsa/eth-watcher/internal/addressbook"
+	"github.com/yermakovsa/eth-watcher/internal/aggregator"
+	"github.com/yermakovsa/eth-watcher/internal/alerting"
+	"github.com/yermakovsa/eth-watcher/internal/ethrpc"
+	"github.com/yermakovsa/eth-watcher/internal/logging"
+	"github.com/yermakovsa/eth-watcher/internal/notifier"
+	"github.com/yermakovsa/eth-watcher/internal/settings"
+)
+
+// pollTimeout is how long a getUpdates request waits for a message before returning empty.
+const pollTimeout = 30 * time.Second
+
+const help = `Commands:
+/status — stream health and monitoring summary
+/wallets — monitored wallets
+/volume <addr> — current window of a wallet
+/mute <addr> <duration> — hold back alerts about a wallet, e.g. /mute 0xabc… 2h
+/unmute [addr] — resume alerts about a wallet, or about every wallet
+/add <addr> from|to|net — monitor a wallet
+/threshold <addr> <eth>|reset — give a wallet its own ETH threshold
+/ack <id> — acknowledge an alert
+/snooze <id> <duration> — hold back the alerts of the same wallet and rule, e.g. /snooze 1a2b3c4d5e6f 2h`
+
+// directions orders the wallets of a reply.
+var directions = []aggregator.Direction{aggregator.From, aggregator.To, aggregator.Net}
+
+// Client receives commands and sends replies.
+type Client interface {
+	UpdatesViaLongPolling(ctx context.Context, params *telego.GetUpdatesParams, options ...telego.LongPollingOption) (<-chan telego.Update, error)
+	SendMessage(ctx context.Context, params *telego.SendMessageParams) (*telego.Message, error)
+	AnswerCallbackQuery(ctx context.Context, params *telego.AnswerCallbackQueryParams) error
+	EditMessageReplyMarkup(ctx context.Context, params *telego.EditMessageReplyMarkupParams) (*telego.Message, error)
+}
+
+// Watcher lists the monitored wallets and reports whether the event stream is healthy.
+type Watcher interface {
+	Wallets() map[aggregator.Direction][]string
+	Ready(maxEventAge time.Duration) error
+}
+
+// Aggregator provides a consistent copy of the aggregation state.
+type Aggregator interface {
+	Snapshot() aggregator.Snapshot
+}
+
+// Mutes holds back the alerts about muted wallets.
+type Mutes interface {
+	Mute(wallet string, until time.Time)
+	Unmute(wallet string) bool
+	UnmuteAll() int
+	Muted() map[string]time.Time
+}
+
+// Manager applies and stores changes to the monitored wallets and thresholds.
+type Manager interface {
+	AddWallet(direction aggregator.Direction, wallet string) error
+	SetWalletThreshold(wallet string, eth float64) error
+	ClearWalletThreshold(wallet string) error
+}
+
+// Alerts acknowledges and snoozes sent alerts by ID.
+type Alerts interface {
+	Ack(id, by string) (alerting.Record, error)
+	Snooze(id string, d time.Duration, by string) (alerting.Record, error)
+}
+
+// Option configures optional Bot behaviour.
+type Option func(*Bot)
+
+// WithAddressBook labels wallets in replies.
+func WithAddressBook(book *addressbook.Book) Option {
+	return func(b *Bot) {
+		b.book = book
+	}
+}
+
+// WithStreamStaleness reports the stream as stale in /status when no event arrived for maxEventAge.
+func WithStreamStaleness(maxEventAge time.Duration) Option {
+	return func(b *Bot) {
+		b.staleness = maxEventAge
+	}
+}
+
+// WithAlerts enables /ack, /snooze and the buttons under alerts.
+func WithAlerts(alerts Alerts) Option {
+	return func(b *Bot) {
+		b.alerts = alerts
+	}
+}
+
+// WithLogger sets the logger used instead of the default one.
+func WithLogger(logger *slog.Logger) Option {
+	return func(b *Bot) {
+		b.logger = logger
+	}
+}
+
+// Bot answers commands sent by authorized Telegram chats. Messages from other chats are ignored.
+type Bot struct {
+	client     Client
+	chats      map[int64]struct{}
+	watcher    Watcher
+	aggregator Aggregator
+	mutes      Mutes
+	manager    Manager
+	alerts     Alerts
+	book       *addressbook.Book
+	staleness  time.Duration
+	logger     *slog.Logger
+	now        func() time.Time
+}
+
+// New creates a bot accepting commands from the given chats.
+func New(client Client, chats []int64, w Watcher, agg Aggregator, mutes Mutes, m Manager, opts ...Option) *Bot {
+	b := &Bot{
+		client:     client,
+		chats:      make(map[int64]struct{}, len(chats)),
+		watcher:    w,
+		aggregator: agg,
+		mutes:      mutes,
+		manager:    m,
+		now:        time.Now,
+	}
+	for _, id := range chats {
+		b.chats[id] = struct{}{}
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	b.logger = logging.Component(b.logger, "bot")
+	return b
+}
+
+// Start begins long polling for commands until ctx is cancelled.
+func (b *Bot) Start(ctx context.Context) error {
+	updates, err := b.client.UpdatesViaLongPolling(ctx, &telego.GetUpdatesParams{
+		Timeout:        int(pollTimeout.Seconds()),
+		AllowedUpdates: []string{"message", "callback_query"},
+	})
+	if err != nil {
+		return err
+	}
+
+	b.logger.Info("Listening for commands", "chats", len(b.chats))
+	go func() {
+		for update := range updates {
+			b.handle(ctx, update)
+		}
+	}()
+	return nil
+}
+
+// handle answers a command or alert button from an authorized chat.
+func (b *Bot) handle(ctx context.Context, update telego.Update) {
+	if update.CallbackQuery != nil {
+		b.callback(ctx, update.CallbackQuery)
+		return
+	}
+
+	msg := update.Message
+	if msg == nil || !strings.HasPrefix(msg.Text, "/") {
+		return
+	}
+	if _, ok := b.chats[msg.Chat.ID]; !ok {
+		b.logger.Warn("Ignoring command from unauthorized chat", "chat_id", msg.Chat.ID)
+		return
+	}
+
+	args := strings.Fields(msg.Text)
+	// In groups, commands may be addressed to the bot as /command@botname
+	command, _, _ := strings.Cut(args[0], "@")
+	b.logger.Info("Received command", "chat_id", msg.Chat.ID, "command", command)
+
+	reply := b.run(sender(msg.From), command, args[1:])
+	params := &telego.SendMessageParams{}
+	if _, err := b.client.SendMessage(ctx, params.WithChatID(telego.ChatID{ID: msg.Chat.ID}).WithText(reply)); err != nil {
+		b.logger.Error("Failed to send reply", "chat_id", msg.Chat.ID, "command", command, "error", err)
+	}
+}
+
+// callback acts on a button pressed under an alert, removing the buttons once it succeeds
+// and replying to the alert so everyone in the chat sees who handled it.
+func (b *Bot) callback(ctx context.Context, q *telego.CallbackQuery) {
+	if q.Message == nil {
+		return
+	}
+	chat := q.Message.GetChat().ID
+	if _, ok := b.chats[chat]; !ok {
+		b.logger.Warn("Ignoring alert action from unauthorized chat", "chat_id", chat)
+		b.answer(ctx, q, "Not authorized.")
+		return
+	}
+
+	b.logger.Info("Received alert action", "chat_id", chat, "data", q.Data)
+	reply, ok := b.action(sender(&q.From), strings.Split(q.Data, ":"))
+	b.answer(ctx, q, reply)
+	if !ok {
+		return
+	}
+
+	edit := &telego.EditMessageReplyMarkupParams{ChatID: telego.ChatID{ID: chat}, MessageID: q.Message.GetMessageID()}
+	if _, err := b.client.EditMessageReplyMarkup(ctx, edit); err != nil {
+		b.logger.Error("Failed to remove alert buttons", "chat_id", chat, "error", err)
+	}
+	params := &telego.SendMessageParams{}
+	params.
+		WithChatID(telego.ChatID{ID: chat}).
+		WithText(reply).
+		WithReplyParameters(&telego.ReplyParameters{MessageID: q.Message.GetMessageID()})
+	if _, err := b.client.SendMessage(ctx, params); err != nil {
+		b.logger.Error("Failed to send reply", "chat_id", chat, "error", err)
+	}
+}
+
+// answer stops the loading indicator of a pressed button, showing text to the user who pressed it.
+func (b *Bot) answer(ctx context.Context, q *telego.CallbackQuery, text string) {
+	if err := b.client.AnswerCallbackQuery(ctx, &telego.AnswerCallbackQueryParams{CallbackQueryID: q.ID, Text: text}); err != nil {
+		b.logger.Error("Failed to answer alert action", "error", err)
+	}
+}
+
+// action acknowledges or snoozes an alert given the callback data of its button split
+// into fields, returning the reply and whether it succeeded.
+func (b *Bot) action(from string, fields []string) (string, bool) {
+	switch {
+	case len(fields) == 2 && fields[0] == notifier.ActionAck:
+		return b.ack(from, fields[1:])
+	case len(fields) == 3 && fields[0] == notifier.ActionSnooze:
+		return b.snooze(from, fields[1:])
+	default:
+		return "Unknown action.", false
+	}
+}
+
+// run executes a command sent by from and returns the reply.
+func (b *Bot) run(from, command string, args []string) string {
+	switch command {
+	case "/start", "/help":
+		return help
+	case "/status":
+		return b.status()
+	case "/wallets":
+		return b.wallets()
+	case "/volume":
+		return b.volume(args)
+	case "/mute":
+		return b.mute(args)
+	case "/unmute":
+		return b.unmute(args)
+	case "/add":
+		return b.add(args)
+	case "/threshold":
+		return b.threshold(args)
+	case "/ack":
+		reply, _ := b.ack(from, args)
+		return reply
+	case "/snooze":
+		reply, _ := b.snooze(from, args)
+		return reply
+	default:
+		return "Unknown command. Send /help for the list of commands."
+	}
+}
+
+func (b *Bot) status() string {
+	var sb strings.Builder
+	if err := b.watcher.Ready(b.staleness); err != nil {
+		fmt.Fprintf(&sb, "⚠️ Stream unhealthy: %s\n", err)
+	} else {
+		sb.WriteString("✅ Stream healthy\n")
+	}
+
+	monitored := b.watcher.Wallets()
+	fmt.Fprintf(&sb, "Monitored wallets: %d from, %d to, %d net\n",
+		len(monitored[aggregator.From]), len(monitored[aggregator.To]), len(monitored[aggregator.Net]))
+
+	snap := b.aggregator.Snapshot()
+	fmt.Fprintf(&sb, "Window: %s, cooldown: %s\n", snap.Window, snap.Cooldown)
+	fmt.Fprintf(&sb, "Muted wallets: %d", len(b.mutes.Muted()))
+	return sb.String()
+}
+
+func (b *Bot) wallets() string {
+	monitored := b.watcher.Wallets()
+	muted := b.mutes.Muted()
+
+	var sb strings.Builder
+	for _, direction := range directions {
+		if len(monitored[direction]) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "%s:\n", direction)
+		for _, wallet := range monitored[direction] {
+			sb.WriteString(b.label(wallet))
+			if until, ok := muted[wallet]; ok {
+				fmt.Fprintf(&sb, " 🔇 until %s", until.UTC().Format(time.DateTime))
+			}
+			sb.WriteString("\n")
+		}
+	}
+	if sb.Len() == 0 {
+		return "No wallets are monitored."
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func (b *Bot) volume(args []string) string {
+	if len(args) != 1 {
+		return "Usage: /volume <addr>"
+	}
+	wallet, ok := parseAddress(args[0])
+	if !ok {
+		return "Invalid address."
+	}
+
+	snap := b.aggregator.Snapshot()
+	monitored := b.watcher.Wallets()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s, last %s\n", b.label(wallet), snap.Window)
+	found := false
+	for _, direction := range directions {
+		if !slices.Contains(monitored[direction], wallet) {
+			continue
+		}
+		found = true
+
+		ws := aggregator.WalletSnapshot{}
+		for _, w := range snap.Wallets {
+			if w.Direction == direction && w.Wallet == wallet {
+				ws = w
+				break
+			}
+		}
+		fmt.Fprintf(&sb, "%s: %s in %d tx, threshold %s\n", direction, formatAmount(ws.Total, ws.TotalUSD), ws.Count, formatThreshold(snap, direction, wallet))
+		if ws.CooldownRemaining > 0 {
+			fmt.Fprintf(&sb, "  cooling down for %s\n", ws.CooldownRemaining.Round(time.Second))
+		}
+	}
+	if !found {
+		return "Wallet is not monitored."
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func (b *Bot) mute(args []string) string {
+	if len(args) != 2 {
+		return "Usage: /mute <addr> <duration>, e.g. /mute 0xabc… 30m"
+	}
+	wallet, ok := parseAddress(args[0])
+	if !ok {
+		return "Invalid address."
+	}
+	d, err := time.ParseDuration(args[1])
+	if err != nil || d <= 0 {
+		return "Invalid duration, use a value such as 30m, 2h or 1h30m."
+	}
+
+	until := b.now().Add(d)
+	b.mutes.Mute(wallet, until)
+	return fmt.Sprintf("🔇 Muted %s until %s UTC. Critical alerts are still sent.", b.label(wallet), until.UTC().Format(time.DateTime))
+}
+
+func (b *Bot) unmute(args []string) string {
+	switch len(args) {
+	case 0:
+		return fmt.Sprintf("🔔 Unmuted %d wallet(s).", b.mutes.UnmuteAll())
+	case 1:
+		wallet, ok := parseAddress(args[0])
+		if !ok {
+			return "Invalid address."
+		}
+		if !b.mutes.Unmute(wallet) {
+			return "Wallet is not muted."
+		}
+		return fmt.Sprintf("🔔 Unmuted %s.", b.label(wallet))
+	default:
+		return "Usage: /unmute [addr]"
+	}
+}
+
+func (b *Bot) add(args []string) string {
+	if len(args) != 2 {
+		return "Usage: /add <addr> from|to|net"
+	}
+	wallet, ok := parseAddress(args[0])
+	if !ok {
+		return "Invalid address."
+	}
+	direction := aggregator.Direction(strings.ToLower(args[1]))
+	if !slices.Contains(directions, direction) {
+		return "Direction must be from, to or net."
+	}
+
+	if err := b.manager.AddWallet(direction, wallet); err != nil {
+		return changeFailed(err)
+	}
+	return fmt.Sprintf("✅ Monitoring %s (%s).", b.label(wallet), direction)
+}
+
+func (b *Bot) threshold(args []string) string {
+	if len(args) != 2 {
+		return "Usage: /threshold <addr> <eth>|reset"
+	}
+	wallet, ok := parseAddress(args[0])
+	if !ok {
+		return "Invalid address."
+	}
+
+	if strings.EqualFold(args[1], "reset") {
+		if err := b.manager.ClearWalletThreshold(wallet); err != nil {
+			return changeFailed(err)
+		}
+		return fmt.Sprintf("✅ %s uses the thresholds of its directions again.", b.label(wallet))
+	}
+
+	eth, err := strconv.ParseFloat(args[1], 64)
+	if err != nil {
+		return "Invalid threshold, give an amount in ETH or reset."
+	}
+	if err := b.manager.SetWalletThreshold(wallet, eth); err != nil {
+		return changeFailed(err)
+	}
+	return fmt.Sprintf("✅ Threshold of %s set to %.4f ETH.", b.label(wallet), eth)
+}
+
+func (b *Bot) ack(from string, args []string) (string, bool) {
+	if len(args) != 1 {
+		return "Usage: /ack <id>", false
+	}
+	if b.alerts == nil {
+		return "Alert actions are disabled.", false
+	}
+
+	if _, err := b.alerts.Ack(args[0], from); err != nil {
+		return alertFailed(err), false
+	}
+	return fmt.Sprintf("✅ Alert %s acknowledged by %s.", args[0], from), true
+}
+
+func (b *Bot) snooze(from string, args []string) (string, bool) {
+	if len(args) != 2 {
+		return "Usage: /snooze <id> <duration>, e.g. /snooze 1a2b3c4d5e6f 2h", false
+	}
+	if b.alerts == nil {
+		return "Alert actions are disabled.", false
+	}
+	d, err := time.ParseDuration(args[1])
+	if err != nil || d <= 0 {
+		return "Invalid duration, use a value such as 30m, 2h or 1h30m.", false
+	}
+
+	r, err := b.alerts.Snooze(args[0], d, from)
+	if err != nil {
+		return alertFailed(err), false
+	}
+	return fmt.Sprintf("💤 %s alerts about %s snoozed by %s until %s UTC.",
+		r.Alert.Key(), b.label(r.Alert.Wallet), from, r.SnoozedUntil.UTC().Format(time.DateTime)), true
+}
+
+// label renders a wallet with its address book label, if any.
+func (b *Bot) label(wallet string) string {
+	if label := b.book.Label(wallet); label != "" {
+		return fmt.Sprintf("%s (%s)", wallet, label)
+	}
+	return wallet
+}
+
+// parseAddress normalizes an address argument, reporting whether it is valid.
+func parseAddress(arg string) (string, bool) {
+	wallet := strings.ToLower(arg)
+	return wallet, ethrpc.IsAddress(wallet)
+}
+
+// formatAmount renders an ETH amount followed by its USD value when prices are enabled.
+func formatAmount(eth, usd float64) string {
+	if usd == 0 {
+		return fmt.Sprintf("%.4f ETH", eth)
+	}
+	return fmt.Sprintf("%.4f ETH ($%.2f)", eth, usd)
+}
+
+// formatThreshold renders the threshold a wallet's window is compared against.
+func formatThreshold(snap aggregator.Snapshot, direction aggregator.Direction, wallet string) string {
+	if eth, ok := snap.WalletThresholds[wallet]; ok {
+		return fmt.Sprintf("%.4f ETH (wallet)", eth)
+	}
+	t := snap.Thresholds[direction]
+	if t.USD > 0 {
+		return fmt.Sprintf("$%.2f", t.USD)
+	}
+	return fmt.Sprintf("%.4f ETH", t.ETH)
+}
+
+// sender names the user who sent a command or pressed a button.
+func sender(u *telego.User) string {
+	switch {
+	case u == nil:
+		return "unknown"
+	case u.Username != "":
+		return "@" + u.Username
+	default:
+		return u.FirstName
+	}
+}
+
+// alertFailed explains why an alert could not be acknowledged or snoozed.
+func alertFailed(err error) string {
+	if errors.Is(err, alerting.ErrUnknownAlert) {
+		return "Unknown alert, it may be too old."
+	}
+	return "❌ " + err.Error()
+}
+
+// changeFailed explains why a change was not made, or was made but not saved.
+func changeFailed(err error) string {
+	var saveErr *settings.SaveError
+	if errors.As(err, &saveErr) {
+		return "⚠️ Applied but not saved, so the change is lost on restart: " + saveErr.Err.Error()
+	}
+	return "❌ " + err.Error()
+}
@@ -0,0 +1,368 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mymmrac/telego"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yermakovsa/eth-watcher/internal/addressbook"
+	"github.com/yermakovsa/eth-watcher/internal/aggregator"
+	"github.com/yermakovsa/eth-watcher/internal/alerting"
+	"github.com/yermakovsa/eth-watcher/internal/notifier"
+	"github.com/yermakovsa/eth-watcher/internal/settings"
+)
+
+const (
+	chatID = int64(42)
+	wallet = "0x00000000000000000000000000000000000000aa"
+	other  = "0x00000000000000000000000000000000000000bb"
+)
+
+type MockClient struct {
+	updates  chan telego.Update
+	mu       sync.Mutex
+	sent     []*telego.SendMessageParams
+	answered []*telego.AnswerCallbackQueryParams
+	edited   []*telego.EditMessageReplyMarkupParams
+}
+
+func (m *MockClient) UpdatesViaLongPolling(ctx context.Context, params *telego.GetUpdatesParams, options ...telego.LongPollingOption) (<-chan telego.Update, error) {
+	return m.updates, nil
+}
+
+func (m *MockClient) SendMessage(ctx context.Context, params *telego.SendMessageParams) (*telego.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, params)
+	return &telego.Message{}, nil
+}
+
+func (m *MockClient) AnswerCallbackQuery(ctx context.Context, params *telego.AnswerCallbackQueryParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.answered = append(m.answered, params)
+	return nil
+}
+
+func (m *MockClient) EditMessageReplyMarkup(ctx context.Context, params *telego.EditMessageReplyMarkupParams) (*telego.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.edited = append(m.edited, params)
+	return &telego.Message{}, nil
+}
+
+type MockWatcher struct {
+	wallets map[aggregator.Direction][]string
+	ready   error
+}
+
+func (m *MockWatcher) Wallets() map[aggregator.Direction][]string {
+	return m.wallets
+}
+
+func (m *MockWatcher) Ready(maxEventAge time.Duration) error {
+	return m.ready
+}
+
+type MockAggregator struct {
+	snapshot aggregator.Snapshot
+}
+
+func (m *MockAggregator) Snapshot() aggregator.Snapshot {
+	return m.snapshot
+}
+
+type MockMutes struct {
+	muted map[string]time.Time
+}
+
+func (m *MockMutes) Mute(wallet string, until time.Time) {
+	m.muted[wallet] = until
+}
+
+func (m *MockMutes) Unmute(wallet string) bool {
+	_, ok := m.muted[wallet]
+	delete(m.muted, wallet)
+	return ok
+}
+
+func (m *MockMutes) UnmuteAll() int {
+	n := len(m.muted)
+	clear(m.muted)
+	return n
+}
+
+func (m *MockMutes) Muted() map[string]time.Time {
+	return m.muted
+}
+
+type MockManager struct {
+	watcher    *MockWatcher
+	thresholds map[string]float64
+	err        error
+}
+
+func (m *MockManager) AddWallet(direction aggregator.Direction, wallet string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.watcher.wallets[direction] = append(m.watcher.wallets[direction], wallet)
+	return nil
+}
+
+func (m *MockManager) SetWalletThreshold(wallet string, eth float64) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.thresholds[wallet] = eth
+	return nil
+}
+
+func (m *MockManager) ClearWalletThreshold(wallet string) error {
+	delete(m.thresholds, wallet)
+	return m.err
+}
+
+type MockAlerts struct {
+	records map[string]alerting.Record
+}
+
+func (m *MockAlerts) Ack(id, by string) (alerting.Record, error) {
+	r, ok := m.records[id]
+	if !ok {
+		return alerting.Record{}, alerting.ErrUnknownAlert
+	}
+	r.AckedBy = by
+	m.records[id] = r
+	return r, nil
+}
+
+func (m *MockAlerts) Snooze(id string, d time.Duration, by string) (alerting.Record, error) {
+	r, err := m.Ack(id, by)
+	if err != nil {
+		return r, err
+	}
+	r.SnoozedUntil = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC).Add(d)
+	m.records[id] = r
+	return r, nil
+}
+
+type fixture struct {
+	bot     *Bot
+	client  *MockClient
+	watcher *MockWatcher
+	mutes   *MockMutes
+	manager *MockManager
+	alerts  *MockAlerts
+}
+
+func newFixture() fixture {
+	w := &MockWatcher{wallets: map[aggregator.Direction][]string{
+		aggregator.From: {wallet},
+		aggregator.Net:  {wallet, other},
+	}}
+	agg := &MockAggregator{snapshot: aggregator.Snapshot{
+		Window:   5 * time.Minute,
+		Cooldown: 30 * time.Second,
+		Thresholds: map[aggregator.Direction]aggregator.Thresholds{
+			aggregator.From: {ETH: 10},
+			aggregator.Net:  {ETH: 20, USD: 50000},
+		},
+		WalletThresholds: map[string]float64{other: 5},
+		Wallets: []aggregator.WalletSnapshot{{
+			Wallet:            wallet,
+			Direction:         aggregator.From,
+			Total:             12.5,
+			Count:             2,
+			CooldownRemaining: 20 * time.Second,
+		}},
+	}}
+	f := fixture{
+		client:  &MockClient{updates: make(chan telego.Update)},
+		watcher: w,
+		mutes:   &MockMutes{muted: map[string]time.Time{}},
+		manager: &MockManager{watcher: w, thresholds: map[string]float64{}},
+		alerts: &MockAlerts{records: map[string]alerting.Record{
+			"1a2b3c": {Alert: notifier.Alert{ID: "1a2b3c", Type: notifier.AlertThreshold, Wallet: wallet}},
+		}},
+	}
+	book := addressbook.New(map[string]addressbook.Entry{wallet: {Label: "Hot wallet"}})
+	f.bot = New(f.client, []int64{chatID}, w, agg, f.mutes, f.manager, WithAddressBook(book), WithAlerts(f.alerts))
+	f.bot.now = func() time.Time { return time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC) }
+	return f
+}
+
+func TestBot_RepliesToAuthorizedChats(t *testing.T) {
+	f := newFixture()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, f.bot.Start(ctx))
+
+	f.client.updates <- telego.Update{Message: &telego.Message{Chat: telego.Chat{ID: 7}, Text: "/status"}}
+	f.client.updates <- telego.Update{Message: &telego.Message{Chat: telego.Chat{ID: chatID}, Text: "hello"}}
+	f.client.updates <- telego.Update{Message: &telego.Message{Chat: telego.Chat{ID: chatID}, Text: "/help@eth_watcher_bot"}}
+	close(f.client.updates)
+
+	require.Eventually(t, func() bool {
+		f.client.mu.Lock()
+		defer f.client.mu.Unlock()
+		return len(f.client.sent) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	f.client.mu.Lock()
+	defer f.client.mu.Unlock()
+	assert.Equal(t, chatID, f.client.sent[0].ChatID.ID)
+	assert.Equal(t, help, f.client.sent[0].Text, "only the command from the authorized chat is answered")
+}
+
+func TestBot_Status(t *testing.T) {
+	f := newFixture()
+	f.mutes.muted[wallet] = time.Now().Add(time.Hour)
+
+	assert.Equal(t, `✅ Stream healthy
+Monitored wallets: 1 from, 0 to, 2 net
+Window: 5m0s, cooldown: 30s
+Muted wallets: 1`, f.bot.run("@alice", "/status", nil))
+
+	f.watcher.ready = errors.New("no event for 20m0s")
+	assert.Contains(t, f.bot.run("@alice", "/status", nil), "⚠️ Stream unhealthy: no event for 20m0s")
+}
+
+func TestBot_Wallets(t *testing.T) {
+	f := newFixture()
+	f.mutes.muted[other] = time.Date(2025, 6, 1, 14, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, `from:
+`+wallet+` (Hot wallet)
+net:
+`+wallet+` (Hot wallet)
+`+other+` 🔇 until 2025-06-01 14:00:00`, f.bot.run("@alice", "/wallets", nil))
+
+	f.watcher.wallets = map[aggregator.Direction][]string{}
+	assert.Equal(t, "No wallets are monitored.", f.bot.run("@alice", "/wallets", nil))
+}
+
+func TestBot_Volume(t *testing.T) {
+	f := newFixture()
+
+	assert.Equal(t, wallet+` (Hot wallet), last 5m0s
+from: 12.5000 ETH in 2 tx, threshold 10.0000 ETH
+  cooling down for 20s
+net: 0.0000 ETH in 0 tx, threshold $50000.00`, f.bot.run("@alice", "/volume", []string{"0x00000000000000000000000000000000000000AA"}))
+
+	assert.Equal(t, other+`, last 5m0s
+net: 0.0000 ETH in 0 tx, threshold 5.0000 ETH (wallet)`, f.bot.run("@alice", "/volume", []string{other}))
+
+	assert.Equal(t, "Wallet is not monitored.", f.bot.run("@alice", "/volume", []string{"0x00000000000000000000000000000000000000cc"}))
+	assert.Equal(t, "Invalid address.", f.bot.run("@alice", "/volume", []string{"0xabc"}))
+	assert.Equal(t, "Usage: /volume <addr>", f.bot.run("@alice", "/volume", nil))
+}
+
+func TestBot_MuteAndUnmute(t *testing.T) {
+	f := newFixture()
+
+	assert.Equal(t, "🔇 Muted "+wallet+" (Hot wallet) until 2025-06-01 14:00:00 UTC. Critical alerts are still sent.",
+		f.bot.run("@alice", "/mute", []string{wallet, "2h"}))
+	assert.Equal(t, time.Date(2025, 6, 1, 14, 0, 0, 0, time.UTC), f.mutes.muted[wallet])
+	assert.Contains(t, f.bot.run("@alice", "/mute", []string{wallet, "soon"}), "Invalid duration")
+	assert.Contains(t, f.bot.run("@alice", "/mute", []string{wallet, "-1h"}), "Invalid duration")
+
+	assert.Equal(t, "🔔 Unmuted "+wallet+" (Hot wallet).", f.bot.run("@alice", "/unmute", []string{wallet}))
+	assert.Equal(t, "Wallet is not muted.", f.bot.run("@alice", "/unmute", []string{wallet}))
+
+	f.bot.run("@alice", "/mute", []string{wallet, "1h"})
+	f.bot.run("@alice", "/mute", []string{other, "1h"})
+	assert.Equal(t, "🔔 Unmuted 2 wallet(s).", f.bot.run("@alice", "/unmute", nil))
+}
+
+func TestBot_Add(t *testing.T) {
+	f := newFixture()
+
+	assert.Equal(t, "✅ Monitoring "+other+" (to).", f.bot.run("@alice", "/add", []string{other, "TO"}))
+	assert.True(t, slices.Contains(f.watcher.wallets[aggregator.To], other))
+	assert.Equal(t, "Direction must be from, to or net.", f.bot.run("@alice", "/add", []string{other, "up"}))
+
+	f.manager.err = errors.New("resubscribe: dial client: connection refused")
+	assert.Equal(t, "❌ resubscribe: dial client: connection refused", f.bot.run("@alice", "/add", []string{other, "from"}))
+}
+
+func TestBot_Threshold(t *testing.T) {
+	f := newFixture()
+
+	assert.Equal(t, "✅ Threshold of "+wallet+" (Hot wallet) set to 25.0000 ETH.", f.bot.run("@alice", "/threshold", []string{wallet, "25"}))
+	assert.Equal(t, map[string]float64{wallet: 25}, f.manager.thresholds)
+	assert.Contains(t, f.bot.run("@alice", "/threshold", []string{wallet, "lots"}), "Invalid threshold")
+
+	assert.Contains(t, f.bot.run("@alice", "/threshold", []string{wallet, "reset"}), "uses the thresholds of its directions again")
+	assert.Empty(t, f.manager.thresholds)
+
+	f.manager.err = &settings.SaveError{Err: errors.New("read-only file system")}
+	assert.Equal(t, "⚠️ Applied but not saved, so the change is lost on restart: read-only file system",
+		f.bot.run("@alice", "/threshold", []string{wallet, "25"}))
+}
+
+func TestBot_UnknownCommand(t *testing.T) {
+	f := newFixture()
+	assert.Equal(t, "Unknown command. Send /help for the list of commands.", f.bot.run("@alice", "/balance", nil))
+}
+
+func TestBot_AckAndSnooze(t *testing.T) {
+	f := newFixture()
+
+	assert.Equal(t, "✅ Alert 1a2b3c acknowledged by @alice.", f.bot.run("@alice", "/ack", []string{"1a2b3c"}))
+	assert.Equal(t, "@alice", f.alerts.records["1a2b3c"].AckedBy)
+	assert.Equal(t, "Unknown alert, it may be too old.", f.bot.run("@alice", "/ack", []string{"ffffff"}))
+	assert.Equal(t, "Usage: /ack <id>", f.bot.run("@alice", "/ack", nil))
+
+	assert.Equal(t, "💤 threshold alerts about "+wallet+" (Hot wallet) snoozed by @bob until 2025-06-01 14:00:00 UTC.",
+		f.bot.run("@bob", "/snooze", []string{"1a2b3c", "2h"}))
+	assert.Equal(t, "Invalid duration, use a value such as 30m, 2h or 1h30m.", f.bot.run("@bob", "/snooze", []string{"1a2b3c", "soon"}))
+
+	f.bot.alerts = nil
+	assert.Equal(t, "Alert actions are disabled.", f.bot.run("@alice", "/ack", []string{"1a2b3c"}))
+}
+
+func TestBot_AlertButtons(t *testing.T) {
+	f := newFixture()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, f.bot.Start(ctx))
+
+	press := func(chat int64, data string) telego.Update {
+		return telego.Update{CallbackQuery: &telego.CallbackQuery{
+			ID:      data,
+			From:    telego.User{FirstName: "Alice"},
+			Message: &telego.Message{Chat: telego.Chat{ID: chat}, MessageID: 9},
+			Data:    data,
+		}}
+	}
+	f.client.updates <- press(7, "ack:1a2b3c")
+	f.client.updates <- press(chatID, "ack:ffffff")
+	f.client.updates <- press(chatID, "snooze:1a2b3c:1h0m0s")
+	close(f.client.updates)
+
+	require.Eventually(t, func() bool {
+		f.client.mu.Lock()
+		defer f.client.mu.Unlock()
+		return len(f.client.answered) == 3 && len(f.client.sent) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	f.client.mu.Lock()
+	defer f.client.mu.Unlock()
+	assert.Equal(t, "Not authorized.", f.client.answered[0].Text)
+	assert.Equal(t, "Unknown alert, it may be too old.", f.client.answered[1].Text)
+	reply := "💤 threshold alerts about " + wallet + " (Hot wallet) snoozed by Alice until 2025-06-01 13:00:00 UTC."
+	assert.Equal(t, reply, f.client.answered[2].Text)
+
+	require.Len(t, f.client.edited, 1, "buttons are removed once the alert is handled")
+	assert.Equal(t, 9, f.client.edited[0].MessageID)
+	assert.Nil(t, f.client.edited[0].ReplyMarkup)
+	assert.Equal(t, reply, f.client.sent[0].Text)
+	assert.Equal(t, 9, f.client.sent[0].ReplyParameters.MessageID)
+}
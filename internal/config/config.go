@@ -1,38 +1,181 @@
 package config
 
 import (
-	"log"
+	"log/slog"
 	"os"
 	"strconv"
 	"strings"
 )
 
+// alchemyHTTPURL is the default JSON-RPC endpoint, completed with the Alchemy API key
+const alchemyHTTPURL = "https://eth-mainnet.g.alchemy.com/v2/"
+
 // Config holds application settings loaded from environment variables
 type Config struct {
 	AlchemyAPIKey     string
+	RPCURL            string
 	TelegramBotAPIKey string
 	TelegramChatID    string
 	WalletsFrom       []string
 	WalletsTo         []string
+	WalletsNet        []string
 	WindowSeconds     int
 	CooldownSeconds   int
 	ThresholdETH      float64
+	NetThresholdETH   float64
+	FeeThresholdETH   float64
+	FeeWindowSeconds  int
+	CheckTxStatus     bool
+	FailedTxCount     int
+	FailedTxSeconds   int
+	CatchUpMaxBlocks  int
+	RulesFile         string
+	// CounterpartyWarmUpSeconds delays new_counterparty rules while known counterparties are learned
+	CounterpartyWarmUpSeconds int
+	TxFilter                  string
+	Watchlist                 []string
+	AddressBookFile           string
+	ContractsFile             string
+	LogsFile                  string
+	LogsPollSeconds           int
+	TraceMethod               string
+
+	SanctionsFiles          []string
+	SanctionsRefreshSeconds int
+
+	BalanceFile        string
+	BalancePollSeconds int
+
+	NonceWallets      []string
+	NoncePollSeconds  int
+	StuckAfterSeconds int
+
+	PriceFeedURL      string
+	PriceFeedField    string
+	PriceFile         string
+	PriceStaticUSD    float64
+	PriceCacheSeconds int
+	ThresholdUSD      float64
+	NetThresholdUSD   float64
+
+	HTTPAddr           string
+	StreamStaleSeconds int
+	LivenessSeconds    int
+
+	LogLevel  string
+	LogFormat string
+
+	// OTLPEndpoint is the URL traces are exported to, enabling tracing; the exporter reads
+	// the remaining OTEL_* variables itself
+	OTLPEndpoint string
+
+	SettingsFile string
+	AdminToken   string
+
+	TelegramCommands     bool
+	TelegramCommandChats []string
+
+	EscalationChatID         string
+	EscalationTimeoutSeconds int
+
+	ThresholdInfoFactor     float64
+	ThresholdCriticalFactor float64
+	InfoChatID              string
+	CriticalChatID          string
+
+	HistoryFile          string
+	HistoryRetentionDays int
 }
 
 // Load reads and parses configuration from environment variables
 func Load() Config {
-	return Config{
-		AlchemyAPIKey:     mustEnv("ALCHEMY_API_KEY"),
+	alchemyAPIKey := mustEnv("ALCHEMY_API_KEY")
+	threshold := getEnvAsFloat("THRESHOLD_ETH", 0.0)
+
+	cfg := Config{
+		AlchemyAPIKey:     alchemyAPIKey,
+		RPCURL:            getEnv("ETH_RPC_URL", alchemyHTTPURL+alchemyAPIKey),
 		TelegramBotAPIKey: mustEnv("TELEGRAM_BOT_API_KEY"),
 		TelegramChatID:    mustEnv("TELEGRAM_CHAT_ID"),
 
 		WalletsFrom: getEnvAsSlice("MONITORED_WALLETS_FROM", ","),
 		WalletsTo:   getEnvAsSlice("MONITORED_WALLETS_TO", ","),
+		WalletsNet:  getEnvAsSlice("MONITORED_WALLETS_NET", ","),
 
 		WindowSeconds:   getEnvAsInt("AGGREGATION_WINDOW_IN_SECONDS", 300),
 		CooldownSeconds: getEnvAsInt("AGGREGATION_NOTIFICATION_COOLDOWN_IN_SECONDS", 30),
-		ThresholdETH:    getEnvAsFloat("THRESHOLD_ETH", 0.0),
+		ThresholdETH:    threshold,
+		NetThresholdETH: getEnvAsFloat("NET_THRESHOLD_ETH", threshold),
+
+		FeeThresholdETH:  getEnvAsFloat("FEE_THRESHOLD_ETH", 0.0),
+		FeeWindowSeconds: getEnvAsInt("FEE_WINDOW_SECONDS", 3600),
+		CheckTxStatus:    getEnvAsBool("CHECK_TX_STATUS", false),
+		FailedTxCount:    getEnvAsInt("FAILED_TX_ALERT_COUNT", 0),
+		FailedTxSeconds:  getEnvAsInt("FAILED_TX_WINDOW_SECONDS", 600),
+
+		CatchUpMaxBlocks: getEnvAsInt("CATCHUP_MAX_BLOCKS", 300),
+		RulesFile:        getEnv("RULES_FILE", ""),
+
+		CounterpartyWarmUpSeconds: getEnvAsInt("COUNTERPARTY_WARMUP_SECONDS", 3600),
+
+		TxFilter:        getEnv("TX_FILTER", ""),
+		Watchlist:       getEnvAsSlice("WATCHLIST", ","),
+		AddressBookFile: getEnv("ADDRESS_BOOK_FILE", ""),
+		ContractsFile:   getEnv("CONTRACTS_FILE", ""),
+		LogsFile:        getEnv("LOGS_FILE", ""),
+		LogsPollSeconds: getEnvAsInt("LOGS_POLL_SECONDS", 12),
+		TraceMethod:     getEnv("TRACE_METHOD", ""),
+
+		SanctionsFiles:          getEnvAsList("SANCTIONS_FILES", ","),
+		SanctionsRefreshSeconds: getEnvAsInt("SANCTIONS_REFRESH_SECONDS", 3600),
+
+		BalanceFile:        getEnv("BALANCE_FILE", ""),
+		BalancePollSeconds: getEnvAsInt("BALANCE_POLL_SECONDS", 60),
+
+		NonceWallets:      getEnvAsSlice("NONCE_WALLETS", ","),
+		NoncePollSeconds:  getEnvAsInt("NONCE_POLL_SECONDS", 30),
+		StuckAfterSeconds: getEnvAsInt("STUCK_TX_SECONDS", 300),
+
+		PriceFeedURL:      getEnv("PRICE_FEED_URL", ""),
+		PriceFeedField:    getEnv("PRICE_FEED_FIELD", "ethereum.usd"),
+		PriceFile:         getEnv("PRICE_FILE", ""),
+		PriceStaticUSD:    getEnvAsFloat("ETH_USD_PRICE", 0.0),
+		PriceCacheSeconds: getEnvAsInt("PRICE_CACHE_SECONDS", 60),
+		ThresholdUSD:      getEnvAsFloat("THRESHOLD_USD", 0.0),
+		NetThresholdUSD:   getEnvAsFloat("NET_THRESHOLD_USD", 0.0),
+
+		HTTPAddr:           getEnv("HTTP_ADDR", "127.0.0.1:8080"),
+		StreamStaleSeconds: getEnvAsInt("STREAM_STALE_SECONDS", 0),
+		LivenessSeconds:    getEnvAsInt("LIVENESS_TIMEOUT_SECONDS", 120),
+
+		LogLevel:  getEnv("LOG_LEVEL", "info"),
+		LogFormat: getEnv("LOG_FORMAT", "text"),
+
+		OTLPEndpoint: otlpTracesEndpoint(),
+
+		SettingsFile: getEnv("SETTINGS_FILE", ""),
+		AdminToken:   getEnv("API_ADMIN_TOKEN", ""),
+
+		TelegramCommands:     getEnvAsBool("TELEGRAM_COMMANDS", false),
+		TelegramCommandChats: getEnvAsList("TELEGRAM_COMMAND_CHAT_IDS", ","),
+
+		EscalationChatID:         getEnv("ESCALATION_TELEGRAM_CHAT_ID", ""),
+		EscalationTimeoutSeconds: getEnvAsInt("ESCALATION_TIMEOUT_SECONDS", 900),
+
+		ThresholdInfoFactor:     getEnvAsFloat("THRESHOLD_INFO_FACTOR", 0),
+		ThresholdCriticalFactor: getEnvAsFloat("THRESHOLD_CRITICAL_FACTOR", 0),
+		InfoChatID:              getEnv("INFO_TELEGRAM_CHAT_ID", ""),
+		CriticalChatID:          getEnv("CRITICAL_TELEGRAM_CHAT_ID", ""),
+
+		HistoryFile:          getEnv("HISTORY_FILE", ""),
+		HistoryRetentionDays: getEnvAsInt("HISTORY_RETENTION_DAYS", 90),
+	}
+
+	// Every total reaches a zero threshold, so a critical tier would grade every transaction critical
+	if cfg.ThresholdCriticalFactor != 0 && cfg.ThresholdETH <= 0 && cfg.ThresholdUSD <= 0 {
+		fatal("THRESHOLD_CRITICAL_FACTOR requires a positive THRESHOLD_ETH or THRESHOLD_USD")
 	}
+	return cfg
 }
 
 // --- Helpers ---
@@ -40,7 +183,15 @@ func Load() Config {
 func mustEnv(key string) string {
 	val := strings.TrimSpace(os.Getenv(key))
 	if val == "" {
-		log.Fatalf("Missing required environment variable: %s", key)
+		fatal("Missing required environment variable", "key", key)
+	}
+	return val
+}
+
+func getEnv(key, defaultVal string) string {
+	val := strings.TrimSpace(os.Getenv(key))
+	if val == "" {
+		return defaultVal
 	}
 	return val
 }
@@ -52,7 +203,7 @@ func getEnvAsInt(key string, defaultVal int) int {
 	}
 	i, err := strconv.Atoi(val)
 	if err != nil {
-		log.Fatalf("Invalid int for %s: %v", key, err)
+		fatal("Invalid int", "key", key, "error", err)
 	}
 	return i
 }
@@ -64,19 +215,61 @@ func getEnvAsFloat(key string, defaultVal float64) float64 {
 	}
 	f, err := strconv.ParseFloat(val, 64)
 	if err != nil {
-		log.Fatalf("Invalid float for %s: %v", key, err)
+		fatal("Invalid float", "key", key, "error", err)
 	}
 	return f
 }
 
+func getEnvAsBool(key string, defaultVal bool) bool {
+	val := strings.TrimSpace(os.Getenv(key))
+	if val == "" {
+		return defaultVal
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		fatal("Invalid bool", "key", key, "error", err)
+	}
+	return b
+}
+
+// getEnvAsSlice splits a list of addresses, normalized to lowercase.
 func getEnvAsSlice(key, sep string) []string {
+	parts := getEnvAsList(key, sep)
+	for i, p := range parts {
+		parts[i] = strings.ToLower(p)
+	}
+	return parts
+}
+
+// getEnvAsList splits a list of values such as file paths, preserving case.
+func getEnvAsList(key, sep string) []string {
 	val := strings.TrimSpace(os.Getenv(key))
 	if val == "" {
 		return nil
 	}
 	parts := strings.Split(val, sep)
 	for i, p := range parts {
-		parts[i] = strings.ToLower(strings.TrimSpace(p))
+		parts[i] = strings.TrimSpace(p)
 	}
 	return parts
 }
+
+// otlpTracesEndpoint returns the URL traces are exported to. As in the OpenTelemetry
+// SDKs, OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is used as is, while OTEL_EXPORTER_OTLP_ENDPOINT
+// is a base URL the traces path is appended to.
+func otlpTracesEndpoint() string {
+	if endpoint := getEnv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", ""); endpoint != "" {
+		return endpoint
+	}
+	if endpoint := getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""); endpoint != "" {
+		return strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+	}
+	return ""
+}
+
+// fatal reports an invalid variable and exits. Configuration is loaded before the
+// configured logger exists, so the default one is used.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, append([]any{"component", "config"}, args...)...)
+	os.Exit(1)
+}
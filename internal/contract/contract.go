@@ -0,0 +1,157 @@
+package contract
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yermakovsa/alchemyws"
+	"github.com/yermakovsa/eth-watcher/internal/abi"
+)
+
+// Spec configures a watched contract.
+type Spec struct {
+	Address string `json:"address"`
+	Label   string `json:"label,omitempty"`
+	// ABI is the path of the contract's JSON ABI, relative to the contracts file.
+	ABI string `json:"abi"`
+	// Functions lists function names or 0x selectors to alert on. Empty matches every call.
+	Functions []string `json:"functions,omitempty"`
+}
+
+// Call is a decoded call to a watched contract.
+type Call struct {
+	Contract  string
+	Label     string
+	Method    string
+	Signature string
+	Args      []abi.Value
+	// DecodeErr is set when the arguments could not be decoded, leaving Args empty.
+	DecodeErr error
+}
+
+// String renders the call with its arguments, e.g. "transferOwnership(newOwner=0xabc...)".
+func (c Call) String() string {
+	args := make([]string, len(c.Args))
+	for i, a := range c.Args {
+		args[i] = a.String()
+	}
+	return c.Method + "(" + strings.Join(args, ", ") + ")"
+}
+
+// Matcher recognizes calls to watched contracts and decodes them.
+type Matcher struct {
+	contracts map[string]watched
+}
+
+type watched struct {
+	label     string
+	abi       *abi.ABI
+	selectors map[string]struct{} // nil matches every call
+}
+
+// New builds a matcher, loading each contract's ABI. Relative ABI paths are resolved against dir.
+func New(specs []Spec, dir string) (*Matcher, error) {
+	m := &Matcher{contracts: make(map[string]watched, len(specs))}
+	for _, spec := range specs {
+		if spec.Address == "" {
+			return nil, fmt.Errorf("contract without address")
+		}
+
+		path := spec.ABI
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		contractABI, err := abi.Load(path)
+		if err != nil {
+			return nil, fmt.Errorf("contract %s: %w", spec.Address, err)
+		}
+
+		w := watched{label: spec.Label, abi: contractABI}
+		if len(spec.Functions) > 0 {
+			w.selectors = make(map[string]struct{}, len(spec.Functions))
+			for _, fn := range spec.Functions {
+				selector, err := resolveSelector(contractABI, fn)
+				if err != nil {
+					return nil, fmt.Errorf("contract %s: %w", spec.Address, err)
+				}
+				w.selectors[selector] = struct{}{}
+			}
+		}
+		m.contracts[strings.ToLower(spec.Address)] = w
+	}
+	return m, nil
+}
+
+// Load reads a JSON array of contract specs from path.
+func Load(path string) (*Matcher, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var specs []Spec
+	if err := json.Unmarshal(data, &specs); err != nil {
+		return nil, fmt.Errorf("parse contracts: %w", err)
+	}
+	return New(specs, filepath.Dir(path))
+}
+
+// Addresses returns the watched contract addresses.
+func (m *Matcher) Addresses() []string {
+	addrs := make([]string, 0, len(m.contracts))
+	for addr := range m.contracts {
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+// Match reports whether the transaction calls a watched function and decodes the call.
+// Calls whose arguments cannot be decoded still match, without arguments and with DecodeErr set.
+func (m *Matcher) Match(tx alchemyws.Transaction) (Call, bool) {
+	contract := strings.ToLower(tx.To)
+	w, ok := m.contracts[contract]
+	if !ok {
+		return Call{}, false
+	}
+
+	input, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(tx.Input), "0x"))
+	if err != nil || len(input) < 4 {
+		return Call{}, false
+	}
+	selector := "0x" + hex.EncodeToString(input[:4])
+	if w.selectors != nil {
+		if _, ok := w.selectors[selector]; !ok {
+			return Call{}, false
+		}
+	}
+
+	call := Call{Contract: contract, Label: w.label, Method: selector}
+	method, ok := w.abi.MethodBySelector(selector)
+	if !ok {
+		return call, true
+	}
+
+	call.Method = method.Name
+	call.Signature = method.Signature()
+	call.Args, call.DecodeErr = method.DecodeInput(input)
+	return call, true
+}
+
+// resolveSelector accepts a 0x selector, or a function name or signature from the ABI.
+func resolveSelector(contractABI *abi.ABI, fn string) (string, error) {
+	if strings.HasPrefix(fn, "0x") && len(fn) == 10 {
+		if _, err := hex.DecodeString(fn[2:]); err != nil {
+			return "", fmt.Errorf("invalid selector %q", fn)
+		}
+		return strings.ToLower(fn), nil
+	}
+	method, err := contractABI.MethodByName(fn)
+	if err != nil {
+		return "", err
+	}
+	return method.Selector, nil
+}
@@ -0,0 +1,83 @@
+package contract
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yermakovsa/alchemyws"
+)
+
+const proxyABI = `[
+	{"type": "function", "name": "upgradeTo", "inputs": [{"name": "newImplementation", "type": "address"}]},
+	{"type": "function", "name": "transferOwnership", "inputs": [{"name": "newOwner", "type": "address"}]},
+	{"type": "function", "name": "pause", "inputs": []},
+	{"type": "function", "name": "deposit", "inputs": []}
+]`
+
+func newMatcher(t *testing.T) *Matcher {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "proxy.json"), []byte(proxyABI), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "contracts.json"), []byte(`[
+		{"address": "0xPROXY", "label": "Bridge proxy", "abi": "proxy.json", "functions": ["upgradeTo", "transferOwnership", "0x8456cb59"]}
+	]`), 0o600))
+
+	m, err := Load(filepath.Join(dir, "contracts.json"))
+	require.NoError(t, err)
+	return m
+}
+
+func TestMatcher_DecodesWatchedCall(t *testing.T) {
+	m := newMatcher(t)
+	assert.Equal(t, []string{"0xproxy"}, m.Addresses())
+
+	call, ok := m.Match(alchemyws.Transaction{
+		To:    "0xProxy",
+		Input: "0x3659cfe6" + strings.Repeat("0", 24) + strings.Repeat("ab", 20),
+	})
+	require.True(t, ok)
+	assert.Equal(t, "Bridge proxy", call.Label)
+	assert.Equal(t, "upgradeTo(address)", call.Signature)
+	assert.Equal(t, "upgradeTo(newImplementation=0x"+strings.Repeat("ab", 20)+")", call.String())
+
+	call, ok = m.Match(alchemyws.Transaction{To: "0xproxy", Input: "0x8456cb59"})
+	require.True(t, ok)
+	assert.Equal(t, "pause()", call.String())
+}
+
+func TestMatcher_MatchesUndecodableCall(t *testing.T) {
+	m := newMatcher(t)
+
+	call, ok := m.Match(alchemyws.Transaction{To: "0xproxy", Input: "0x3659cfe6abcd"})
+	require.True(t, ok)
+	assert.Equal(t, "upgradeTo", call.Method)
+	assert.Empty(t, call.Args)
+	assert.Error(t, call.DecodeErr)
+}
+
+func TestMatcher_IgnoresOtherCalls(t *testing.T) {
+	m := newMatcher(t)
+
+	_, ok := m.Match(alchemyws.Transaction{To: "0xproxy", Input: "0xd0e30db0"}) // deposit()
+	assert.False(t, ok)
+
+	_, ok = m.Match(alchemyws.Transaction{To: "0xother", Input: "0x8456cb59"})
+	assert.False(t, ok)
+
+	_, ok = m.Match(alchemyws.Transaction{To: "0xproxy", Input: "0x"})
+	assert.False(t, ok)
+}
+
+func TestNew_UnknownFunction(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "proxy.json"), []byte(proxyABI), 0o600))
+
+	_, err := New([]Spec{{Address: "0xproxy", ABI: "proxy.json", Functions: []string{"selfdestruct"}}}, dir)
+	assert.ErrorContains(t, err, "not found in ABI")
+
+	_, err = New([]Spec{{Address: "0xproxy", ABI: "proxy.json", Functions: []string{"0xzzzzzzzz"}}}, dir)
+	assert.ErrorContains(t, err, `invalid selector "0xzzzzzzzz"`)
+}
@@ -0,0 +1,398 @@
+package ethrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yermakovsa/alchemyws"
+)
+
+const (
+	jsonRPCVersion = "2.0"
+	blockCacheSize = 1024
+)
+
+// addressPattern matches a hex address in either case, without checking an EIP-55 checksum.
+var addressPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+// Block is the subset of an eth_getBlockByNumber response used by the service.
+type Block struct {
+	Number       string                  `json:"number"`
+	Hash         string                  `json:"hash"`
+	Timestamp    string                  `json:"timestamp"`
+	Transactions []alchemyws.Transaction `json:"transactions"`
+}
+
+// Log is an event log as returned by eth_getLogs.
+type Log struct {
+	Address         string   `json:"address"`
+	Topics          []string `json:"topics"`
+	Data            string   `json:"data"`
+	BlockNumber     string   `json:"blockNumber"`
+	TransactionHash string   `json:"transactionHash"`
+	LogIndex        string   `json:"logIndex"`
+	Removed         bool     `json:"removed"`
+}
+
+// Receipt is the subset of an eth_getTransactionReceipt response used by the service.
+type Receipt struct {
+	TransactionHash   string `json:"transactionHash"`
+	BlockNumber       string `json:"blockNumber"`
+	From              string `json:"from"`
+	To                string `json:"to"`
+	Status            string `json:"status"`
+	GasUsed           string `json:"gasUsed"`
+	EffectiveGasPrice string `json:"effectiveGasPrice"`
+}
+
+// Failed reports whether the transaction reverted. Receipts without a status,
+// from before the Byzantium fork, are treated as successful.
+func (r *Receipt) Failed() bool {
+	return r.Status == "0x0"
+}
+
+// Fee returns the amount paid for the transaction in wei, gasUsed × effectiveGasPrice.
+func (r *Receipt) Fee() (*big.Int, error) {
+	gasUsed, err := ParseBig(r.GasUsed)
+	if err != nil {
+		return nil, fmt.Errorf("gas used: %w", err)
+	}
+	price, err := ParseBig(r.EffectiveGasPrice)
+	if err != nil {
+		return nil, fmt.Errorf("effective gas price: %w", err)
+	}
+	return gasUsed.Mul(gasUsed, price), nil
+}
+
+// LogFilter selects logs by block range, emitting contracts and topics.
+// Each topic position holds alternatives; an empty position matches anything.
+type LogFilter struct {
+	FromBlock uint64
+	ToBlock   uint64
+	Addresses []string
+	Topics    [][]string
+}
+
+type request struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int64  `json:"id"`
+	Method  string `json:"method"`
+	Params  []any  `json:"params"`
+}
+
+type response struct {
+	ID     int64           `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+// Error is a JSON-RPC error returned by the node.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// Client is a minimal Ethereum JSON-RPC client over HTTP.
+type Client struct {
+	url    string
+	http   *http.Client
+	nextID atomic.Int64
+
+	mu         sync.Mutex
+	blockTimes map[uint64]time.Time
+}
+
+// NewClient creates a JSON-RPC client for the given endpoint.
+// If httpClient is nil, a client with a 10 second timeout is used.
+func NewClient(url string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Client{
+		url:        url,
+		http:       httpClient,
+		blockTimes: make(map[uint64]time.Time),
+	}
+}
+
+// Call invokes a JSON-RPC method and decodes its result into result.
+func (c *Client) Call(ctx context.Context, result any, method string, params ...any) error {
+	if params == nil {
+		params = []any{}
+	}
+
+	var out response
+	if err := c.post(ctx, request{
+		JSONRPC: jsonRPCVersion,
+		ID:      c.nextID.Add(1),
+		Method:  method,
+		Params:  params,
+	}, &out); err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	if out.Error != nil {
+		return fmt.Errorf("%s: %w", method, out.Error)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(out.Result, result)
+}
+
+// BatchElem is one request of a batch call. Result and Error are filled in by BatchCall.
+type BatchElem struct {
+	Method string
+	Params []any
+	Result any
+	Error  error
+}
+
+// BatchCall sends every request in a single round trip. Failures of individual requests
+// are reported in the element's Error; the returned error only covers the round trip itself.
+func (c *Client) BatchCall(ctx context.Context, batch []BatchElem) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	reqs := make([]request, len(batch))
+	byID := make(map[int64]int, len(batch))
+	for i, elem := range batch {
+		params := elem.Params
+		if params == nil {
+			params = []any{}
+		}
+		id := c.nextID.Add(1)
+		reqs[i] = request{JSONRPC: jsonRPCVersion, ID: id, Method: elem.Method, Params: params}
+		byID[id] = i
+	}
+
+	var out []response
+	if err := c.post(ctx, reqs, &out); err != nil {
+		return fmt.Errorf("batch: %w", err)
+	}
+
+	answered := make([]bool, len(batch))
+	for _, resp := range out {
+		i, ok := byID[resp.ID]
+		if !ok {
+			continue
+		}
+		answered[i] = true
+		elem := &batch[i]
+		switch {
+		case resp.Error != nil:
+			elem.Error = fmt.Errorf("%s: %w", elem.Method, resp.Error)
+		case elem.Result != nil:
+			if err := json.Unmarshal(resp.Result, elem.Result); err != nil {
+				elem.Error = fmt.Errorf("%s: %w", elem.Method, err)
+			}
+		}
+	}
+	for i, ok := range answered {
+		if !ok {
+			batch[i].Error = fmt.Errorf("%s: missing response", batch[i].Method)
+		}
+	}
+	return nil
+}
+
+// post sends a JSON-RPC payload and decodes the response body into out.
+func (c *Client) post(ctx context.Context, payload any, out any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// BlockNumber returns the number of the most recent block.
+func (c *Client) BlockNumber(ctx context.Context) (uint64, error) {
+	var raw string
+	if err := c.Call(ctx, &raw, "eth_blockNumber"); err != nil {
+		return 0, err
+	}
+	return ParseQuantity(raw)
+}
+
+// BlockByNumber fetches a block together with its full transaction objects.
+func (c *Client) BlockByNumber(ctx context.Context, number uint64) (*Block, error) {
+	var block *Block
+	if err := c.Call(ctx, &block, "eth_getBlockByNumber", EncodeQuantity(number), true); err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block %d not found", number)
+	}
+	if ts, err := ParseQuantity(block.Timestamp); err == nil {
+		c.cacheBlockTime(number, time.Unix(int64(ts), 0).UTC())
+	}
+	return block, nil
+}
+
+// GetLogs returns the logs matching the filter.
+func (c *Client) GetLogs(ctx context.Context, filter LogFilter) ([]Log, error) {
+	topics := make([]any, len(filter.Topics))
+	for i, alternatives := range filter.Topics {
+		if len(alternatives) > 0 {
+			topics[i] = alternatives
+		}
+	}
+
+	params := map[string]any{
+		"fromBlock": EncodeQuantity(filter.FromBlock),
+		"toBlock":   EncodeQuantity(filter.ToBlock),
+		"address":   filter.Addresses,
+		"topics":    topics,
+	}
+
+	var logs []Log
+	if err := c.Call(ctx, &logs, "eth_getLogs", params); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// Balance returns the balance of an address in wei at the latest block.
+func (c *Client) Balance(ctx context.Context, address string) (*big.Int, error) {
+	var raw string
+	if err := c.Call(ctx, &raw, "eth_getBalance", address, "latest"); err != nil {
+		return nil, err
+	}
+	return ParseBig(raw)
+}
+
+// TransactionCount returns the number of transactions sent from an address, which is also
+// its next nonce. block is "latest" for mined transactions or "pending" to include the mempool.
+func (c *Client) TransactionCount(ctx context.Context, address, block string) (uint64, error) {
+	var raw string
+	if err := c.Call(ctx, &raw, "eth_getTransactionCount", address, block); err != nil {
+		return 0, err
+	}
+	return ParseQuantity(raw)
+}
+
+// TransactionReceipt fetches the receipt of a mined transaction.
+func (c *Client) TransactionReceipt(ctx context.Context, hash string) (*Receipt, error) {
+	var receipt *Receipt
+	if err := c.Call(ctx, &receipt, "eth_getTransactionReceipt", hash); err != nil {
+		return nil, err
+	}
+	if receipt == nil {
+		return nil, fmt.Errorf("receipt for %s not found", hash)
+	}
+	return receipt, nil
+}
+
+// BlockTimestamp returns the timestamp of a block, served from cache when possible.
+func (c *Client) BlockTimestamp(ctx context.Context, number uint64) (time.Time, error) {
+	c.mu.Lock()
+	ts, ok := c.blockTimes[number]
+	c.mu.Unlock()
+	if ok {
+		return ts, nil
+	}
+
+	var header struct {
+		Timestamp string `json:"timestamp"`
+	}
+	if err := c.Call(ctx, &header, "eth_getBlockByNumber", EncodeQuantity(number), false); err != nil {
+		return time.Time{}, err
+	}
+	raw, err := ParseQuantity(header.Timestamp)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("block %d: invalid timestamp: %w", number, err)
+	}
+
+	ts = time.Unix(int64(raw), 0).UTC()
+	c.cacheBlockTime(number, ts)
+	return ts, nil
+}
+
+// cacheBlockTime stores a block timestamp, evicting the oldest block when the cache is full.
+func (c *Client) cacheBlockTime(number uint64, ts time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.blockTimes[number]; !ok && len(c.blockTimes) >= blockCacheSize {
+		oldest := number
+		for n := range c.blockTimes {
+			if n < oldest {
+				oldest = n
+			}
+		}
+		if oldest == number {
+			return
+		}
+		delete(c.blockTimes, oldest)
+	}
+	c.blockTimes[number] = ts
+}
+
+// ParseQuantity decodes a hex-encoded JSON-RPC quantity such as "0x1b4".
+func ParseQuantity(raw string) (uint64, error) {
+	cleaned := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(raw)), "0x")
+	if cleaned == "" {
+		return 0, fmt.Errorf("empty quantity")
+	}
+	return strconv.ParseUint(cleaned, 16, 64)
+}
+
+// ParseBig decodes a hex-encoded quantity that may exceed 64 bits, such as a wei amount.
+func ParseBig(raw string) (*big.Int, error) {
+	cleaned := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(raw)), "0x")
+	n, ok := new(big.Int).SetString(cleaned, 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid hex quantity '%s'", raw)
+	}
+	return n, nil
+}
+
+// WeiToEth converts an amount in wei to ETH.
+func WeiToEth(wei *big.Int) float64 {
+	eth := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e18))
+	result, _ := eth.Float64()
+	return result
+}
+
+// IsAddress reports whether s is a 0x-prefixed, 20-byte hex address.
+func IsAddress(s string) bool {
+	return addressPattern.MatchString(s)
+}
+
+// EncodeQuantity encodes a number as a hex JSON-RPC quantity.
+func EncodeQuantity(n uint64) string {
+	return "0x" + strconv.FormatUint(n, 16)
+}
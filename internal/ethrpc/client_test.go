@@ -0,0 +1,220 @@
+package ethrpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestServer answers every JSON-RPC request through handler and counts the calls.
+func newTestServer(t *testing.T, handler func(method string, params []json.RawMessage) (any, *Error)) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var req struct {
+			ID     int64             `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		result, rpcErr := handler(req.Method, req.Params)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+			"result":  result,
+			"error":   rpcErr,
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+// batchLog records the number of requests in every batch received by a test server.
+type batchLog struct {
+	mu    sync.Mutex
+	batch []int
+}
+
+func (b *batchLog) sizes() []int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]int(nil), b.batch...)
+}
+
+// newBatchTestServer answers JSON-RPC batch requests through handler.
+func newBatchTestServer(t *testing.T, handler func(method string, params []json.RawMessage) (any, *Error)) (*httptest.Server, *batchLog) {
+	var log batchLog
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqs []struct {
+			ID     int64             `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqs))
+
+		log.mu.Lock()
+		log.batch = append(log.batch, len(reqs))
+		log.mu.Unlock()
+
+		out := make([]map[string]any, 0, len(reqs))
+		for _, req := range reqs {
+			result, rpcErr := handler(req.Method, req.Params)
+			out = append(out, map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result, "error": rpcErr})
+		}
+		_ = json.NewEncoder(w).Encode(out)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &log
+}
+
+func TestClient_BlockNumber(t *testing.T) {
+	srv, _ := newTestServer(t, func(method string, params []json.RawMessage) (any, *Error) {
+		assert.Equal(t, "eth_blockNumber", method)
+		return "0x1b4", nil
+	})
+
+	n, err := NewClient(srv.URL, nil).BlockNumber(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(436), n)
+}
+
+func TestClient_BlockTimestamp_Cached(t *testing.T) {
+	srv, calls := newTestServer(t, func(method string, params []json.RawMessage) (any, *Error) {
+		assert.Equal(t, "eth_getBlockByNumber", method)
+		assert.JSONEq(t, `"0xa"`, string(params[0]))
+		return map[string]any{"number": "0xa", "timestamp": "0x6553f100"}, nil
+	})
+
+	client := NewClient(srv.URL, nil)
+	for i := 0; i < 3; i++ {
+		ts, err := client.BlockTimestamp(context.Background(), 10)
+		require.NoError(t, err)
+		assert.Equal(t, time.Unix(0x6553f100, 0).UTC(), ts)
+	}
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestClient_RPCError(t *testing.T) {
+	srv, _ := newTestServer(t, func(method string, params []json.RawMessage) (any, *Error) {
+		return nil, &Error{Code: -32000, Message: "header not found"}
+	})
+
+	_, err := NewClient(srv.URL, nil).BlockTimestamp(context.Background(), 1)
+	assert.ErrorContains(t, err, "header not found")
+}
+
+func TestClient_GetLogs(t *testing.T) {
+	srv, _ := newTestServer(t, func(method string, params []json.RawMessage) (any, *Error) {
+		assert.Equal(t, "eth_getLogs", method)
+		assert.JSONEq(t, `{
+			"fromBlock": "0x1",
+			"toBlock": "0xa",
+			"address": ["0xtoken"],
+			"topics": [["0xtopic"], null, ["0xto"]]
+		}`, string(params[0]))
+		return []map[string]any{{"address": "0xtoken", "topics": []string{"0xtopic"}, "data": "0x", "blockNumber": "0x5"}}, nil
+	})
+
+	logs, err := NewClient(srv.URL, nil).GetLogs(context.Background(), LogFilter{
+		FromBlock: 1,
+		ToBlock:   10,
+		Addresses: []string{"0xtoken"},
+		Topics:    [][]string{{"0xtopic"}, nil, {"0xto"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Equal(t, "0x5", logs[0].BlockNumber)
+}
+
+func TestClient_Balance(t *testing.T) {
+	srv, _ := newTestServer(t, func(method string, params []json.RawMessage) (any, *Error) {
+		assert.Equal(t, "eth_getBalance", method)
+		assert.JSONEq(t, `"0xabc"`, string(params[0]))
+		assert.JSONEq(t, `"latest"`, string(params[1]))
+		return "0x1bc16d674ec80000", nil
+	})
+
+	wei, err := NewClient(srv.URL, nil).Balance(context.Background(), "0xabc")
+	require.NoError(t, err)
+	assert.InDelta(t, 2.0, WeiToEth(wei), 1e-12)
+}
+
+func TestClient_TransactionCount(t *testing.T) {
+	srv, _ := newTestServer(t, func(method string, params []json.RawMessage) (any, *Error) {
+		assert.Equal(t, "eth_getTransactionCount", method)
+		assert.JSONEq(t, `"pending"`, string(params[1]))
+		return "0x2a", nil
+	})
+
+	n, err := NewClient(srv.URL, nil).TransactionCount(context.Background(), "0xabc", "pending")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), n)
+}
+
+func TestClient_TransactionReceipt(t *testing.T) {
+	srv, _ := newTestServer(t, func(method string, params []json.RawMessage) (any, *Error) {
+		assert.Equal(t, "eth_getTransactionReceipt", method)
+		if string(params[0]) == `"0xpending"` {
+			return nil, nil
+		}
+		return map[string]any{"transactionHash": "0xhash", "status": "0x1", "gasUsed": "0x5208", "effectiveGasPrice": "0x3b9aca00"}, nil
+	})
+	client := NewClient(srv.URL, nil)
+
+	receipt, err := client.TransactionReceipt(context.Background(), "0xhash")
+	require.NoError(t, err)
+	fee, err := receipt.Fee()
+	require.NoError(t, err)
+	assert.Equal(t, "21000000000000", fee.String()) // 21000 gas at 1 gwei
+
+	_, err = client.TransactionReceipt(context.Background(), "0xpending")
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestClient_BatchCall(t *testing.T) {
+	srv, batches := newBatchTestServer(t, func(method string, params []json.RawMessage) (any, *Error) {
+		if method == "eth_blockNumber" {
+			return "0x10", nil
+		}
+		return nil, &Error{Code: -32601, Message: "method not found"}
+	})
+
+	var number string
+	batch := []BatchElem{
+		{Method: "eth_blockNumber", Result: &number},
+		{Method: "eth_unknown"},
+	}
+	require.NoError(t, NewClient(srv.URL, nil).BatchCall(context.Background(), batch))
+
+	assert.Equal(t, []int{2}, batches.sizes())
+	assert.NoError(t, batch[0].Error)
+	assert.Equal(t, "0x10", number)
+	assert.ErrorContains(t, batch[1].Error, "method not found")
+}
+
+func TestParseQuantity(t *testing.T) {
+	n, err := ParseQuantity("0x10")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(16), n)
+
+	_, err = ParseQuantity("0x")
+	assert.Error(t, err)
+
+	assert.Equal(t, "0x10", EncodeQuantity(16))
+}
+
+func TestIsAddress(t *testing.T) {
+	assert.True(t, IsAddress("0x00000000219ab540356cBB839Cbe05303d7705Fa"))
+	assert.False(t, IsAddress("00000000219ab540356cBB839Cbe05303d7705Fa"))
+	assert.False(t, IsAddress("0x00000000219ab540356cBB839Cbe05303d7705"))
+	assert.False(t, IsAddress("0x00000000219ab540356cBB839Cbe05303d7705Fz"))
+}
@@ -0,0 +1,134 @@
+package ethrpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	receiptCacheSize = 4096
+	receiptBatchSize = 100
+)
+
+// Receipts fetches transaction receipts, caching them and coalescing lookups made within
+// a short delay of each other into a single batch request. Transactions of the same block
+// are usually processed concurrently, so this turns one request per transaction into one per block.
+type Receipts struct {
+	client *Client
+	delay  time.Duration
+
+	mu      sync.Mutex
+	cache   map[string]*Receipt
+	order   []string
+	queue   []string
+	pending map[string][]chan receiptResult
+}
+
+type receiptResult struct {
+	receipt *Receipt
+	err     error
+}
+
+// NewReceipts returns a receipt fetcher that waits up to delay to batch concurrent lookups.
+func NewReceipts(client *Client, delay time.Duration) *Receipts {
+	return &Receipts{
+		client:  client,
+		delay:   delay,
+		cache:   make(map[string]*Receipt),
+		pending: make(map[string][]chan receiptResult),
+	}
+}
+
+// TransactionReceipt returns the receipt of a mined transaction, served from cache when possible.
+func (r *Receipts) TransactionReceipt(ctx context.Context, hash string) (*Receipt, error) {
+	hash = strings.ToLower(hash)
+
+	r.mu.Lock()
+	if receipt, ok := r.cache[hash]; ok {
+		r.mu.Unlock()
+		return receipt, nil
+	}
+
+	ch := make(chan receiptResult, 1)
+	waiters, inFlight := r.pending[hash]
+	r.pending[hash] = append(waiters, ch)
+	if !inFlight {
+		r.queue = append(r.queue, hash)
+		switch len(r.queue) {
+		case 1:
+			time.AfterFunc(r.delay, r.flush)
+		case receiptBatchSize:
+			go r.flush()
+		}
+	}
+	r.mu.Unlock()
+
+	select {
+	case res := <-ch:
+		return res.receipt, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// flush fetches every queued receipt in batches of at most receiptBatchSize.
+func (r *Receipts) flush() {
+	r.mu.Lock()
+	hashes := r.queue
+	r.queue = nil
+	r.mu.Unlock()
+
+	for len(hashes) > 0 {
+		n := min(len(hashes), receiptBatchSize)
+		r.fetch(hashes[:n])
+		hashes = hashes[n:]
+	}
+}
+
+// fetch loads one batch of receipts and hands the results to everyone waiting for them.
+func (r *Receipts) fetch(hashes []string) {
+	receipts := make([]*Receipt, len(hashes))
+	batch := make([]BatchElem, len(hashes))
+	for i, hash := range hashes {
+		batch[i] = BatchElem{Method: "eth_getTransactionReceipt", Params: []any{hash}, Result: &receipts[i]}
+	}
+	err := r.client.BatchCall(context.Background(), batch)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, hash := range hashes {
+		res := receiptResult{receipt: receipts[i], err: err}
+		if res.err == nil {
+			res.err = batch[i].Error
+		}
+		if res.err == nil && res.receipt == nil {
+			res.err = fmt.Errorf("receipt for %s not found", hash)
+		}
+		if res.err == nil {
+			r.store(hash, res.receipt)
+		}
+
+		for _, ch := range r.pending[hash] {
+			ch <- res
+		}
+		delete(r.pending, hash)
+	}
+}
+
+// store caches a receipt, evicting the oldest entry when the cache is full.
+// Must be called with r.mu held.
+func (r *Receipts) store(hash string, receipt *Receipt) {
+	if _, ok := r.cache[hash]; ok {
+		return
+	}
+	if len(r.order) >= receiptCacheSize {
+		delete(r.cache, r.order[0])
+		r.order = r.order[1:]
+	}
+	r.cache[hash] = receipt
+	r.order = append(r.order, hash)
+}
@@ -0,0 +1,47 @@
+package ethrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceipts_BatchesAndCaches(t *testing.T) {
+	srv, batches := newBatchTestServer(t, func(method string, params []json.RawMessage) (any, *Error) {
+		assert.Equal(t, "eth_getTransactionReceipt", method)
+		var hash string
+		require.NoError(t, json.Unmarshal(params[0], &hash))
+		if hash == "0xpending" {
+			return nil, nil
+		}
+		return map[string]any{"transactionHash": hash, "status": "0x0"}, nil
+	})
+	receipts := NewReceipts(NewClient(srv.URL, nil), 20*time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			receipt, err := receipts.TransactionReceipt(context.Background(), fmt.Sprintf("0x%d", i%3))
+			require.NoError(t, err)
+			assert.True(t, receipt.Failed())
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, []int{3}, batches.sizes())
+
+	receipt, err := receipts.TransactionReceipt(context.Background(), "0x1")
+	require.NoError(t, err)
+	assert.Equal(t, "0x1", receipt.TransactionHash)
+	assert.Equal(t, []int{3}, batches.sizes(), "cached receipt must not be fetched again")
+
+	_, err = receipts.TransactionReceipt(context.Background(), "0xpending")
+	assert.ErrorContains(t, err, "not found")
+}
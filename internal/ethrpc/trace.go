@@ -0,0 +1,193 @@
+package ethrpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// TraceMethod selects the tracing API used to discover internal value transfers.
+type TraceMethod string
+
+const (
+	// TraceTransaction uses the Parity/Erigon trace_transaction API.
+	TraceTransaction TraceMethod = "trace_transaction"
+	// DebugTraceTransaction uses the Geth debug_traceTransaction API with the built-in callTracer.
+	DebugTraceTransaction TraceMethod = "debug_traceTransaction"
+)
+
+// InternalTransfer is a value transfer made by a contract during a transaction.
+type InternalTransfer struct {
+	Type  string // call, create or selfdestruct
+	From  string
+	To    string
+	Value string // hex-encoded wei
+}
+
+// Tracer extracts internal value transfers from transaction traces.
+type Tracer struct {
+	client *Client
+	method TraceMethod
+}
+
+// NewTracer returns a tracer calling the given trace method on client.
+func NewTracer(client *Client, method TraceMethod) (*Tracer, error) {
+	switch method {
+	case TraceTransaction, DebugTraceTransaction:
+		return &Tracer{client: client, method: method}, nil
+	default:
+		return nil, fmt.Errorf("unsupported trace method '%s'", method)
+	}
+}
+
+// parityTrace is a single entry of a trace_transaction response.
+type parityTrace struct {
+	Type   string `json:"type"`
+	Action struct {
+		CallType      string `json:"callType"`
+		From          string `json:"from"`
+		To            string `json:"to"`
+		Value         string `json:"value"`
+		Address       string `json:"address"`
+		RefundAddress string `json:"refundAddress"`
+		Balance       string `json:"balance"`
+	} `json:"action"`
+	Result *struct {
+		Address string `json:"address"`
+	} `json:"result"`
+	TraceAddress []int  `json:"traceAddress"`
+	Error        string `json:"error"`
+}
+
+// callFrame is a node of the callTracer output of debug_traceTransaction.
+type callFrame struct {
+	Type  string      `json:"type"`
+	From  string      `json:"from"`
+	To    string      `json:"to"`
+	Value string      `json:"value"`
+	Error string      `json:"error"`
+	Calls []callFrame `json:"calls"`
+}
+
+// InternalTransfers returns the value transfers made by nested calls of the transaction.
+// The top-level transfer and calls that were reverted are not included.
+func (t *Tracer) InternalTransfers(ctx context.Context, hash string) ([]InternalTransfer, error) {
+	if t.method == DebugTraceTransaction {
+		var root callFrame
+		if err := t.client.Call(ctx, &root, string(t.method), hash, map[string]string{"tracer": "callTracer"}); err != nil {
+			return nil, err
+		}
+		if root.Error != "" {
+			return nil, nil
+		}
+		var transfers []InternalTransfer
+		for _, call := range root.Calls {
+			transfers = appendFrameTransfers(transfers, call)
+		}
+		return transfers, nil
+	}
+
+	var traces []parityTrace
+	if err := t.client.Call(ctx, &traces, string(t.method), hash); err != nil {
+		return nil, err
+	}
+	return parityTransfers(traces), nil
+}
+
+// appendFrameTransfers walks a call frame and its children, skipping reverted subtrees.
+func appendFrameTransfers(transfers []InternalTransfer, frame callFrame) []InternalTransfer {
+	if frame.Error != "" {
+		return transfers
+	}
+
+	var kind string
+	switch strings.ToUpper(frame.Type) {
+	case "CALL":
+		kind = "call"
+	case "CREATE", "CREATE2":
+		kind = "create"
+	case "SELFDESTRUCT":
+		kind = "selfdestruct"
+	}
+	if kind != "" && hasValue(frame.Value) {
+		transfers = append(transfers, InternalTransfer{
+			Type:  kind,
+			From:  strings.ToLower(frame.From),
+			To:    strings.ToLower(frame.To),
+			Value: frame.Value,
+		})
+	}
+
+	for _, call := range frame.Calls {
+		transfers = appendFrameTransfers(transfers, call)
+	}
+	return transfers
+}
+
+// parityTransfers converts flat trace_transaction entries, skipping the root and reverted subtrees.
+func parityTransfers(traces []parityTrace) []InternalTransfer {
+	var (
+		transfers []InternalTransfer
+		reverted  [][]int
+	)
+	for _, tr := range traces {
+		if tr.Error != "" {
+			reverted = append(reverted, tr.TraceAddress)
+			continue
+		}
+		if len(tr.TraceAddress) == 0 || underAny(tr.TraceAddress, reverted) {
+			continue
+		}
+
+		var transfer InternalTransfer
+		switch tr.Type {
+		case "call":
+			if tr.Action.CallType != "" && tr.Action.CallType != "call" {
+				continue
+			}
+			transfer = InternalTransfer{Type: "call", From: tr.Action.From, To: tr.Action.To, Value: tr.Action.Value}
+		case "create":
+			transfer = InternalTransfer{Type: "create", From: tr.Action.From, Value: tr.Action.Value}
+			if tr.Result != nil {
+				transfer.To = tr.Result.Address
+			}
+		case "suicide":
+			transfer = InternalTransfer{Type: "selfdestruct", From: tr.Action.Address, To: tr.Action.RefundAddress, Value: tr.Action.Balance}
+		default:
+			continue
+		}
+		if !hasValue(transfer.Value) {
+			continue
+		}
+		transfer.From = strings.ToLower(transfer.From)
+		transfer.To = strings.ToLower(transfer.To)
+		transfers = append(transfers, transfer)
+	}
+	return transfers
+}
+
+// underAny reports whether a trace address equals or descends from one of the prefixes.
+func underAny(address []int, prefixes [][]int) bool {
+	for _, prefix := range prefixes {
+		if len(prefix) > len(address) {
+			continue
+		}
+		match := true
+		for i := range prefix {
+			if prefix[i] != address[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// hasValue reports whether a hex quantity is present and non-zero.
+func hasValue(raw string) bool {
+	n, err := ParseBig(raw)
+	return err == nil && n.Sign() > 0
+}
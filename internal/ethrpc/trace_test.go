@@ -0,0 +1,67 @@
+package ethrpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTracer_UnsupportedMethod(t *testing.T) {
+	_, err := NewTracer(NewClient("http://localhost", nil), "ots_traceTransaction")
+	assert.ErrorContains(t, err, "unsupported trace method")
+}
+
+func TestTracer_TraceTransaction(t *testing.T) {
+	srv, _ := newTestServer(t, func(method string, params []json.RawMessage) (any, *Error) {
+		assert.Equal(t, "trace_transaction", method)
+		assert.JSONEq(t, `"0xhash"`, string(params[0]))
+		return json.RawMessage(`[
+			{"type": "call", "action": {"callType": "call", "from": "0xsigner", "to": "0xSAFE", "value": "0x0"}, "traceAddress": []},
+			{"type": "call", "action": {"callType": "call", "from": "0xSAFE", "to": "0xbob", "value": "0xde0b6b3a7640000"}, "traceAddress": [0]},
+			{"type": "call", "action": {"callType": "delegatecall", "from": "0xsafe", "to": "0xlib", "value": "0x1"}, "traceAddress": [1]},
+			{"type": "call", "action": {"callType": "call", "from": "0xsafe", "to": "0xcarol", "value": "0x1"}, "traceAddress": [2], "error": "Reverted"},
+			{"type": "call", "action": {"callType": "call", "from": "0xcarol", "to": "0xdave", "value": "0x1"}, "traceAddress": [2, 0]},
+			{"type": "suicide", "action": {"address": "0xold", "refundAddress": "0xsafe", "balance": "0x5"}, "traceAddress": [3]}
+		]`), nil
+	})
+
+	tracer, err := NewTracer(NewClient(srv.URL, nil), TraceTransaction)
+	require.NoError(t, err)
+
+	transfers, err := tracer.InternalTransfers(context.Background(), "0xhash")
+	require.NoError(t, err)
+	assert.Equal(t, []InternalTransfer{
+		{Type: "call", From: "0xsafe", To: "0xbob", Value: "0xde0b6b3a7640000"},
+		{Type: "selfdestruct", From: "0xold", To: "0xsafe", Value: "0x5"},
+	}, transfers)
+}
+
+func TestTracer_DebugTraceTransaction(t *testing.T) {
+	srv, _ := newTestServer(t, func(method string, params []json.RawMessage) (any, *Error) {
+		assert.Equal(t, "debug_traceTransaction", method)
+		assert.JSONEq(t, `{"tracer": "callTracer"}`, string(params[1]))
+		return json.RawMessage(`{
+			"type": "CALL", "from": "0xsigner", "to": "0xsafe", "value": "0x0",
+			"calls": [
+				{"type": "CALL", "from": "0xsafe", "to": "0xBOB", "value": "0x2", "calls": [
+					{"type": "CREATE2", "from": "0xbob", "to": "0xnew", "value": "0x1"}
+				]},
+				{"type": "STATICCALL", "from": "0xsafe", "to": "0xoracle"},
+				{"type": "CALL", "from": "0xsafe", "to": "0xcarol", "value": "0x3", "error": "execution reverted"}
+			]
+		}`), nil
+	})
+
+	tracer, err := NewTracer(NewClient(srv.URL, nil), DebugTraceTransaction)
+	require.NoError(t, err)
+
+	transfers, err := tracer.InternalTransfers(context.Background(), "0xhash")
+	require.NoError(t, err)
+	assert.Equal(t, []InternalTransfer{
+		{Type: "call", From: "0xsafe", To: "0xbob", Value: "0x2"},
+		{Type: "create", From: "0xbob", To: "0xnew", Value: "0x1"},
+	}, transfers)
+}
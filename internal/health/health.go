@@ -0,0 +1,41 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Check is a named probe that returns an error when its component is unhealthy.
+type Check struct {
+	Name string
+	Run  func() error
+}
+
+// response is the JSON body of a probe, listing the result of every check.
+type response struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Handler runs every check on each request. It responds 200 when all of them pass and
+// 503 Service Unavailable otherwise, with the result of each check in the body.
+func Handler(checks ...Check) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		res := response{Status: "ok", Checks: make(map[string]string, len(checks))}
+		code := http.StatusOK
+		for _, c := range checks {
+			if err := c.Run(); err != nil {
+				res.Checks[c.Name] = err.Error()
+				res.Status = "unavailable"
+				code = http.StatusServiceUnavailable
+				continue
+			}
+			res.Checks[c.Name] = "ok"
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Header().Set("Cache-Control", "no-store")
+		rw.WriteHeader(code)
+		_ = json.NewEncoder(rw).Encode(res)
+	})
+}
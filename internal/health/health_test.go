@@ -0,0 +1,54 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_AllChecksPass(t *testing.T) {
+	h := Handler(
+		Check{Name: "watcher", Run: func() error { return nil }},
+		Check{Name: "notifier", Run: func() error { return nil }},
+	)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var res response
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	assert.Equal(t, response{Status: "ok", Checks: map[string]string{"watcher": "ok", "notifier": "ok"}}, res)
+}
+
+func TestHandler_FailingCheck(t *testing.T) {
+	h := Handler(
+		Check{Name: "subscription", Run: func() error { return nil }},
+		Check{Name: "events", Run: func() error { return errors.New("no event for 10m0s") }},
+	)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	var res response
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	assert.Equal(t, "unavailable", res.Status)
+	assert.Equal(t, "ok", res.Checks["subscription"])
+	assert.Equal(t, "no event for 10m0s", res.Checks["events"])
+}
+
+func TestHandler_NoChecks(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
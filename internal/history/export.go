@@ -0,0 +1,93 @@
+package history
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export formats.
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// csvHeader names the columns written by WriteCSV.
+var csvHeader = []string{
+	"id", "fired_at", "type", "rule", "severity", "title", "wallet", "label", "direction",
+	"amount_eth", "amount_usd", "tx_hash", "tx_hashes", "deliveries",
+}
+
+// Write exports entries in the given format, json or csv.
+func Write(w io.Writer, format string, entries []Entry) error {
+	switch format {
+	case FormatJSON:
+		return WriteJSON(w, entries)
+	case FormatCSV:
+		return WriteCSV(w, entries)
+	default:
+		return fmt.Errorf("unknown format %q, use json or csv", format)
+	}
+}
+
+// WriteJSON exports entries as a JSON array.
+func WriteJSON(w io.Writer, entries []Entry) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
+
+// WriteCSV exports entries as CSV with a header row. Transaction hashes are separated by
+// spaces and deliveries by semicolons, each as notifier:status.
+func WriteCSV(w io.Writer, entries []Entry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		deliveries := make([]string, 0, len(e.Deliveries))
+		for _, d := range e.Deliveries {
+			deliveries = append(deliveries, d.Notifier+":"+d.Status)
+		}
+		err := cw.Write([]string{
+			e.ID,
+			e.FiredAt.UTC().Format(time.RFC3339),
+			e.Type,
+			e.Rule,
+			e.Severity,
+			e.Title,
+			e.Wallet,
+			e.Label,
+			e.Direction,
+			strconv.FormatFloat(e.AmountETH, 'f', -1, 64),
+			strconv.FormatFloat(e.AmountUSD, 'f', -1, 64),
+			e.TxHash,
+			strings.Join(e.TxHashes, " "),
+			strings.Join(deliveries, ";"),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ParseTime reads a query bound: an RFC 3339 time, a date such as 2025-01-31, or a
+// duration such as 24h counted back from now.
+func ParseTime(raw string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, raw); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, use RFC 3339, a date such as 2025-01-31 or a duration such as 24h", raw)
+}
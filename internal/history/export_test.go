@@ -0,0 +1,68 @@
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var exported = []Entry{{
+	ID:         "a1",
+	FiredAt:    time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC),
+	Type:       "threshold",
+	Severity:   "critical",
+	Title:      "High Volume Detected",
+	Wallet:     "0xabc",
+	Label:      "Treasury, hot",
+	Direction:  "from",
+	AmountETH:  12.5,
+	AmountUSD:  40000,
+	TxHash:     "0x2",
+	TxHashes:   []string{"0x1", "0x2"},
+	Deliveries: []Delivery{{Notifier: "telegram", Status: StatusSent}, {Notifier: "telegram_escalation", Status: StatusFailed}},
+}}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatCSV, exported))
+	assert.Equal(t,
+		"id,fired_at,type,rule,severity,title,wallet,label,direction,amount_eth,amount_usd,tx_hash,tx_hashes,deliveries\n"+
+			`a1,2025-01-31T12:00:00Z,threshold,,critical,High Volume Detected,0xabc,"Treasury, hot",from,12.5,40000,0x2,0x1 0x2,telegram:sent;telegram_escalation:failed`+"\n",
+		buf.String())
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatJSON, exported))
+
+	var decoded []Entry
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, exported, decoded)
+
+	assert.ErrorContains(t, Write(&buf, "xml", exported), `unknown format "xml"`)
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	got, err := ParseTime("2025-01-30T08:00:00Z", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 1, 30, 8, 0, 0, 0, time.UTC), got)
+
+	got, err = ParseTime("2025-01-30", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 1, 30, 0, 0, 0, 0, time.UTC), got)
+
+	got, err = ParseTime("24h", now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-24*time.Hour), got)
+
+	_, err = ParseTime("yesterday", now)
+	assert.Error(t, err)
+	_, err = ParseTime("-1h", now)
+	assert.Error(t, err)
+}
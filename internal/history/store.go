@@ -0,0 +1,395 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/yermakovsa/eth-watcher/internal/logging"
+	"github.com/yermakovsa/eth-watcher/internal/notifier"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Delivery statuses of an alert sent through a notifier, or held back before reaching one.
+const (
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+	StatusMuted   = notifier.HeldMuted
+	StatusSnoozed = notifier.HeldSnoozed
+)
+
+// heldBy names the notifier of the deliveries recorded for alerts held back.
+const heldBy = "muter"
+
+// ErrLocked is returned when another process, usually the running watcher, holds the history file.
+var ErrLocked = errors.New("history file is locked by another process")
+
+var (
+	// alertsBucket holds entries keyed by the time they fired followed by their ID, so
+	// they are stored in order
+	alertsBucket = []byte("alerts")
+	// idsBucket maps alert IDs to their key in alertsBucket
+	idsBucket = []byte("ids")
+)
+
+// openTimeout bounds how long Open waits for the lock on the history file.
+const openTimeout = time.Second
+
+// Option configures optional Store behaviour.
+type Option func(*Store)
+
+// WithLogger sets the logger used instead of the default one.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Store) {
+		s.logger = logger
+	}
+}
+
+// WithRetention deletes alerts that fired more than maxAge ago, when the file is opened and
+// as new alerts are stored. Zero keeps every alert.
+func WithRetention(maxAge time.Duration) Option {
+	return func(s *Store) {
+		s.retention = maxAge
+	}
+}
+
+// ReadOnly opens the history file for queries only, so that it can be shared with other readers.
+func ReadOnly() Option {
+	return func(s *Store) {
+		s.readOnly = true
+	}
+}
+
+// Entry is a stored alert and how it was delivered.
+type Entry struct {
+	ID         string     `json:"id"`
+	FiredAt    time.Time  `json:"fired_at"`
+	Type       string     `json:"type"`
+	Rule       string     `json:"rule,omitempty"`
+	Severity   string     `json:"severity"`
+	Title      string     `json:"title"`
+	Wallet     string     `json:"wallet,omitempty"`
+	Label      string     `json:"label,omitempty"`
+	Direction  string     `json:"direction,omitempty"`
+	AmountETH  float64    `json:"amount_eth,omitempty"`
+	AmountUSD  float64    `json:"amount_usd,omitempty"`
+	TxHash     string     `json:"tx_hash,omitempty"`
+	TxHashes   []string   `json:"tx_hashes,omitempty"`
+	Fields     []Field    `json:"fields,omitempty"`
+	Deliveries []Delivery `json:"deliveries"`
+}
+
+// Field is one named value of an alert.
+type Field struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Delivery is the outcome of sending an alert through one notifier.
+type Delivery struct {
+	Notifier string    `json:"notifier"`
+	Status   string    `json:"status"`
+	Error    string    `json:"error,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+// Query filters stored alerts. Zero fields match every alert.
+type Query struct {
+	Wallet   string
+	Severity notifier.Severity
+	// Since and Until bound the time alerts fired, inclusive
+	Since time.Time
+	Until time.Time
+	// Limit caps the number of alerts returned, newest first
+	Limit int
+}
+
+// matches reports whether the entry passes the wallet and severity filters.
+func (q Query) matches(e Entry) bool {
+	if q.Wallet != "" && !strings.EqualFold(q.Wallet, e.Wallet) {
+		return false
+	}
+	return q.Severity == "" || string(q.Severity) == e.Severity
+}
+
+// Store persists every alert and its deliveries in a bbolt file. It is safe for concurrent use.
+type Store struct {
+	db        *bolt.DB
+	readOnly  bool
+	retention time.Duration
+	logger    *slog.Logger
+	now       func() time.Time
+}
+
+// Open opens the history file at path, creating it unless opened read-only.
+func Open(path string, opts ...Option) (*Store, error) {
+	s := &Store{now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.logger = logging.Component(s.logger, "history")
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: openTimeout, ReadOnly: s.readOnly})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("%w: %s", ErrLocked, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.db = db
+
+	if !s.readOnly {
+		err = db.Update(func(tx *bolt.Tx) error {
+			for _, name := range [][]byte{alertsBucket, idsBucket} {
+				if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+					return err
+				}
+			}
+			return s.prune(tx)
+		})
+		if err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Close releases the history file.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Add stores an alert fired at the given time. Alerts without a severity are stored as warnings.
+func (s *Store) Add(alert notifier.Alert, firedAt time.Time) error {
+	e := Entry{
+		ID:         alert.ID,
+		FiredAt:    firedAt,
+		Type:       string(alert.Type),
+		Rule:       alert.Rule,
+		Severity:   string(alert.Severity),
+		Title:      alert.Title,
+		Wallet:     alert.Wallet,
+		Label:      alert.Label,
+		Direction:  alert.Direction,
+		AmountETH:  alert.Amount,
+		AmountUSD:  alert.AmountUSD,
+		TxHash:     alert.TxID,
+		TxHashes:   alert.TxHashes,
+		Deliveries: []Delivery{},
+	}
+	if e.Severity == "" {
+		e.Severity = string(notifier.SeverityWarning)
+	}
+	for _, f := range alert.Fields {
+		e.Fields = append(e.Fields, Field{Name: f.Name, Value: f.Value})
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		key := entryKey(firedAt, e.ID)
+		if err := put(tx.Bucket(alertsBucket), key, e); err != nil {
+			return err
+		}
+		if err := tx.Bucket(idsBucket).Put([]byte(e.ID), key); err != nil {
+			return err
+		}
+		return s.prune(tx)
+	})
+}
+
+// prune deletes the alerts older than the retention from both buckets.
+func (s *Store) prune(tx *bolt.Tx) error {
+	if s.retention <= 0 {
+		return nil
+	}
+	cutoff := timeKey(s.now().Add(-s.retention))
+
+	// Deleting while iterating makes the cursor skip keys, so collect them first
+	var expired [][]byte
+	c := tx.Bucket(alertsBucket).Cursor()
+	for k, _ := c.First(); k != nil && bytes.Compare(k, cutoff) < 0; k, _ = c.Next() {
+		expired = append(expired, bytes.Clone(k))
+	}
+	for _, key := range expired {
+		if err := tx.Bucket(idsBucket).Delete(key[len(cutoff):]); err != nil {
+			return err
+		}
+		if err := tx.Bucket(alertsBucket).Delete(key); err != nil {
+			return err
+		}
+	}
+	if len(expired) > 0 {
+		s.logger.Debug("Deleted expired alerts", "count", len(expired), "retention", s.retention)
+	}
+	return nil
+}
+
+// AddDelivery records the outcome of sending the alert with the given ID through a notifier.
+func (s *Store) AddDelivery(id string, d Delivery) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		key := tx.Bucket(idsBucket).Get([]byte(id))
+		if key == nil {
+			return fmt.Errorf("unknown alert %q", id)
+		}
+		alerts := tx.Bucket(alertsBucket)
+		e, err := get(alerts, key)
+		if err != nil {
+			return err
+		}
+		e.Deliveries = append(e.Deliveries, d)
+		return put(alerts, key, e)
+	})
+}
+
+// Get returns the stored alert with the given ID, reporting false when there is none.
+func (s *Store) Get(id string) (Entry, bool, error) {
+	var (
+		e  Entry
+		ok bool
+	)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		ids := tx.Bucket(idsBucket)
+		if ids == nil {
+			return nil
+		}
+		key := ids.Get([]byte(id))
+		if key == nil {
+			return nil
+		}
+		var err error
+		e, err = get(tx.Bucket(alertsBucket), key)
+		ok = err == nil
+		return err
+	})
+	return e, ok, err
+}
+
+// Find returns the stored alerts matching the query, newest first.
+func (s *Store) Find(q Query) ([]Entry, error) {
+	entries := []Entry{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		alerts := tx.Bucket(alertsBucket)
+		if alerts == nil {
+			return nil
+		}
+
+		c := alerts.Cursor()
+		var k, v []byte
+		if q.Until.IsZero() {
+			k, v = c.Last()
+		} else if k, _ = c.Seek(timeKey(q.Until.Add(time.Nanosecond))); k == nil {
+			k, v = c.Last()
+		} else {
+			k, v = c.Prev()
+		}
+
+		since := timeKey(q.Since)
+		for ; k != nil; k, v = c.Prev() {
+			if !q.Since.IsZero() && bytes.Compare(k, since) < 0 {
+				break
+			}
+			var e Entry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return fmt.Errorf("decode alert %x: %w", k, err)
+			}
+			if !q.matches(e) {
+				continue
+			}
+			entries = append(entries, e)
+			if q.Limit > 0 && len(entries) == q.Limit {
+				break
+			}
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// Record stores every alert before forwarding it to next, assigning an ID to alerts
+// without one. A nil store forwards alerts without storing them.
+func (s *Store) Record(next notifier.Notifier) notifier.Notifier {
+	if s == nil {
+		return next
+	}
+	return notifierFunc(func(ctx context.Context, alert notifier.Alert) error {
+		if alert.ID == "" {
+			alert.ID = notifier.NewID()
+		}
+		if err := s.Add(alert, s.now()); err != nil {
+			s.logger.Error("Failed to store alert", "alert_id", alert.ID, "type", alert.Type, "wallet", alert.Wallet, "error", err)
+		}
+		return next.Notify(ctx, alert)
+	})
+}
+
+// RecordDeliveries stores the outcome of every alert sent through next as a delivery
+// named backend. A nil store forwards alerts without storing anything.
+func (s *Store) RecordDeliveries(backend string, next notifier.Notifier) notifier.Notifier {
+	if s == nil {
+		return next
+	}
+	return notifierFunc(func(ctx context.Context, alert notifier.Alert) error {
+		err := next.Notify(ctx, alert)
+		d := Delivery{Notifier: backend, Status: StatusSent, At: s.now()}
+		if err != nil {
+			d.Status = StatusFailed
+			d.Error = err.Error()
+		}
+		if err := s.AddDelivery(alert.ID, d); err != nil {
+			s.logger.Error("Failed to store alert delivery", "alert_id", alert.ID, "backend", backend, "error", err)
+		}
+		return err
+	})
+}
+
+// RecordHeld stores that an alert was held back by a mute or snooze, with the reason as
+// its delivery status. It suits notifier.WithHeld. A nil store records nothing.
+func (s *Store) RecordHeld(ctx context.Context, alert notifier.Alert, reason string) {
+	if s == nil {
+		return
+	}
+	if err := s.AddDelivery(alert.ID, Delivery{Notifier: heldBy, Status: reason, At: s.now()}); err != nil {
+		s.logger.Error("Failed to store alert delivery", "alert_id", alert.ID, "backend", heldBy, "error", err)
+	}
+}
+
+// notifierFunc adapts a function to the notifier.Notifier interface.
+type notifierFunc func(ctx context.Context, alert notifier.Alert) error
+
+func (f notifierFunc) Notify(ctx context.Context, alert notifier.Alert) error {
+	return f(ctx, alert)
+}
+
+// timeKey encodes t so that keys sort in time order.
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return key
+}
+
+// entryKey is the key of an alert in alertsBucket.
+func entryKey(firedAt time.Time, id string) []byte {
+	return append(timeKey(firedAt), id...)
+}
+
+func get(b *bolt.Bucket, key []byte) (Entry, error) {
+	var e Entry
+	if err := json.Unmarshal(b.Get(key), &e); err != nil {
+		return Entry{}, fmt.Errorf("decode alert %x: %w", key, err)
+	}
+	return e, nil
+}
+
+func put(b *bolt.Bucket, key []byte, e Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return b.Put(key, data)
+}
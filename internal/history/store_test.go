@@ -0,0 +1,200 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yermakovsa/eth-watcher/internal/notifier"
+)
+
+func openStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "history.db")
+	s, err := Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+	return s, path
+}
+
+func TestStore_AddAndGet(t *testing.T) {
+	s, _ := openStore(t)
+	firedAt := time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, s.Add(notifier.Alert{
+		ID:       "a1",
+		Type:     notifier.AlertThreshold,
+		Title:    "High Volume Detected",
+		Wallet:   "0xabc",
+		Amount:   12.5,
+		TxID:     "0x2",
+		TxHashes: []string{"0x1", "0x2"},
+		Fields:   []notifier.Field{{Name: "Amount", Value: "12.5000 ETH"}},
+	}, firedAt))
+	require.NoError(t, s.AddDelivery("a1", Delivery{Notifier: "telegram", Status: StatusSent, At: firedAt}))
+
+	e, ok, err := s.Get("a1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, Entry{
+		ID:         "a1",
+		FiredAt:    firedAt,
+		Type:       "threshold",
+		Severity:   "warning",
+		Title:      "High Volume Detected",
+		Wallet:     "0xabc",
+		AmountETH:  12.5,
+		TxHash:     "0x2",
+		TxHashes:   []string{"0x1", "0x2"},
+		Fields:     []Field{{Name: "Amount", Value: "12.5000 ETH"}},
+		Deliveries: []Delivery{{Notifier: "telegram", Status: StatusSent, At: firedAt}},
+	}, e)
+
+	_, ok, err = s.Get("missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.ErrorContains(t, s.AddDelivery("missing", Delivery{}), `unknown alert "missing"`)
+}
+
+func TestStore_Find(t *testing.T) {
+	s, _ := openStore(t)
+	start := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+	add := func(id, wallet string, severity notifier.Severity, hours int) {
+		require.NoError(t, s.Add(notifier.Alert{ID: id, Wallet: wallet, Severity: severity}, start.Add(time.Duration(hours)*time.Hour)))
+	}
+	add("a1", "0xabc", notifier.SeverityInfo, 0)
+	add("a2", "0xdef", notifier.SeverityCritical, 1)
+	add("a3", "0xabc", "", 2)
+	add("a4", "0xabc", notifier.SeverityCritical, 3)
+
+	ids := func(q Query) []string {
+		entries, err := s.Find(q)
+		require.NoError(t, err)
+		res := []string{}
+		for _, e := range entries {
+			res = append(res, e.ID)
+		}
+		return res
+	}
+	assert.Equal(t, []string{"a4", "a3", "a2", "a1"}, ids(Query{}))
+	assert.Equal(t, []string{"a4", "a3", "a1"}, ids(Query{Wallet: "0xABC"}))
+	assert.Equal(t, []string{"a4", "a2"}, ids(Query{Severity: notifier.SeverityCritical}))
+	assert.Equal(t, []string{"a3"}, ids(Query{Severity: notifier.SeverityWarning}), "alerts without a severity are warnings")
+	assert.Equal(t, []string{"a3", "a2"}, ids(Query{Since: start.Add(time.Hour), Until: start.Add(2 * time.Hour)}))
+	assert.Equal(t, []string{"a4", "a3"}, ids(Query{Since: start.Add(90 * time.Minute)}))
+	assert.Equal(t, []string{"a1"}, ids(Query{Until: start.Add(30 * time.Minute)}))
+	assert.Equal(t, []string{"a4", "a3"}, ids(Query{Limit: 2}))
+	assert.Empty(t, ids(Query{Wallet: "0x123"}))
+}
+
+func TestStore_RecordsAlertsAndDeliveries(t *testing.T) {
+	s, path := openStore(t)
+	failing := s.RecordDeliveries("telegram_escalation", notifierFunc(func(ctx context.Context, alert notifier.Alert) error {
+		return errors.New("chat not found")
+	}))
+	var delivered notifier.Alert
+	n := s.Record(s.RecordDeliveries("telegram", notifierFunc(func(ctx context.Context, alert notifier.Alert) error {
+		delivered = alert
+		return failing.Notify(ctx, alert)
+	})))
+
+	err := n.Notify(context.Background(), notifier.Alert{Type: notifier.AlertLowBalance, Wallet: "0xabc"})
+	require.EqualError(t, err, "chat not found")
+	require.NotEmpty(t, delivered.ID, "alerts get an ID before they are delivered")
+
+	e, ok, err := s.Get(delivered.ID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Len(t, e.Deliveries, 2)
+	assert.Equal(t, "telegram_escalation", e.Deliveries[0].Notifier)
+	assert.Equal(t, StatusFailed, e.Deliveries[0].Status)
+	assert.Equal(t, "chat not found", e.Deliveries[0].Error)
+	assert.Equal(t, "telegram", e.Deliveries[1].Notifier)
+	assert.Equal(t, StatusFailed, e.Deliveries[1].Status)
+
+	require.NoError(t, s.Close())
+	ro, err := Open(path, ReadOnly())
+	require.NoError(t, err)
+	defer ro.Close()
+	entries, err := ro.Find(Query{})
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "alerts outlive the process that stored them")
+}
+
+func TestStore_RecordsHeldAlerts(t *testing.T) {
+	s, _ := openStore(t)
+	n := s.Record(notifierFunc(func(ctx context.Context, alert notifier.Alert) error {
+		s.RecordHeld(ctx, alert, notifier.HeldMuted)
+		return nil
+	}))
+	require.NoError(t, n.Notify(context.Background(), notifier.Alert{ID: "a1", Wallet: "0xabc"}))
+
+	e, ok, err := s.Get("a1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Len(t, e.Deliveries, 1)
+	assert.Equal(t, "muter", e.Deliveries[0].Notifier)
+	assert.Equal(t, StatusMuted, e.Deliveries[0].Status)
+}
+
+func TestStore_NilForwardsAlerts(t *testing.T) {
+	var s *Store
+	calls := 0
+	next := notifierFunc(func(ctx context.Context, alert notifier.Alert) error {
+		calls++
+		return nil
+	})
+	require.NoError(t, s.Record(next).Notify(context.Background(), notifier.Alert{}))
+	require.NoError(t, s.RecordDeliveries("telegram", next).Notify(context.Background(), notifier.Alert{}))
+	assert.Equal(t, 2, calls)
+	s.RecordHeld(context.Background(), notifier.Alert{}, StatusMuted)
+}
+
+func TestStore_Retention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	now := time.Now()
+	s, err := Open(path)
+	require.NoError(t, err)
+	require.NoError(t, s.Add(notifier.Alert{ID: "old"}, now.Add(-48*time.Hour)))
+	require.NoError(t, s.Add(notifier.Alert{ID: "recent"}, now.Add(-time.Hour)))
+	require.NoError(t, s.Close())
+
+	// Opening the file deletes the alerts already expired
+	s, err = Open(path, WithRetention(24*time.Hour))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+	_, ok, err := s.Get("old")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, []string{"recent"}, ids(t, s))
+
+	// Storing an alert deletes those expired since
+	s.now = func() time.Time { return now.Add(24 * time.Hour) }
+	require.NoError(t, s.Add(notifier.Alert{ID: "new"}, now.Add(24*time.Hour)))
+	_, ok, err = s.Get("recent")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, []string{"new"}, ids(t, s))
+}
+
+// ids returns the IDs of every stored alert, newest first.
+func ids(t *testing.T, s *Store) []string {
+	t.Helper()
+	entries, err := s.Find(Query{})
+	require.NoError(t, err)
+	var ids []string
+	for _, e := range entries {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func TestOpen_Locked(t *testing.T) {
+	_, path := openStore(t)
+	_, err := Open(path, ReadOnly())
+	assert.ErrorIs(t, err, ErrLocked)
+}
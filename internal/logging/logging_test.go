@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "json", "info")
+	require.NoError(t, err)
+
+	Component(logger, "watcher").Info("Resubscribed", "block", 42)
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "INFO", record["level"])
+	assert.Equal(t, "Resubscribed", record["msg"])
+	assert.Equal(t, "watcher", record["component"])
+	assert.Equal(t, float64(42), record["block"])
+}
+
+func TestNew_TextFiltersByLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "text", "warn")
+	require.NoError(t, err)
+
+	logger.Info("dropped")
+	logger.Warn("kept", "wallet", "0xabc")
+
+	assert.NotContains(t, buf.String(), "dropped")
+	assert.Contains(t, buf.String(), "level=WARN msg=kept wallet=0xabc")
+}
+
+func TestNew_Invalid(t *testing.T) {
+	_, err := New(&bytes.Buffer{}, "xml", "info")
+	assert.ErrorContains(t, err, "invalid log format")
+
+	_, err = New(&bytes.Buffer{}, "text", "verbose")
+	assert.ErrorContains(t, err, "invalid log level")
+}
+
+func TestComponent_DefaultsToGlobalLogger(t *testing.T) {
+	assert.NotNil(t, Component(nil, "aggregator"))
+}
@@ -0,0 +1,124 @@
+package logwatcher
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/yermakovsa/eth-watcher/internal/ethrpc"
+	"github.com/yermakovsa/eth-watcher/internal/logging"
+)
+
+// maxBlockRange bounds a single eth_getLogs request after downtime.
+const maxBlockRange = 1000
+
+type LogClient interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+	GetLogs(ctx context.Context, filter ethrpc.LogFilter) ([]ethrpc.Log, error)
+}
+
+type Aggregator interface {
+	ProcessLog(event Event)
+}
+
+// LogWatcher follows the chain head and feeds decoded contract events to the aggregator.
+// It runs alongside the transaction watcher.
+type LogWatcher struct {
+	client     LogClient
+	aggregator Aggregator
+	subs       []Subscription
+	interval   time.Duration
+	next       uint64
+	logger     *slog.Logger
+	ctx        context.Context
+	cancel     context.CancelFunc
+}
+
+// NewLogWatcher initializes a log watcher polling for new logs every interval.
+// A nil logger uses the default one.
+func NewLogWatcher(ctx context.Context, client LogClient, subs []Subscription, aggregator Aggregator, interval time.Duration, logger *slog.Logger) *LogWatcher {
+	w := &LogWatcher{
+		client:     client,
+		aggregator: aggregator,
+		subs:       subs,
+		interval:   interval,
+		logger:     logging.Component(logger, "logwatcher"),
+	}
+	w.ctx, w.cancel = context.WithCancel(ctx)
+	return w
+}
+
+// Start begins watching for logs mined after the current head.
+func (w *LogWatcher) Start() error {
+	head, err := w.client.BlockNumber(w.ctx)
+	if err != nil {
+		return err
+	}
+	w.next = head + 1
+
+	w.logger.Info("Started log watcher", "events", len(w.subs), "block", w.next)
+
+	go w.watch()
+
+	return nil
+}
+
+// Stop halts the log watcher.
+func (w *LogWatcher) Stop() {
+	w.logger.Info("Stopping log watcher")
+	w.cancel()
+}
+
+func (w *LogWatcher) watch() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			w.logger.Info("Shutdown signal received")
+			return
+		case <-ticker.C:
+			w.poll()
+		}
+	}
+}
+
+// poll fetches logs for every subscription from the next unseen block up to the head.
+// On failure the range is retried on the next tick.
+func (w *LogWatcher) poll() {
+	head, err := w.client.BlockNumber(w.ctx)
+	if err != nil {
+		w.logger.Error("Failed to fetch block number", "error", err)
+		return
+	}
+	if head < w.next {
+		return
+	}
+	to := min(head, w.next+maxBlockRange-1)
+
+	var events []Event
+	for _, sub := range w.subs {
+		logs, err := w.client.GetLogs(w.ctx, sub.filter(w.next, to))
+		if err != nil {
+			w.logger.Error("Failed to fetch logs", "event", sub.spec.Name, "from_block", w.next, "to_block", to, "error", err)
+			return
+		}
+		for _, l := range logs {
+			if l.Removed {
+				continue
+			}
+			ev, err := sub.decode(l)
+			if err != nil {
+				w.logger.Warn("Failed to decode log", "event", sub.spec.Name, "tx_hash", l.TransactionHash, "block", l.BlockNumber, "error", err)
+				continue
+			}
+			events = append(events, ev)
+		}
+	}
+
+	for _, ev := range events {
+		w.aggregator.ProcessLog(ev)
+	}
+	w.next = to + 1
+}
@@ -0,0 +1,130 @@
+package logwatcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yermakovsa/eth-watcher/internal/ethrpc"
+)
+
+const (
+	erc20ABI      = `[{"type": "event", "name": "Transfer", "inputs": [{"name": "from", "type": "address", "indexed": true}, {"name": "to", "type": "address", "indexed": true}, {"name": "value", "type": "uint256"}]}]`
+	transferTopic = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+)
+
+func word(hexStr string) string {
+	return "0x" + strings.Repeat("0", 64-len(hexStr)) + hexStr
+}
+
+type MockLogClient struct {
+	mu      sync.Mutex
+	head    uint64
+	logs    []ethrpc.Log
+	filters []ethrpc.LogFilter
+}
+
+func (m *MockLogClient) BlockNumber(ctx context.Context) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.head, nil
+}
+
+func (m *MockLogClient) GetLogs(ctx context.Context, filter ethrpc.LogFilter) ([]ethrpc.Log, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.filters = append(m.filters, filter)
+	return m.logs, nil
+}
+
+type MockAggregator struct {
+	events chan Event
+}
+
+func (m *MockAggregator) ProcessLog(event Event) {
+	m.events <- event
+}
+
+func loadSubs(t *testing.T, specs string) ([]Subscription, error) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "erc20.json"), []byte(erc20ABI), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "logs.json"), []byte(specs), 0o600))
+	return Load(filepath.Join(dir, "logs.json"))
+}
+
+func TestLoad_BuildsTopicFilters(t *testing.T) {
+	subs, err := loadSubs(t, `[{
+		"name": "usdc-to-bridge", "address": "0xTOKEN", "abi": "erc20.json", "event": "Transfer",
+		"filter": {"to": ["0x00000000000000000000000000000000000000bb"]}
+	}]`)
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+
+	f := subs[0].filter(1, 2)
+	assert.Equal(t, []string{"0xtoken"}, f.Addresses)
+	assert.Equal(t, [][]string{{transferTopic}, nil, {word("bb")}}, f.Topics)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	_, err := loadSubs(t, `[{"name": "x", "address": "0xtoken", "abi": "erc20.json", "event": "Approval"}]`)
+	assert.ErrorContains(t, err, "not found in ABI")
+
+	_, err = loadSubs(t, `[{"name": "x", "address": "0xtoken", "abi": "erc20.json", "event": "Transfer", "filter": {"value": ["1"]}}]`)
+	assert.ErrorContains(t, err, "indexed arguments")
+
+	_, err = loadSubs(t, `[{"name": "x", "address": "0xtoken", "abi": "erc20.json", "event": "Transfer", "amount_arg": "value"}]`)
+	assert.ErrorContains(t, err, "must be set together")
+}
+
+func TestLogWatcher_PollsAndDecodesLogs(t *testing.T) {
+	subs, err := loadSubs(t, `[{
+		"name": "usdc", "address": "0xtoken", "abi": "erc20.json", "event": "Transfer",
+		"wallet_arg": "from", "amount_arg": "value", "decimals": 6, "symbol": "USDC", "threshold": 1000000
+	}]`)
+	require.NoError(t, err)
+
+	client := &MockLogClient{head: 100}
+	agg := &MockAggregator{events: make(chan Event, 1)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w := NewLogWatcher(ctx, client, subs, agg, 10*time.Millisecond, nil)
+	require.NoError(t, w.Start())
+
+	client.mu.Lock()
+	client.head = 105
+	client.logs = []ethrpc.Log{{
+		Address:         "0xTOKEN",
+		Topics:          []string{transferTopic, word("aa"), word("bb")},
+		Data:            word("3b9aca00"), // 1000 USDC
+		BlockNumber:     "0x65",
+		TransactionHash: "0xhash",
+	}}
+	client.mu.Unlock()
+
+	select {
+	case ev := <-agg.events:
+		assert.True(t, ev.Aggregated)
+		assert.Equal(t, "0x00000000000000000000000000000000000000aa", ev.Wallet)
+		assert.InDelta(t, 1000.0, ev.Amount, 1e-9)
+		assert.Equal(t, "USDC", ev.Symbol)
+		assert.Equal(t, 1000000.0, ev.Threshold)
+		assert.Equal(t, "Transfer(from=0x00000000000000000000000000000000000000aa, to=0x00000000000000000000000000000000000000bb, value=1000000000)", ev.String())
+	case <-time.After(1 * time.Second):
+		t.Fatal("expected decoded event not received")
+	}
+
+	w.Stop()
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	assert.Equal(t, uint64(101), client.filters[0].FromBlock)
+	assert.Equal(t, uint64(105), client.filters[0].ToBlock)
+}
@@ -0,0 +1,243 @@
+package logwatcher
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yermakovsa/eth-watcher/internal/abi"
+	"github.com/yermakovsa/eth-watcher/internal/ethrpc"
+)
+
+const defaultDecimals = 18
+
+// Spec configures the logs of one contract event to watch.
+type Spec struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	Label   string `json:"label,omitempty"`
+	// ABI is the path of the contract's JSON ABI, relative to the logs file.
+	ABI   string `json:"abi"`
+	Event string `json:"event"`
+	// Filter restricts indexed arguments to any of the given values, keyed by argument name.
+	Filter map[string][]string `json:"filter,omitempty"`
+
+	// WalletArg and AmountArg aggregate events per wallet over the window and alert once the
+	// total reaches Threshold. Without them, every matching event raises an alert.
+	WalletArg string  `json:"wallet_arg,omitempty"`
+	AmountArg string  `json:"amount_arg,omitempty"`
+	Decimals  *int    `json:"decimals,omitempty"` // defaults to 18
+	Symbol    string  `json:"symbol,omitempty"`
+	Threshold float64 `json:"threshold,omitempty"`
+}
+
+// Subscription is a compiled Spec ready to query and decode logs.
+type Subscription struct {
+	spec   Spec
+	event  abi.Event
+	topics [][]string
+}
+
+// Event is a decoded log handed to the aggregator.
+type Event struct {
+	Name        string
+	Contract    string
+	Label       string
+	Signature   string
+	Args        []abi.Value
+	TxHash      string
+	BlockNumber string
+
+	// Aggregated is set when the spec aggregates amounts per wallet.
+	Aggregated bool
+	Wallet     string
+	Amount     float64
+	Symbol     string
+	Threshold  float64
+}
+
+// String renders the event with its arguments, e.g. "Transfer(from=0x..., to=0x..., value=1)".
+func (e Event) String() string {
+	args := make([]string, len(e.Args))
+	for i, a := range e.Args {
+		args[i] = a.String()
+	}
+	name, _, _ := strings.Cut(e.Signature, "(")
+	return name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// Compile loads each spec's ABI and resolves its event and topic filters.
+// Relative ABI paths are resolved against dir.
+func Compile(specs []Spec, dir string) ([]Subscription, error) {
+	subs := make([]Subscription, 0, len(specs))
+	for _, spec := range specs {
+		sub, err := compile(spec, dir)
+		if err != nil {
+			return nil, fmt.Errorf("log spec %q: %w", spec.Name, err)
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
+
+// Load reads a JSON array of log specs from path and compiles them.
+func Load(path string) ([]Subscription, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var specs []Spec
+	if err := json.Unmarshal(data, &specs); err != nil {
+		return nil, fmt.Errorf("parse log specs: %w", err)
+	}
+	return Compile(specs, filepath.Dir(path))
+}
+
+func compile(spec Spec, dir string) (Subscription, error) {
+	if spec.Name == "" || spec.Address == "" || spec.Event == "" {
+		return Subscription{}, fmt.Errorf("name, address and event are required")
+	}
+	if (spec.WalletArg == "") != (spec.AmountArg == "") {
+		return Subscription{}, fmt.Errorf("wallet_arg and amount_arg must be set together")
+	}
+
+	path := spec.ABI
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	contractABI, err := abi.Load(path)
+	if err != nil {
+		return Subscription{}, err
+	}
+	event, err := contractABI.EventByName(spec.Event)
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	topics := [][]string{{event.Topic}}
+	if event.Anonymous {
+		topics = [][]string{nil}
+	}
+	var matched int
+	for _, in := range event.Inputs {
+		if !in.Indexed {
+			continue
+		}
+		var alternatives []string
+		for _, value := range spec.Filter[in.Name] {
+			topic, err := abi.EncodeTopic(in.Type, value)
+			if err != nil {
+				return Subscription{}, fmt.Errorf("filter %q: %w", in.Name, err)
+			}
+			alternatives = append(alternatives, topic)
+		}
+		if len(alternatives) > 0 {
+			matched++
+		}
+		topics = append(topics, alternatives)
+	}
+	if matched != len(spec.Filter) {
+		return Subscription{}, fmt.Errorf("filter may only reference indexed arguments of %s", event.Signature())
+	}
+
+	for _, arg := range []string{spec.WalletArg, spec.AmountArg} {
+		if arg != "" && !hasInput(event, arg) {
+			return Subscription{}, fmt.Errorf("argument %q not found in %s", arg, event.Signature())
+		}
+	}
+
+	spec.Address = strings.ToLower(spec.Address)
+	return Subscription{spec: spec, event: event, topics: topics}, nil
+}
+
+// filter returns the eth_getLogs filter for the given block range.
+func (s Subscription) filter(from, to uint64) ethrpc.LogFilter {
+	return ethrpc.LogFilter{
+		FromBlock: from,
+		ToBlock:   to,
+		Addresses: []string{s.spec.Address},
+		Topics:    s.topics,
+	}
+}
+
+// decode turns a raw log into an Event.
+func (s Subscription) decode(log ethrpc.Log) (Event, error) {
+	topics := make([][]byte, len(log.Topics))
+	for i, t := range log.Topics {
+		raw, err := hex.DecodeString(strings.TrimPrefix(t, "0x"))
+		if err != nil {
+			return Event{}, fmt.Errorf("invalid topic %q: %w", t, err)
+		}
+		topics[i] = raw
+	}
+	data, err := hex.DecodeString(strings.TrimPrefix(log.Data, "0x"))
+	if err != nil {
+		return Event{}, fmt.Errorf("invalid data: %w", err)
+	}
+
+	args, err := s.event.DecodeLog(topics, data)
+	if err != nil {
+		return Event{}, err
+	}
+
+	ev := Event{
+		Name:        s.spec.Name,
+		Contract:    strings.ToLower(log.Address),
+		Label:       s.spec.Label,
+		Signature:   s.event.Signature(),
+		Args:        args,
+		TxHash:      log.TransactionHash,
+		BlockNumber: log.BlockNumber,
+		Symbol:      s.spec.Symbol,
+		Threshold:   s.spec.Threshold,
+	}
+	if s.spec.AmountArg == "" {
+		return ev, nil
+	}
+
+	ev.Aggregated = true
+	for _, a := range args {
+		switch a.Name {
+		case s.spec.WalletArg:
+			wallet, ok := a.Value.(string)
+			if !ok {
+				return Event{}, fmt.Errorf("wallet argument %q is not an address", a.Name)
+			}
+			ev.Wallet = wallet
+		case s.spec.AmountArg:
+			amount, ok := a.Value.(*big.Int)
+			if !ok {
+				return Event{}, fmt.Errorf("amount argument %q is not an integer", a.Name)
+			}
+			ev.Amount = scale(amount, s.decimals())
+		}
+	}
+	return ev, nil
+}
+
+func (s Subscription) decimals() int {
+	if s.spec.Decimals == nil {
+		return defaultDecimals
+	}
+	return *s.spec.Decimals
+}
+
+func scale(amount *big.Int, decimals int) float64 {
+	divisor := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil))
+	result, _ := new(big.Float).Quo(new(big.Float).SetInt(amount), divisor).Float64()
+	return result
+}
+
+func hasInput(event abi.Event, name string) bool {
+	for _, in := range event.Inputs {
+		if in.Name == name {
+			return true
+		}
+	}
+	return false
+}
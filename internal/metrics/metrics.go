@@ -0,0 +1,200 @@
+package metrics
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "eth_watcher"
+
+// Metrics holds the Prometheus collectors shared by the watcher, aggregator and notifiers.
+// All methods are safe to call on a nil *Metrics, which records nothing.
+type Metrics struct {
+	eventsReceived   *prometheus.CounterVec
+	eventsMatched    *prometheus.CounterVec
+	reconnects       prometheus.Counter
+	alertsFired      *prometheus.CounterVec
+	alertsSuppressed *prometheus.CounterVec
+	alertsSnoozed    *prometheus.CounterVec
+	alertsEscalated  *prometheus.CounterVec
+	notifications    *prometheus.CounterVec
+	trackedWallets   *prometheus.GaugeVec
+	windowRecords    *prometheus.GaugeVec
+
+	// lastEvent is the arrival time of the latest event in Unix nanoseconds
+	lastEvent atomic.Int64
+	now       func() time.Time
+}
+
+// New creates the collectors and registers them with reg.
+func New(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		eventsReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "events_received_total",
+			Help:      "Mined transactions received for a monitored wallet or contract, by direction.",
+		}, []string{"direction"}),
+		eventsMatched: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "events_matched_total",
+			Help:      "Received transactions that passed the filter and were aggregated, by direction.",
+		}, []string{"direction"}),
+		reconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reconnects_total",
+			Help:      "Subscriptions re-established after the event stream was lost.",
+		}),
+		alertsFired: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "alerts_fired_total",
+			Help:      "Alerts sent to the notifier, by alert type.",
+		}, []string{"type"}),
+		alertsSuppressed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "alerts_suppressed_total",
+			Help:      "Alerts not sent because the cooldown had not passed, by alert type.",
+		}, []string{"type"}),
+		alertsSnoozed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "alerts_snoozed_total",
+			Help:      "Alerts not sent because an operator snoozed their wallet or rule or muted their wallet, by alert type.",
+		}, []string{"type"}),
+		alertsEscalated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "alerts_escalated_total",
+			Help:      "Alerts sent to the escalation notifier because nobody acknowledged them in time, by alert type.",
+		}, []string{"type"}),
+		notifications: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "notifications_total",
+			Help:      "Notification attempts, by backend and result.",
+		}, []string{"backend", "result"}),
+		trackedWallets: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "tracked_wallets",
+			Help:      "Wallets with aggregation state, by direction.",
+		}, []string{"direction"}),
+		windowRecords: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "window_records",
+			Help:      "Transaction records held for the aggregation windows, by direction.",
+		}, []string{"direction"}),
+		now: time.Now,
+	}
+
+	lastEventAge := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_event_age_seconds",
+		Help:      "Seconds since the latest event was received, or -1 before the first one.",
+	}, m.lastEventAge)
+
+	reg.MustRegister(
+		m.eventsReceived,
+		m.eventsMatched,
+		m.reconnects,
+		m.alertsFired,
+		m.alertsSuppressed,
+		m.alertsSnoozed,
+		m.alertsEscalated,
+		m.notifications,
+		m.trackedWallets,
+		m.windowRecords,
+		lastEventAge,
+	)
+	return m
+}
+
+// EventArrived records the arrival of an event from the subscription stream.
+func (m *Metrics) EventArrived() {
+	if m == nil {
+		return
+	}
+	m.lastEvent.Store(m.now().UnixNano())
+}
+
+// EventReceived counts an event for a monitored wallet or contract.
+func (m *Metrics) EventReceived(direction string) {
+	if m == nil {
+		return
+	}
+	m.eventsReceived.WithLabelValues(direction).Inc()
+}
+
+// EventMatched counts an event that passed the filter and was handed to the aggregator.
+func (m *Metrics) EventMatched(direction string) {
+	if m == nil {
+		return
+	}
+	m.eventsMatched.WithLabelValues(direction).Inc()
+}
+
+// Reconnected counts a re-established subscription.
+func (m *Metrics) Reconnected() {
+	if m == nil {
+		return
+	}
+	m.reconnects.Inc()
+}
+
+// AlertFired counts an alert handed to the notifier.
+func (m *Metrics) AlertFired(alertType string) {
+	if m == nil {
+		return
+	}
+	m.alertsFired.WithLabelValues(alertType).Inc()
+}
+
+// AlertSuppressed counts an alert held back by its cooldown.
+func (m *Metrics) AlertSuppressed(alertType string) {
+	if m == nil {
+		return
+	}
+	m.alertsSuppressed.WithLabelValues(alertType).Inc()
+}
+
+// AlertSnoozed counts an alert held back by a snooze or a mute.
+func (m *Metrics) AlertSnoozed(alertType string) {
+	if m == nil {
+		return
+	}
+	m.alertsSnoozed.WithLabelValues(alertType).Inc()
+}
+
+// AlertEscalated counts an unacknowledged alert sent to the escalation notifier.
+func (m *Metrics) AlertEscalated(alertType string) {
+	if m == nil {
+		return
+	}
+	m.alertsEscalated.WithLabelValues(alertType).Inc()
+}
+
+// NotificationSent counts a delivery attempt by a notifier backend.
+func (m *Metrics) NotificationSent(backend string, err error) {
+	if m == nil {
+		return
+	}
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	m.notifications.WithLabelValues(backend, result).Inc()
+}
+
+// SetWindow reports the aggregation state held for a direction.
+func (m *Metrics) SetWindow(direction string, wallets, records int) {
+	if m == nil {
+		return
+	}
+	m.trackedWallets.WithLabelValues(direction).Set(float64(wallets))
+	m.windowRecords.WithLabelValues(direction).Set(float64(records))
+}
+
+func (m *Metrics) lastEventAge() float64 {
+	last := m.lastEvent.Load()
+	if last == 0 {
+		return -1
+	}
+	return m.now().Sub(time.Unix(0, last)).Seconds()
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics_Collect(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := New(reg)
+	now := time.Unix(1_700_000_000, 0)
+	m.now = func() time.Time { return now }
+
+	assert.Equal(t, -1.0, m.lastEventAge())
+
+	m.EventArrived()
+	m.EventReceived("from")
+	m.EventReceived("from")
+	m.EventMatched("from")
+	m.AlertSnoozed("threshold")
+	m.AlertEscalated("rule")
+	m.NotificationSent("telegram", nil)
+	m.NotificationSent("telegram", errors.New("timeout"))
+	m.SetWindow("to", 2, 5)
+	now = now.Add(30 * time.Second)
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP eth_watcher_events_received_total Mined transactions received for a monitored wallet or contract, by direction.
+# TYPE eth_watcher_events_received_total counter
+eth_watcher_events_received_total{direction="from"} 2
+# HELP eth_watcher_events_matched_total Received transactions that passed the filter and were aggregated, by direction.
+# TYPE eth_watcher_events_matched_total counter
+eth_watcher_events_matched_total{direction="from"} 1
+# HELP eth_watcher_alerts_snoozed_total Alerts not sent because an operator snoozed their wallet or rule or muted their wallet, by alert type.
+# TYPE eth_watcher_alerts_snoozed_total counter
+eth_watcher_alerts_snoozed_total{type="threshold"} 1
+# HELP eth_watcher_alerts_escalated_total Alerts sent to the escalation notifier because nobody acknowledged them in time, by alert type.
+# TYPE eth_watcher_alerts_escalated_total counter
+eth_watcher_alerts_escalated_total{type="rule"} 1
+# HELP eth_watcher_notifications_total Notification attempts, by backend and result.
+# TYPE eth_watcher_notifications_total counter
+eth_watcher_notifications_total{backend="telegram",result="failure"} 1
+eth_watcher_notifications_total{backend="telegram",result="success"} 1
+# HELP eth_watcher_window_records Transaction records held for the aggregation windows, by direction.
+# TYPE eth_watcher_window_records gauge
+eth_watcher_window_records{direction="to"} 5
+# HELP eth_watcher_last_event_age_seconds Seconds since the latest event was received, or -1 before the first one.
+# TYPE eth_watcher_last_event_age_seconds gauge
+eth_watcher_last_event_age_seconds 30
+`),
+		"eth_watcher_events_received_total",
+		"eth_watcher_events_matched_total",
+		"eth_watcher_alerts_snoozed_total",
+		"eth_watcher_alerts_escalated_total",
+		"eth_watcher_notifications_total",
+		"eth_watcher_window_records",
+		"eth_watcher_last_event_age_seconds",
+	))
+}
+
+func TestMetrics_NilIsNoop(t *testing.T) {
+	var m *Metrics
+	m.EventReceived("from")
+	m.AlertFired("threshold")
+	m.NotificationSent("telegram", nil)
+	m.SetWindow("from", 1, 1)
+}
@@ -0,0 +1,201 @@
+package nonce
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yermakovsa/eth-watcher/internal/addressbook"
+	"github.com/yermakovsa/eth-watcher/internal/logging"
+	"github.com/yermakovsa/eth-watcher/internal/notifier"
+)
+
+// Client queries the transaction count of an address at a block tag such as "latest" or "pending".
+type Client interface {
+	TransactionCount(ctx context.Context, address, block string) (uint64, error)
+}
+
+// Tracker follows the outgoing nonces of wallets we operate. It alerts when mined nonces
+// skip ahead of the last one observed, meaning transactions were sent without passing
+// through the watcher, and when a pending transaction stays unmined for too long.
+// It is safe for concurrent use.
+type Tracker struct {
+	client     Client
+	notifier   notifier.Notifier
+	book       *addressbook.Book
+	stuckAfter time.Duration
+	logger     *slog.Logger
+	now        func() time.Time
+
+	mu      sync.Mutex
+	wallets map[string]*state
+}
+
+// state is the nonce bookkeeping of a single wallet.
+type state struct {
+	// next is the nonce expected in the wallet's next mined transaction, known once
+	// a mined transaction was observed or the transaction count was polled.
+	next  uint64
+	known bool
+
+	// stuckNonce is the oldest pending nonce, waiting since stuckSince.
+	stuckNonce uint64
+	stuckSince time.Time
+	waiting    bool
+	alerted    bool
+}
+
+// New creates a tracker for the given wallets. A pending nonce that has not been mined
+// after stuckAfter raises an alert. The address book is used for labels and may be nil.
+// A nil logger uses the default one.
+func New(client Client, notif notifier.Notifier, wallets []string, stuckAfter time.Duration, book *addressbook.Book, logger *slog.Logger) *Tracker {
+	t := &Tracker{
+		client:     client,
+		notifier:   notif,
+		book:       book,
+		stuckAfter: stuckAfter,
+		logger:     logging.Component(logger, "nonce"),
+		now:        time.Now,
+		wallets:    make(map[string]*state, len(wallets)),
+	}
+	for _, w := range wallets {
+		t.wallets[strings.ToLower(w)] = &state{}
+	}
+	return t
+}
+
+// Wallets returns the tracked wallets.
+func (t *Tracker) Wallets() []string {
+	wallets := make([]string, 0, len(t.wallets))
+	for w := range t.wallets {
+		wallets = append(wallets, w)
+	}
+	return wallets
+}
+
+// Mined records a mined transaction sent by wallet with the given nonce.
+// Transactions from untracked wallets are ignored.
+func (t *Tracker) Mined(ctx context.Context, wallet string, nonce uint64, hash string) {
+	wallet = strings.ToLower(wallet)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.wallets[wallet]
+	if !ok {
+		return
+	}
+	if s.known && nonce > s.next {
+		t.notify(ctx, notifier.Alert{
+			Type:      notifier.AlertNonceGap,
+			Title:     "Nonce Gap Detected",
+			Wallet:    wallet,
+			Direction: "from",
+			TxID:      hash,
+			Fields: []notifier.Field{
+				{Name: "Nonce", Value: fmt.Sprintf("%d", nonce)},
+				{Name: "Unobserved", Value: nonceRange(s.next, nonce-1)},
+			},
+		})
+	}
+	if !s.known || nonce >= s.next {
+		s.next = nonce + 1
+		s.known = true
+	}
+}
+
+// Check polls the mined and pending transaction counts of a wallet and alerts when its
+// oldest pending nonce has been waiting longer than the stuck threshold.
+func (t *Tracker) Check(ctx context.Context, wallet string) error {
+	latest, err := t.client.TransactionCount(ctx, wallet, "latest")
+	if err != nil {
+		return fmt.Errorf("fetch nonce of %s: %w", wallet, err)
+	}
+	pending, err := t.client.TransactionCount(ctx, wallet, "pending")
+	if err != nil {
+		return fmt.Errorf("fetch pending nonce of %s: %w", wallet, err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.wallets[wallet]
+	if !ok {
+		return nil
+	}
+	// Only establish the baseline here; advancing it from polls would hide nonces the watcher missed
+	if !s.known {
+		s.next = latest
+		s.known = true
+	}
+
+	if pending <= latest {
+		s.waiting = false
+		return nil
+	}
+
+	now := t.now()
+	if !s.waiting || s.stuckNonce != latest {
+		s.stuckNonce = latest
+		s.stuckSince = now
+		s.waiting = true
+		s.alerted = false
+		return nil
+	}
+	if s.alerted || now.Sub(s.stuckSince) < t.stuckAfter {
+		return nil
+	}
+	s.alerted = true
+
+	t.notify(ctx, notifier.Alert{
+		Type:      notifier.AlertStuckTx,
+		Title:     "Stuck Transaction",
+		Wallet:    wallet,
+		Direction: "from",
+		Fields: []notifier.Field{
+			{Name: "Nonce", Value: fmt.Sprintf("%d", latest)},
+			{Name: "Pending", Value: fmt.Sprintf("%d transaction(s)", pending-latest)},
+			{Name: "Waiting", Value: now.Sub(s.stuckSince).Round(time.Second).String()},
+		},
+	})
+	return nil
+}
+
+// Run polls every tracked wallet immediately and then every interval until ctx is cancelled.
+func (t *Tracker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for wallet := range t.wallets {
+			if err := t.Check(ctx, wallet); err != nil {
+				t.logger.Error("Nonce check failed", "wallet", wallet, "error", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// notify labels the alert and sends it without blocking the caller.
+// Must be called with t.mu held.
+func (t *Tracker) notify(ctx context.Context, alert notifier.Alert) {
+	alert.Label = t.book.Label(alert.Wallet)
+	t.logger.Info("Alert fired", "type", alert.Type, "wallet", alert.Wallet, "direction", alert.Direction, "tx_hash", alert.TxID)
+	go t.notifier.Notify(ctx, alert)
+}
+
+// nonceRange formats an inclusive range of nonces.
+func nonceRange(from, to uint64) string {
+	if from == to {
+		return fmt.Sprintf("%d", from)
+	}
+	return fmt.Sprintf("%d-%d", from, to)
+}
@@ -0,0 +1,136 @@
+package nonce
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yermakovsa/eth-watcher/internal/notifier"
+)
+
+type MockClient struct {
+	mu      sync.Mutex
+	latest  uint64
+	pending uint64
+}
+
+func (m *MockClient) set(latest, pending uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.latest, m.pending = latest, pending
+}
+
+func (m *MockClient) TransactionCount(ctx context.Context, address, block string) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if block == "pending" {
+		return m.pending, nil
+	}
+	return m.latest, nil
+}
+
+type MockNotifier struct {
+	alerts chan notifier.Alert
+}
+
+func (m *MockNotifier) Notify(ctx context.Context, alert notifier.Alert) error {
+	m.alerts <- alert
+	return nil
+}
+
+func (m *MockNotifier) next(t *testing.T) notifier.Alert {
+	t.Helper()
+	select {
+	case alert := <-m.alerts:
+		return alert
+	case <-time.After(1 * time.Second):
+		t.Fatal("expected alert not received")
+		return notifier.Alert{}
+	}
+}
+
+func (m *MockNotifier) none(t *testing.T) {
+	t.Helper()
+	select {
+	case alert := <-m.alerts:
+		t.Fatalf("unexpected alert %q", alert.Title)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestTracker_NonceGap(t *testing.T) {
+	notif := &MockNotifier{alerts: make(chan notifier.Alert, 2)}
+	tracker := New(&MockClient{}, notif, []string{"0xOPS"}, time.Minute, nil, nil)
+	ctx := context.Background()
+
+	tracker.Mined(ctx, "0xops", 5, "0x5")
+	tracker.Mined(ctx, "0xops", 6, "0x6")
+	tracker.Mined(ctx, "0xother", 1, "0x1")
+	notif.none(t)
+
+	tracker.Mined(ctx, "0xops", 9, "0x9")
+	alert := notif.next(t)
+	assert.Equal(t, notifier.AlertNonceGap, alert.Type)
+	assert.Equal(t, "0x9", alert.TxID)
+	assert.Contains(t, alert.Fields, notifier.Field{Name: "Unobserved", Value: "7-8"})
+
+	// A late event for an older nonce is not a gap
+	tracker.Mined(ctx, "0xops", 8, "0x8")
+	tracker.Mined(ctx, "0xops", 10, "0xa")
+	notif.none(t)
+}
+
+func TestTracker_PollSetsBaseline(t *testing.T) {
+	notif := &MockNotifier{alerts: make(chan notifier.Alert, 1)}
+	client := &MockClient{}
+	client.set(20, 20)
+	tracker := New(client, notif, []string{"0xops"}, time.Minute, nil, nil)
+	ctx := context.Background()
+
+	require.NoError(t, tracker.Check(ctx, "0xops"))
+	tracker.Mined(ctx, "0xops", 20, "0x14")
+	notif.none(t)
+
+	tracker.Mined(ctx, "0xops", 22, "0x16")
+	assert.Contains(t, notif.next(t).Fields, notifier.Field{Name: "Unobserved", Value: "21"})
+}
+
+func TestTracker_StuckTransaction(t *testing.T) {
+	notif := &MockNotifier{alerts: make(chan notifier.Alert, 2)}
+	client := &MockClient{}
+	client.set(7, 9)
+	tracker := New(client, notif, []string{"0xops"}, 5*time.Minute, nil, nil)
+	now := time.Unix(1_700_000_000, 0)
+	tracker.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	require.NoError(t, tracker.Check(ctx, "0xops"))
+	now = now.Add(4 * time.Minute)
+	require.NoError(t, tracker.Check(ctx, "0xops"))
+	notif.none(t)
+
+	now = now.Add(2 * time.Minute)
+	require.NoError(t, tracker.Check(ctx, "0xops"))
+	alert := notif.next(t)
+	assert.Equal(t, notifier.AlertStuckTx, alert.Type)
+	assert.Equal(t, []notifier.Field{
+		{Name: "Nonce", Value: "7"},
+		{Name: "Pending", Value: "2 transaction(s)"},
+		{Name: "Waiting", Value: "6m0s"},
+	}, alert.Fields)
+
+	// Alerted once per stuck nonce
+	now = now.Add(time.Minute)
+	require.NoError(t, tracker.Check(ctx, "0xops"))
+	notif.none(t)
+
+	// Progress restarts the clock for the next nonce
+	client.set(8, 9)
+	require.NoError(t, tracker.Check(ctx, "0xops"))
+	now = now.Add(time.Minute)
+	require.NoError(t, tracker.Check(ctx, "0xops"))
+	notif.none(t)
+}
@@ -0,0 +1,93 @@
+package notifier
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// AlertType identifies the condition that produced an alert.
+type AlertType string
+
+const (
+	AlertThreshold     AlertType = "threshold"
+	AlertNetFlow       AlertType = "net_flow"
+	AlertRule          AlertType = "rule"
+	AlertSanctions     AlertType = "sanctions"
+	AlertContractCall  AlertType = "contract_call"
+	AlertLogEvent      AlertType = "log_event"
+	AlertLogVolume     AlertType = "log_volume"
+	AlertFeeSpend      AlertType = "fee_spend"
+	AlertFailedTx      AlertType = "failed_tx"
+	AlertLowBalance    AlertType = "low_balance"
+	AlertBalanceChange AlertType = "balance_change"
+	AlertNonceGap      AlertType = "nonce_gap"
+	AlertStuckTx       AlertType = "stuck_tx"
+)
+
+// Severity ranks how urgently an alert needs attention. Alerts without one are warnings.
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// Rank orders severities from info to critical, counting an empty severity as a warning
+// and an unknown one as zero.
+func (s Severity) Rank() int {
+	switch s {
+	case SeverityInfo:
+		return 1
+	case SeverityWarning, "":
+		return 2
+	case SeverityCritical:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// Valid reports whether s is one of the known severities.
+func (s Severity) Valid() bool {
+	return s != "" && s.Rank() > 0
+}
+
+// Alert describes a condition detected on a monitored wallet.
+type Alert struct {
+	ID        string // assigned when the alert fires, used to acknowledge or snooze it
+	Type      AlertType
+	Rule      string // name of the rule or log spec that fired
+	Severity  Severity
+	Title     string
+	Wallet    string
+	Label     string // address book label of the wallet, if any
+	Direction string // "from" or "to" renders the wallet as sender or receiver
+	Amount    float64
+	AmountUSD float64 // zero unless a price feed is configured
+	TxID      string
+	TxHashes  []string // transactions in the window of volume alerts, oldest first
+	Fields    []Field
+}
+
+// Key names the condition that produced the alert: the rule or log spec for those that
+// have one, the alert type otherwise. Snoozes hold back alerts by wallet and key.
+func (a Alert) Key() string {
+	if a.Rule != "" {
+		return a.Rule
+	}
+	return string(a.Type)
+}
+
+// NewID returns a random alert ID, short enough for Telegram button data.
+func NewID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Field is a named value rendered as one line of an alert.
+type Field struct {
+	Name  string
+	Value string
+}
@@ -0,0 +1,95 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/yermakovsa/eth-watcher/internal/logging"
+	"github.com/yermakovsa/eth-watcher/internal/metrics"
+	"github.com/yermakovsa/eth-watcher/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Instrumented wraps a notifier backend, counting, logging and tracing its delivery
+// results and tracking deliveries still in flight. It is safe for concurrent use.
+type Instrumented struct {
+	backend string
+	next    Notifier
+	metrics *metrics.Metrics
+	logger  *slog.Logger
+	tracer  trace.Tracer
+
+	mu      sync.Mutex
+	seq     uint64
+	pending map[uint64]time.Time
+}
+
+// Instrument wraps next, reporting its deliveries under the backend name.
+// A nil logger or tracer provider uses the global one.
+func Instrument(backend string, next Notifier, m *metrics.Metrics, logger *slog.Logger, tp trace.TracerProvider) *Instrumented {
+	return &Instrumented{
+		backend: backend,
+		next:    next,
+		metrics: m,
+		logger:  logging.Component(logger, "notifier").With("backend", backend),
+		tracer:  tracing.Tracer(tp, "notifier"),
+		pending: make(map[uint64]time.Time),
+	}
+}
+
+// Notify forwards the alert and records whether it was delivered, in a child span of ctx.
+func (i *Instrumented) Notify(ctx context.Context, alert Alert) error {
+	ctx, span := i.tracer.Start(ctx, "notifier.notify", trace.WithAttributes(
+		attribute.String("backend", i.backend),
+		attribute.String("type", string(alert.Type)),
+		attribute.String("wallet", alert.Wallet),
+		attribute.String("tx_hash", alert.TxID),
+	))
+	defer span.End()
+
+	i.mu.Lock()
+	i.seq++
+	id := i.seq
+	i.pending[id] = time.Now()
+	i.mu.Unlock()
+
+	err := i.next.Notify(ctx, alert)
+
+	i.mu.Lock()
+	delete(i.pending, id)
+	i.mu.Unlock()
+
+	i.metrics.NotificationSent(i.backend, err)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "delivery failed")
+		i.logger.Error("Failed to send alert", "type", alert.Type, "wallet", alert.Wallet, "direction", alert.Direction, "tx_hash", alert.TxID, "error", err)
+	}
+	return err
+}
+
+// Alive reports an error when a delivery has been in flight for longer than maxPending,
+// meaning the backend is hanging instead of succeeding or failing.
+func (i *Instrumented) Alive(maxPending time.Duration) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var oldest time.Time
+	for _, started := range i.pending {
+		if oldest.IsZero() || started.Before(oldest) {
+			oldest = started
+		}
+	}
+	if oldest.IsZero() {
+		return nil
+	}
+	if age := time.Since(oldest); age > maxPending {
+		return fmt.Errorf("%d %s notification(s) pending, oldest for %s", len(i.pending), i.backend, age.Round(time.Second))
+	}
+	return nil
+}
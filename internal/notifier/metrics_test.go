@@ -0,0 +1,92 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yermakovsa/eth-watcher/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type notifierFunc func(ctx context.Context, alert Alert) error
+
+func (f notifierFunc) Notify(ctx context.Context, alert Alert) error {
+	return f(ctx, alert)
+}
+
+func TestInstrumented_CountsResults(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	fail := false
+	n := Instrument("telegram", notifierFunc(func(ctx context.Context, alert Alert) error {
+		if fail {
+			return errors.New("bad gateway")
+		}
+		return nil
+	}), metrics.New(reg), nil, nil)
+
+	require.NoError(t, n.Notify(context.Background(), Alert{Title: "High Volume Detected"}))
+	fail = true
+	assert.ErrorContains(t, n.Notify(context.Background(), Alert{Title: "High Volume Detected"}), "bad gateway")
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP eth_watcher_notifications_total Notification attempts, by backend and result.
+# TYPE eth_watcher_notifications_total counter
+eth_watcher_notifications_total{backend="telegram",result="failure"} 1
+eth_watcher_notifications_total{backend="telegram",result="success"} 1
+`), "eth_watcher_notifications_total"))
+}
+
+func TestInstrumented_AliveDetectsHangingDelivery(t *testing.T) {
+	release := make(chan struct{})
+	n := Instrument("telegram", notifierFunc(func(ctx context.Context, alert Alert) error {
+		<-release
+		return nil
+	}), nil, nil, nil)
+
+	assert.NoError(t, n.Alive(time.Millisecond), "nothing pending")
+
+	done := make(chan struct{})
+	go func() {
+		_ = n.Notify(context.Background(), Alert{Title: "High Volume Detected"})
+		close(done)
+	}()
+	assert.Eventually(t, func() bool { return n.Alive(20*time.Millisecond) != nil }, time.Second, 10*time.Millisecond)
+	assert.ErrorContains(t, n.Alive(20*time.Millisecond), "1 telegram notification(s) pending")
+	assert.NoError(t, n.Alive(time.Hour))
+
+	close(release)
+	<-done
+	assert.NoError(t, n.Alive(time.Millisecond))
+}
+
+func TestInstrumented_RecordsSpan(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	n := Instrument("telegram", notifierFunc(func(ctx context.Context, alert Alert) error {
+		return errors.New("bad gateway")
+	}), nil, nil, tp)
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "aggregator.process")
+	_ = n.Notify(ctx, Alert{Type: AlertThreshold, Wallet: "0xabc", TxID: "0x1"})
+	parent.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	notify := spans[0]
+	assert.Equal(t, "notifier.notify", notify.Name)
+	assert.Equal(t, parent.SpanContext().SpanID(), notify.Parent.SpanID())
+	assert.Equal(t, codes.Error, notify.Status.Code)
+	assert.Contains(t, notify.Attributes, attribute.String("backend", "telegram"))
+	assert.Contains(t, notify.Attributes, attribute.String("type", "threshold"))
+}
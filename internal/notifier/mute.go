@@ -0,0 +1,166 @@
+package notifier
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yermakovsa/eth-watcher/internal/logging"
+	"github.com/yermakovsa/eth-watcher/internal/metrics"
+)
+
+// Snoozer reports whether operators snoozed the wallet and rule of an alert.
+type Snoozer interface {
+	Snoozed(alert Alert) bool
+}
+
+// Reasons an alert was held back, reported to the WithHeld callback.
+const (
+	HeldMuted   = "muted"
+	HeldSnoozed = "snoozed"
+)
+
+// Muter holds back the alerts about muted wallets for a while, and those about snoozed
+// wallets and rules. Critical alerts, such as interactions with flagged addresses, are
+// always delivered. It is safe for concurrent use.
+type Muter struct {
+	next    Notifier
+	snoozes Snoozer
+	metrics *metrics.Metrics
+	held    func(ctx context.Context, alert Alert, reason string)
+	logger  *slog.Logger
+	now     func() time.Time
+
+	mu    sync.Mutex
+	muted map[string]time.Time // wallet to the end of its mute
+}
+
+// MuterOption configures a Muter.
+type MuterOption func(*Muter)
+
+// WithSnoozes also holds back the alerts whose wallet and rule are snoozed.
+func WithSnoozes(s Snoozer) MuterOption {
+	return func(m *Muter) {
+		m.snoozes = s
+	}
+}
+
+// WithMuterMetrics counts the alerts forwarded as fired and those held back as snoozed.
+// The Muter sits in front of every alert source, so the counts cover them all.
+func WithMuterMetrics(reg *metrics.Metrics) MuterOption {
+	return func(m *Muter) {
+		m.metrics = reg
+	}
+}
+
+// WithHeld calls held with each alert held back and the reason, HeldMuted or HeldSnoozed,
+// such as to record that it was not delivered.
+func WithHeld(held func(ctx context.Context, alert Alert, reason string)) MuterOption {
+	return func(m *Muter) {
+		m.held = held
+	}
+}
+
+// NewMuter wraps next. A nil logger uses the default one.
+func NewMuter(next Notifier, logger *slog.Logger, opts ...MuterOption) *Muter {
+	m := &Muter{
+		next:   next,
+		logger: logging.Component(logger, "notifier"),
+		now:    time.Now,
+		muted:  make(map[string]time.Time),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Notify forwards the alert unless its wallet is muted or its wallet and rule are snoozed.
+func (m *Muter) Notify(ctx context.Context, alert Alert) error {
+	if alert.Severity != SeverityCritical {
+		if m.isMuted(alert.Wallet) {
+			m.logger.Info("Alert muted", "alert_id", alert.ID, "type", alert.Type, "wallet", alert.Wallet, "tx_hash", alert.TxID)
+			m.hold(ctx, alert, HeldMuted)
+			return nil
+		}
+		if m.snoozes != nil && m.snoozes.Snoozed(alert) {
+			m.logger.Info("Alert snoozed", "alert_id", alert.ID, "type", alert.Type, "rule", alert.Rule, "wallet", alert.Wallet, "tx_hash", alert.TxID)
+			m.hold(ctx, alert, HeldSnoozed)
+			return nil
+		}
+	}
+	m.metrics.AlertFired(string(alert.Type))
+	return m.next.Notify(ctx, alert)
+}
+
+func (m *Muter) hold(ctx context.Context, alert Alert, reason string) {
+	m.metrics.AlertSnoozed(string(alert.Type))
+	if m.held != nil {
+		m.held(ctx, alert, reason)
+	}
+}
+
+// Mute holds back the alerts about a wallet until the given time.
+func (m *Muter) Mute(wallet string, until time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.muted[strings.ToLower(wallet)] = until
+}
+
+// Unmute resumes the alerts about a wallet, reporting whether it was muted.
+func (m *Muter) Unmute(wallet string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune()
+
+	wallet = strings.ToLower(wallet)
+	_, ok := m.muted[wallet]
+	delete(m.muted, wallet)
+	return ok
+}
+
+// UnmuteAll resumes the alerts about every wallet, returning how many were muted.
+func (m *Muter) UnmuteAll() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune()
+
+	n := len(m.muted)
+	clear(m.muted)
+	return n
+}
+
+// Muted returns the wallets currently muted and when each mute ends.
+func (m *Muter) Muted() map[string]time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune()
+
+	muted := make(map[string]time.Time, len(m.muted))
+	for wallet, until := range m.muted {
+		muted[wallet] = until
+	}
+	return muted
+}
+
+func (m *Muter) isMuted(wallet string) bool {
+	if wallet == "" {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	until, ok := m.muted[strings.ToLower(wallet)]
+	return ok && m.now().Before(until)
+}
+
+// prune forgets the mutes that have ended. Must be called with m.mu held.
+func (m *Muter) prune() {
+	now := m.now()
+	for wallet, until := range m.muted {
+		if !now.Before(until) {
+			delete(m.muted, wallet)
+		}
+	}
+}
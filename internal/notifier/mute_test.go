@@ -0,0 +1,117 @@
+package notifier
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yermakovsa/eth-watcher/internal/metrics"
+)
+
+func TestMuter_HoldsBackMutedWallets(t *testing.T) {
+	var delivered []string
+	m := NewMuter(notifierFunc(func(ctx context.Context, alert Alert) error {
+		delivered = append(delivered, alert.TxID)
+		return nil
+	}), nil)
+	now := time.Unix(1_700_000_000, 0)
+	m.now = func() time.Time { return now }
+
+	m.Mute("0xABC", now.Add(time.Hour))
+	require.NoError(t, m.Notify(context.Background(), Alert{Wallet: "0xabc", TxID: "0x1"}))
+	require.NoError(t, m.Notify(context.Background(), Alert{Wallet: "0xdef", TxID: "0x2"}))
+	require.NoError(t, m.Notify(context.Background(), Alert{Wallet: "0xabc", Severity: SeverityCritical, TxID: "0x3"}))
+	assert.Equal(t, map[string]time.Time{"0xabc": now.Add(time.Hour)}, m.Muted())
+
+	now = now.Add(time.Hour)
+	require.NoError(t, m.Notify(context.Background(), Alert{Wallet: "0xabc", TxID: "0x4"}))
+	assert.Empty(t, m.Muted(), "the mute has ended")
+
+	assert.Equal(t, []string{"0x2", "0x3", "0x4"}, delivered)
+}
+
+func TestMuter_Unmute(t *testing.T) {
+	m := NewMuter(notifierFunc(func(ctx context.Context, alert Alert) error { return nil }), nil)
+	until := time.Now().Add(time.Hour)
+
+	m.Mute("0xabc", until)
+	m.Mute("0xdef", until)
+	m.Mute("0x123", time.Now().Add(-time.Minute))
+
+	assert.True(t, m.Unmute("0xABC"))
+	assert.False(t, m.Unmute("0xabc"))
+	assert.False(t, m.Unmute("0x123"), "an ended mute does not count")
+	assert.Equal(t, 1, m.UnmuteAll())
+	assert.Empty(t, m.Muted())
+}
+
+type snoozerFunc func(alert Alert) bool
+
+func (f snoozerFunc) Snoozed(alert Alert) bool { return f(alert) }
+
+func TestMuter_HoldsBackSnoozedAlerts(t *testing.T) {
+	var delivered []string
+	m := NewMuter(notifierFunc(func(ctx context.Context, alert Alert) error {
+		delivered = append(delivered, alert.TxID)
+		return nil
+	}), nil, WithSnoozes(snoozerFunc(func(alert Alert) bool {
+		return alert.Wallet == "0xabc" && alert.Key() == "low_balance"
+	})))
+
+	require.NoError(t, m.Notify(context.Background(), Alert{Type: AlertLowBalance, Wallet: "0xabc", TxID: "0x1"}))
+	require.NoError(t, m.Notify(context.Background(), Alert{Type: AlertThreshold, Wallet: "0xabc", TxID: "0x2"}))
+	require.NoError(t, m.Notify(context.Background(), Alert{Type: AlertLowBalance, Wallet: "0xdef", TxID: "0x3"}))
+	require.NoError(t, m.Notify(context.Background(), Alert{Type: AlertLowBalance, Wallet: "0xabc", Severity: SeverityCritical, TxID: "0x4"}))
+
+	assert.Equal(t, []string{"0x2", "0x3", "0x4"}, delivered, "snoozes hold back alerts from any source, except critical ones")
+}
+
+func TestMuter_CountsAlertsOfEverySource(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := NewMuter(notifierFunc(func(ctx context.Context, alert Alert) error { return nil }), nil,
+		WithSnoozes(snoozerFunc(func(alert Alert) bool { return alert.Key() == "nonce_gap" })),
+		WithMuterMetrics(metrics.New(reg)))
+	m.Mute("0xdef", time.Now().Add(time.Hour))
+
+	for _, alert := range []Alert{
+		{Type: AlertThreshold, Wallet: "0xabc"},
+		{Type: AlertLowBalance, Wallet: "0xabc"},
+		{Type: AlertNonceGap, Wallet: "0xabc"},
+		{Type: AlertStuckTx, Wallet: "0xdef"},
+	} {
+		require.NoError(t, m.Notify(context.Background(), alert))
+	}
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP eth_watcher_alerts_fired_total Alerts sent to the notifier, by alert type.
+# TYPE eth_watcher_alerts_fired_total counter
+eth_watcher_alerts_fired_total{type="low_balance"} 1
+eth_watcher_alerts_fired_total{type="threshold"} 1
+# HELP eth_watcher_alerts_snoozed_total Alerts not sent because an operator snoozed their wallet or rule or muted their wallet, by alert type.
+# TYPE eth_watcher_alerts_snoozed_total counter
+eth_watcher_alerts_snoozed_total{type="nonce_gap"} 1
+eth_watcher_alerts_snoozed_total{type="stuck_tx"} 1
+`), "eth_watcher_alerts_fired_total", "eth_watcher_alerts_snoozed_total"))
+}
+
+func TestMuter_ReportsHeldAlerts(t *testing.T) {
+	held := map[string]string{}
+	m := NewMuter(notifierFunc(func(ctx context.Context, alert Alert) error { return nil }), nil,
+		WithSnoozes(snoozerFunc(func(alert Alert) bool { return alert.Wallet == "0xdef" })),
+		WithHeld(func(ctx context.Context, alert Alert, reason string) { held[alert.ID] = reason }))
+	m.Mute("0xabc", time.Now().Add(time.Hour))
+
+	for _, alert := range []Alert{
+		{ID: "a1", Wallet: "0xabc"},
+		{ID: "a2", Wallet: "0xdef"},
+		{ID: "a3", Wallet: "0x123"},
+	} {
+		require.NoError(t, m.Notify(context.Background(), alert))
+	}
+	assert.Equal(t, map[string]string{"a1": HeldMuted, "a2": HeldSnoozed}, held)
+}
@@ -0,0 +1,24 @@
+package notifier
+
+import "context"
+
+// Router delivers each alert through the notifier configured for its severity, falling
+// back to a default one for the others.
+type Router struct {
+	fallback Notifier
+	routes   map[Severity]Notifier
+}
+
+// NewRouter routes the alerts of the severities in routes to their notifier and every
+// other alert, including those without a severity, to fallback.
+func NewRouter(fallback Notifier, routes map[Severity]Notifier) *Router {
+	return &Router{fallback: fallback, routes: routes}
+}
+
+// Notify forwards the alert to the notifier of its severity.
+func (r *Router) Notify(ctx context.Context, alert Alert) error {
+	if next, ok := r.routes[alert.Severity]; ok {
+		return next.Notify(ctx, alert)
+	}
+	return r.fallback.Notify(ctx, alert)
+}
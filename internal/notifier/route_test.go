@@ -0,0 +1,43 @@
+package notifier
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter_RoutesBySeverity(t *testing.T) {
+	delivered := map[string][]string{}
+	to := func(name string) Notifier {
+		return notifierFunc(func(ctx context.Context, alert Alert) error {
+			delivered[name] = append(delivered[name], alert.TxID)
+			return nil
+		})
+	}
+	r := NewRouter(to("default"), map[Severity]Notifier{
+		SeverityInfo:     to("info"),
+		SeverityCritical: to("oncall"),
+	})
+
+	require.NoError(t, r.Notify(context.Background(), Alert{Severity: SeverityInfo, TxID: "0x1"}))
+	require.NoError(t, r.Notify(context.Background(), Alert{Severity: SeverityWarning, TxID: "0x2"}))
+	require.NoError(t, r.Notify(context.Background(), Alert{Severity: SeverityCritical, TxID: "0x3"}))
+	require.NoError(t, r.Notify(context.Background(), Alert{TxID: "0x4"}))
+
+	assert.Equal(t, map[string][]string{
+		"info":    {"0x1"},
+		"default": {"0x2", "0x4"},
+		"oncall":  {"0x3"},
+	}, delivered)
+}
+
+func TestSeverity_Rank(t *testing.T) {
+	assert.Less(t, SeverityInfo.Rank(), SeverityWarning.Rank())
+	assert.Less(t, SeverityWarning.Rank(), SeverityCritical.Rank())
+	assert.Equal(t, SeverityWarning.Rank(), Severity("").Rank(), "alerts without a severity are warnings")
+	assert.True(t, SeverityInfo.Valid())
+	assert.False(t, Severity("").Valid())
+	assert.False(t, Severity("urgent").Valid())
+}
@@ -3,53 +3,116 @@ package notifier
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/mymmrac/telego"
 )
 
+// Callback data of the alert buttons is the action and the alert ID separated by colons,
+// followed by the duration for snoozes, e.g. "snooze:1a2b3c4d5e6f:1h0m0s".
+const (
+	ActionAck    = "ack"
+	ActionSnooze = "snooze"
+)
+
+// snoozeButtons are the snooze durations offered under an alert.
+var snoozeButtons = []time.Duration{time.Hour, 24 * time.Hour}
+
 type Notifier interface {
-	NotifyThresholdExceeded(ctx context.Context, txID, walletFrom string, walletTo string, total float64) error
+	Notify(ctx context.Context, alert Alert) error
 }
 
 type Bot interface {
 	SendMessage(ctx context.Context, params *telego.SendMessageParams) (*telego.Message, error)
 }
 
+// TelegramOption configures optional TelegramNotifier behaviour.
+type TelegramOption func(*TelegramNotifier)
+
+// WithAlertButtons adds buttons to acknowledge or snooze an alert under each alert with
+// an ID. The command bot must be running to answer them.
+func WithAlertButtons() TelegramOption {
+	return func(t *TelegramNotifier) {
+		t.buttons = true
+	}
+}
+
 type TelegramNotifier struct {
-	bot    Bot
-	chatID int64
+	bot     Bot
+	chatID  int64
+	buttons bool
 }
 
-func NewTelegramNotifier(bot Bot, chatID int64) *TelegramNotifier {
-	return &TelegramNotifier{
+func NewTelegramNotifier(bot Bot, chatID int64, opts ...TelegramOption) *TelegramNotifier {
+	t := &TelegramNotifier{
 		bot:    bot,
 		chatID: chatID,
 	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
 }
 
-func (t *TelegramNotifier) NotifyThresholdExceeded(ctx context.Context, txID, walletFrom string, walletTo string, total float64) error {
-	var msg string
-	switch {
-	case walletFrom != "":
-		msg = fmt.Sprintf(
-			"🔔 High Volume Detected\n\nSender: %s\nAmount: %.4f ETH\nTxID: %s",
-			walletFrom, total, txID,
-		)
-	case walletTo != "":
-		msg = fmt.Sprintf(
-			"🔔 High Volume Detected\n\nReceiver: %s\nAmount: %.4f ETH\nTxID: %s",
-			walletTo, total, txID,
-		)
-	default:
-		return nil
+// Notify sends a generic alert, listing its fields in order.
+func (t *TelegramNotifier) Notify(ctx context.Context, alert Alert) error {
+	icon := "🔔"
+	switch alert.Severity {
+	case SeverityCritical:
+		icon = "🚨"
+	case SeverityInfo:
+		icon = "ℹ️"
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s\n\n", icon, alert.Title)
+	if alert.Wallet != "" {
+		wallet := alert.Wallet
+		if alert.Label != "" {
+			wallet = fmt.Sprintf("%s (%s)", alert.Wallet, alert.Label)
+		}
+		switch alert.Direction {
+		case "from":
+			fmt.Fprintf(&sb, "Sender: %s\n", wallet)
+		case "to":
+			fmt.Fprintf(&sb, "Receiver: %s\n", wallet)
+		default:
+			fmt.Fprintf(&sb, "Wallet: %s\n", wallet)
+		}
+	}
+	for _, f := range alert.Fields {
+		fmt.Fprintf(&sb, "%s: %s\n", f.Name, f.Value)
+	}
+	if alert.TxID != "" {
+		fmt.Fprintf(&sb, "TxID: %s\n", alert.TxID)
 	}
 
 	params := &telego.SendMessageParams{}
-	_, err := t.bot.SendMessage(ctx,
-		params.
-			WithChatID(telego.ChatID{ID: t.chatID}).
-			WithText(msg),
-	)
+	params.
+		WithChatID(telego.ChatID{ID: t.chatID}).
+		WithText(strings.TrimSuffix(sb.String(), "\n"))
+	if t.buttons && alert.ID != "" {
+		params.WithReplyMarkup(alertButtons(alert))
+	}
+	_, err := t.bot.SendMessage(ctx, params)
 
 	return err
 }
+
+// alertButtons offers to acknowledge an alert and, unless it is critical, to snooze its
+// wallet and rule.
+func alertButtons(alert Alert) *telego.InlineKeyboardMarkup {
+	row := []telego.InlineKeyboardButton{
+		{Text: "✅ Ack", CallbackData: ActionAck + ":" + alert.ID},
+	}
+	if alert.Severity != SeverityCritical {
+		for _, d := range snoozeButtons {
+			row = append(row, telego.InlineKeyboardButton{
+				Text:         "💤 " + strings.TrimSuffix(strings.TrimSuffix(d.String(), "0s"), "0m"),
+				CallbackData: ActionSnooze + ":" + alert.ID + ":" + d.String(),
+			})
+		}
+	}
+	return &telego.InlineKeyboardMarkup{InlineKeyboard: [][]telego.InlineKeyboardButton{row}}
+}
@@ -12,38 +12,140 @@ import (
 type mockBot struct {
 	sendCalled bool
 	shouldFail bool
+	text       string
+	markup     telego.ReplyMarkup
 }
 
 func (m *mockBot) SendMessage(ctx context.Context, params *telego.SendMessageParams) (*telego.Message, error) {
 	m.sendCalled = true
+	m.text = params.Text
+	m.markup = params.ReplyMarkup
 	if m.shouldFail {
 		return nil, errors.New("send failed")
 	}
 	return &telego.Message{}, nil
 }
 
-func TestNotifyThresholdExceeded_Success(t *testing.T) {
+// thresholdAlert is the alert the aggregator sends for an incoming volume threshold.
+var thresholdAlert = Alert{
+	Type:      AlertThreshold,
+	Title:     "High Volume Detected",
+	Wallet:    "0xwallet",
+	Direction: "to",
+	Amount:    1.5,
+	TxID:      "0xtxhash",
+	Fields:    []Field{{Name: "Amount", Value: "1.5000 ETH"}},
+}
+
+func TestNotify_Success(t *testing.T) {
 	mock := &mockBot{}
 	notifier := &TelegramNotifier{
 		bot:    mock,
 		chatID: 123456,
 	}
 
-	err := notifier.NotifyThresholdExceeded(context.Background(), "0xtxhash", "0xwallet", "", 123.45)
+	err := notifier.Notify(context.Background(), thresholdAlert)
 
 	assert.NoError(t, err)
 	assert.True(t, mock.sendCalled)
 }
 
-func TestNotifyThresholdExceeded_Error(t *testing.T) {
+func TestNotify_Error(t *testing.T) {
 	mock := &mockBot{shouldFail: true}
 	notifier := &TelegramNotifier{
 		bot:    mock,
 		chatID: 123456,
 	}
 
-	err := notifier.NotifyThresholdExceeded(context.Background(), "0xtxhash", "0xwallet", "", 123.45)
+	err := notifier.Notify(context.Background(), thresholdAlert)
 
 	assert.Error(t, err)
 	assert.True(t, mock.sendCalled)
 }
+
+func TestNotify_ThresholdText(t *testing.T) {
+	mock := &mockBot{}
+	notifier := &TelegramNotifier{
+		bot:    mock,
+		chatID: 123456,
+	}
+
+	err := notifier.Notify(context.Background(), thresholdAlert)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "🔔 High Volume Detected\n\nReceiver: 0xwallet\nAmount: 1.5000 ETH\nTxID: 0xtxhash", mock.text)
+}
+
+func TestNotify_RendersFields(t *testing.T) {
+	mock := &mockBot{}
+	notifier := &TelegramNotifier{
+		bot:    mock,
+		chatID: 123456,
+	}
+
+	err := notifier.Notify(context.Background(), Alert{
+		Type:   AlertNetFlow,
+		Title:  "Net Flow Detected",
+		Wallet: "0xwallet",
+		Label:  "Hot wallet",
+		TxID:   "0xtxhash",
+		Fields: []Field{{Name: "Net", Value: "-2.0000 ETH"}},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "🔔 Net Flow Detected\n\nWallet: 0xwallet (Hot wallet)\nNet: -2.0000 ETH\nTxID: 0xtxhash", mock.text)
+}
+
+func TestNotify_CriticalIcon(t *testing.T) {
+	mock := &mockBot{}
+	notifier := &TelegramNotifier{
+		bot:    mock,
+		chatID: 123456,
+	}
+
+	err := notifier.Notify(context.Background(), Alert{
+		Type:     AlertSanctions,
+		Severity: SeverityCritical,
+		Title:    "Flagged Address Interaction",
+		Wallet:   "0xwallet",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "🚨 Flagged Address Interaction\n\nWallet: 0xwallet", mock.text)
+}
+
+func TestNotify_AlertButtons(t *testing.T) {
+	mock := &mockBot{}
+	notifier := NewTelegramNotifier(mock, 123456, WithAlertButtons())
+
+	err := notifier.Notify(context.Background(), Alert{ID: "1a2b3c", Title: "High Volume Detected"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &telego.InlineKeyboardMarkup{InlineKeyboard: [][]telego.InlineKeyboardButton{{
+		{Text: "✅ Ack", CallbackData: "ack:1a2b3c"},
+		{Text: "💤 1h", CallbackData: "snooze:1a2b3c:1h0m0s"},
+		{Text: "💤 24h", CallbackData: "snooze:1a2b3c:24h0m0s"},
+	}}}, mock.markup)
+
+	err = notifier.Notify(context.Background(), Alert{ID: "4d5e6f", Title: "Flagged Address Interaction", Severity: SeverityCritical})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &telego.InlineKeyboardMarkup{InlineKeyboard: [][]telego.InlineKeyboardButton{{
+		{Text: "✅ Ack", CallbackData: "ack:4d5e6f"},
+	}}}, mock.markup, "critical alerts cannot be snoozed")
+
+	err = notifier.Notify(context.Background(), Alert{Title: "High Volume Detected"})
+
+	assert.NoError(t, err)
+	assert.Nil(t, mock.markup, "alerts without an ID cannot be acknowledged")
+}
+
+func TestNotify_NoButtonsByDefault(t *testing.T) {
+	mock := &mockBot{}
+	notifier := NewTelegramNotifier(mock, 123456)
+
+	err := notifier.Notify(context.Background(), Alert{ID: "1a2b3c", Title: "High Volume Detected"})
+
+	assert.NoError(t, err)
+	assert.Nil(t, mock.markup)
+}
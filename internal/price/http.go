@@ -0,0 +1,115 @@
+package price
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yermakovsa/eth-watcher/internal/logging"
+)
+
+// HTTP fetches the ETH price from a JSON endpoint such as the CoinGecko simple price API.
+// Prices are cached for the configured TTL. If a refresh fails, the last known price is
+// returned until it is older than ten times the TTL, and the refresh is retried after another TTL.
+type HTTP struct {
+	url    string
+	field  []string
+	ttl    time.Duration
+	http   *http.Client
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu      sync.Mutex
+	price   float64
+	fetched time.Time
+	failed  time.Time
+}
+
+// NewHTTP returns a feed reading the dot-separated field of the JSON document at url,
+// for example "ethereum.usd". If httpClient is nil, a client with a 10 second timeout is used.
+// A nil logger uses the default one.
+func NewHTTP(url, field string, ttl time.Duration, httpClient *http.Client, logger *slog.Logger) *HTTP {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &HTTP{
+		url:    url,
+		field:  strings.Split(field, "."),
+		ttl:    ttl,
+		http:   httpClient,
+		logger: logging.Component(logger, "price"),
+		now:    time.Now,
+	}
+}
+
+// ETHUSD returns the cached price, refreshing it when it has expired.
+func (h *HTTP) ETHUSD(ctx context.Context) (float64, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.now()
+	stale := !h.fetched.IsZero() && now.Sub(h.fetched) < 10*h.ttl
+	if !h.fetched.IsZero() && now.Sub(h.fetched) < h.ttl || stale && now.Sub(h.failed) < h.ttl {
+		return h.price, nil
+	}
+
+	price, err := h.fetch(ctx)
+	if err != nil {
+		h.failed = now
+		if stale {
+			h.logger.Warn("Failed to refresh price, using last known value", "fetched", h.fetched, "error", err)
+			return h.price, nil
+		}
+		return 0, err
+	}
+
+	h.price = price
+	h.fetched = now
+	return price, nil
+}
+
+// fetch requests the document and extracts the configured field.
+func (h *HTTP) fetch(ctx context.Context) (float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := h.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var doc any
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return 0, fmt.Errorf("decode response: %w", err)
+	}
+
+	for _, key := range h.field {
+		obj, ok := doc.(map[string]any)
+		if !ok {
+			return 0, fmt.Errorf("field '%s' not found", strings.Join(h.field, "."))
+		}
+		doc = obj[key]
+	}
+
+	switch v := doc.(type) {
+	case float64:
+		return parse(fmt.Sprint(v))
+	case string:
+		return parse(v)
+	default:
+		return 0, fmt.Errorf("field '%s' not found", strings.Join(h.field, "."))
+	}
+}
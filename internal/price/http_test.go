@@ -0,0 +1,64 @@
+package price
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTP_CachesAndFallsBack(t *testing.T) {
+	var (
+		calls  atomic.Int32
+		broken atomic.Bool
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if broken.Load() {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{"ethereum": {"usd": 3210.5}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	feed := NewHTTP(srv.URL, "ethereum.usd", time.Minute, nil, nil)
+	now := time.Unix(1_700_000_000, 0)
+	feed.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		price, err := feed.ETHUSD(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 3210.5, price)
+	}
+	assert.Equal(t, int32(1), calls.Load())
+
+	// A failed refresh keeps serving the last price for a while
+	broken.Store(true)
+	now = now.Add(2 * time.Minute)
+	price, err := feed.ETHUSD(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3210.5, price)
+	_, _ = feed.ETHUSD(ctx)
+	assert.Equal(t, int32(2), calls.Load(), "failed refresh must not be retried before the TTL")
+
+	now = now.Add(time.Hour)
+	_, err = feed.ETHUSD(ctx)
+	assert.ErrorContains(t, err, "429")
+}
+
+func TestHTTP_MissingField(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"ethereum": {"eur": 2900}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	_, err := NewHTTP(srv.URL, "ethereum.usd", time.Minute, nil, nil).ETHUSD(context.Background())
+	assert.ErrorContains(t, err, "field 'ethereum.usd' not found")
+}
@@ -0,0 +1,80 @@
+package price
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Feed provides the current price of ETH in USD.
+type Feed interface {
+	ETHUSD(ctx context.Context) (float64, error)
+}
+
+// Static is a fixed ETH price, useful for tests and for deployments without a price source.
+type Static float64
+
+// ETHUSD returns the fixed price.
+func (s Static) ETHUSD(ctx context.Context) (float64, error) {
+	return float64(s), nil
+}
+
+// File reads the ETH price from a local file containing a single number.
+// The file is re-read only when its modification time changes, so an external
+// job can keep it up to date.
+type File struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	price   float64
+}
+
+// NewFile returns a feed backed by the file at path.
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+// ETHUSD returns the price currently stored in the file.
+func (f *File) ETHUSD(ctx context.Context) (float64, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return 0, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.modTime.IsZero() && info.ModTime().Equal(f.modTime) {
+		return f.price, nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return 0, err
+	}
+	price, err := parse(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", f.path, err)
+	}
+
+	f.price = price
+	f.modTime = info.ModTime()
+	return price, nil
+}
+
+// parse reads a positive price.
+func parse(raw string) (float64, error) {
+	price, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid price '%s'", raw)
+	}
+	if price <= 0 {
+		return 0, fmt.Errorf("price must be positive, got %v", price)
+	}
+	return price, nil
+}
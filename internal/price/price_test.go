@@ -0,0 +1,44 @@
+package price
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatic(t *testing.T) {
+	price, err := Static(3150.5).ETHUSD(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3150.5, price)
+}
+
+func TestFile_ReloadsOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "eth-usd")
+	require.NoError(t, os.WriteFile(path, []byte("3000\n"), 0o600))
+	feed := NewFile(path)
+
+	price, err := feed.ETHUSD(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3000.0, price)
+
+	require.NoError(t, os.WriteFile(path, []byte("3500.25"), 0o600))
+	later := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(path, later, later))
+
+	price, err = feed.ETHUSD(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3500.25, price)
+}
+
+func TestFile_InvalidPrice(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "eth-usd")
+	require.NoError(t, os.WriteFile(path, []byte("-1"), 0o600))
+
+	_, err := NewFile(path).ETHUSD(context.Background())
+	assert.ErrorContains(t, err, "must be positive")
+}
@@ -0,0 +1,167 @@
+package sanctions
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/yermakovsa/eth-watcher/internal/logging"
+)
+
+// addressPattern finds Ethereum addresses anywhere in a CSV field, which also covers
+// exports such as the OFAC SDN list where addresses are embedded in remarks.
+var addressPattern = regexp.MustCompile(`0x[0-9a-fA-F]{40}`)
+
+// List is a set of flagged addresses loaded from local CSV or JSON files.
+// It is safe for concurrent use and can be reloaded while in use.
+type List struct {
+	paths  []string
+	logger *slog.Logger
+
+	mu      sync.RWMutex
+	entries map[string]string
+}
+
+// New creates an empty list backed by the given files. Call Reload to populate it.
+// A nil logger uses the default one.
+func New(paths []string, logger *slog.Logger) *List {
+	return &List{
+		paths:   paths,
+		logger:  logging.Component(logger, "sanctions"),
+		entries: make(map[string]string),
+	}
+}
+
+// Lookup reports whether an address is flagged, along with the reason from the source file.
+func (l *List) Lookup(addr string) (string, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	reason, ok := l.entries[strings.ToLower(addr)]
+	return reason, ok
+}
+
+// Len returns the number of flagged addresses.
+func (l *List) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.entries)
+}
+
+// Reload reads every source file and replaces the list. On error the previous list is kept.
+func (l *List) Reload() error {
+	entries := make(map[string]string)
+	for _, path := range l.paths {
+		if err := loadFile(path, entries); err != nil {
+			return fmt.Errorf("load %s: %w", path, err)
+		}
+	}
+
+	l.mu.Lock()
+	l.entries = entries
+	l.mu.Unlock()
+	return nil
+}
+
+// Run reloads the list every interval until ctx is cancelled.
+func (l *List) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := l.Reload(); err != nil {
+				l.logger.Error("Failed to refresh list, keeping previous entries", "error", err)
+				continue
+			}
+			l.logger.Info("Refreshed list", "addresses", l.Len())
+		}
+	}
+}
+
+func loadFile(path string, entries map[string]string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return loadJSON(f, entries)
+	}
+	return loadCSV(f, entries)
+}
+
+// loadCSV lists every address found in a row, using the row's first textual field as the reason.
+func loadCSV(r io.Reader, entries map[string]string) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var (
+			addresses []string
+			reason    string
+		)
+		for _, field := range record {
+			found := addressPattern.FindAllString(field, -1)
+			addresses = append(addresses, found...)
+			if reason == "" && len(found) == 0 && strings.IndexFunc(field, unicode.IsLetter) >= 0 {
+				reason = strings.TrimSpace(field)
+			}
+		}
+		for _, addr := range addresses {
+			entries[strings.ToLower(addr)] = reason
+		}
+	}
+}
+
+// loadJSON accepts an array of addresses or an array of {"address", "reason"} objects.
+func loadJSON(r io.Reader, entries map[string]string) error {
+	var raw []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return err
+	}
+
+	for _, item := range raw {
+		var addr string
+		if err := json.Unmarshal(item, &addr); err == nil {
+			entries[strings.ToLower(addr)] = ""
+			continue
+		}
+
+		var entry struct {
+			Address string `json:"address"`
+			Reason  string `json:"reason"`
+		}
+		if err := json.Unmarshal(item, &entry); err != nil {
+			return err
+		}
+		if entry.Address == "" {
+			return fmt.Errorf("entry without address: %s", item)
+		}
+		entries[strings.ToLower(entry.Address)] = entry.Reason
+	}
+	return nil
+}
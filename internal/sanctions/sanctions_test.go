@@ -0,0 +1,58 @@
+package sanctions
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	addrA = "0x098b716b8aaf21512996dc57eb0615e2383e2f96"
+	addrB = "0x8589427373d6d84e98730d7795d8f6f8731fda16"
+	addrC = "0x722122df12d4e14e13ac3b6895a86e84145b6967"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestReload_CSVAndJSON(t *testing.T) {
+	csvPath := writeFile(t, "sdn.csv",
+		`36,"LAZARUS GROUP",-0-,"Digital Currency Address - ETH 0x098B716B8Aaf21512996dC57EB0615e2383E2f96; Digital Currency Address - ETH `+addrB+`"`+"\n")
+	jsonPath := writeFile(t, "flagged.json", `["`+addrC+`", {"address": "0x0000000000000000000000000000000000000001", "reason": "test"}]`)
+
+	list := New([]string{csvPath, jsonPath}, nil)
+	require.NoError(t, list.Reload())
+
+	assert.Equal(t, 4, list.Len())
+
+	reason, ok := list.Lookup(addrA)
+	assert.True(t, ok)
+	assert.Equal(t, "LAZARUS GROUP", reason)
+
+	_, ok = list.Lookup("0x722122DF12D4E14E13AC3B6895A86E84145B6967")
+	assert.True(t, ok)
+
+	reason, _ = list.Lookup("0x0000000000000000000000000000000000000001")
+	assert.Equal(t, "test", reason)
+
+	_, ok = list.Lookup("0x0000000000000000000000000000000000000002")
+	assert.False(t, ok)
+}
+
+func TestReload_KeepsPreviousListOnError(t *testing.T) {
+	path := writeFile(t, "flagged.json", `["`+addrA+`"]`)
+	list := New([]string{path}, nil)
+	require.NoError(t, list.Reload())
+
+	require.NoError(t, os.WriteFile(path, []byte(`{not json`), 0o600))
+	assert.Error(t, list.Reload())
+
+	_, ok := list.Lookup(addrA)
+	assert.True(t, ok)
+}
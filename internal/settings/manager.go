@@ -0,0 +1,206 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/yermakovsa/eth-watcher/internal/aggregator"
+)
+
+// ErrNotMonitored is returned when removing a wallet that is not monitored.
+var ErrNotMonitored = errors.New("wallet is not monitored")
+
+// SaveError reports a change that was applied but could not be stored. It stays in
+// effect until the next restart.
+type SaveError struct {
+	Err error
+}
+
+func (e *SaveError) Error() string {
+	return fmt.Sprintf("change applied but not saved: %s", e.Err)
+}
+
+func (e *SaveError) Unwrap() error {
+	return e.Err
+}
+
+// Watcher changes the wallets the watcher subscribes to while it runs.
+type Watcher interface {
+	Wallets() map[aggregator.Direction][]string
+	AddWallet(direction aggregator.Direction, wallet string) error
+	RemoveWallet(direction aggregator.Direction, wallet string) error
+}
+
+// Aggregator changes the aggregation settings while the aggregator runs.
+type Aggregator interface {
+	Snapshot() aggregator.Snapshot
+	SetThresholds(direction aggregator.Direction, t aggregator.Thresholds) error
+	SetWalletThreshold(wallet string, eth float64) error
+	ClearWalletThreshold(wallet string)
+	AddNetWallet(wallet string)
+	RemoveNetWallet(wallet string)
+	SetRules(rules []aggregator.Rule)
+}
+
+// Manager applies runtime changes to the watcher and aggregator together and stores the
+// result. Changes are applied one at a time, whether they come from the API or the bot.
+type Manager struct {
+	mu         sync.Mutex
+	watcher    Watcher
+	aggregator Aggregator
+	store      *Store
+	// watchlist is compiled into rule expressions
+	watchlist []string
+	// rules are the rules to store, nil while those of the rules file are in effect
+	rules []aggregator.Rule
+}
+
+// ManagerOption configures optional Manager behaviour.
+type ManagerOption func(*Manager)
+
+// WithRules compiles replaced rules against watchlist. Stored rules loaded on startup
+// keep being stored with later changes; nil means the rules file is in effect.
+func WithRules(watchlist []string, stored []aggregator.Rule) ManagerOption {
+	return func(m *Manager) {
+		m.watchlist = watchlist
+		m.rules = stored
+	}
+}
+
+// NewManager creates a manager. Without a store, changes last until the next restart.
+func NewManager(w Watcher, agg Aggregator, store *Store, opts ...ManagerOption) *Manager {
+	m := &Manager{watcher: w, aggregator: agg, store: store}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// AddWallet starts monitoring a wallet in a direction.
+func (m *Manager) AddWallet(direction aggregator.Direction, wallet string) error {
+	wallet = strings.ToLower(wallet)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// The aggregator attributes net flows to the wallet, so it learns about it first
+	if direction == aggregator.Net {
+		m.aggregator.AddNetWallet(wallet)
+	}
+	if err := m.watcher.AddWallet(direction, wallet); err != nil {
+		if direction == aggregator.Net && !slices.Contains(m.watcher.Wallets()[aggregator.Net], wallet) {
+			m.aggregator.RemoveNetWallet(wallet)
+		}
+		return err
+	}
+	return m.save()
+}
+
+// RemoveWallet stops monitoring a wallet in the given directions, or in all of them when
+// none are given. It returns ErrNotMonitored when the wallet is in none of them.
+func (m *Manager) RemoveWallet(wallet string, directions ...aggregator.Direction) error {
+	wallet = strings.ToLower(wallet)
+	if len(directions) == 0 {
+		directions = []aggregator.Direction{aggregator.From, aggregator.To, aggregator.Net}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	monitored := m.watcher.Wallets()
+	removed := 0
+	for _, direction := range directions {
+		if !slices.Contains(monitored[direction], wallet) {
+			continue
+		}
+		if err := m.watcher.RemoveWallet(direction, wallet); err != nil {
+			// Directions removed before the failure stay removed, so they are saved too
+			if removed > 0 {
+				if err := m.save(); err != nil {
+					return err
+				}
+			}
+			return err
+		}
+		if direction == aggregator.Net {
+			m.aggregator.RemoveNetWallet(wallet)
+		}
+		removed++
+	}
+	if removed == 0 {
+		return ErrNotMonitored
+	}
+	return m.save()
+}
+
+// SetThresholds replaces the volume alert thresholds of a direction.
+func (m *Manager) SetThresholds(direction aggregator.Direction, t aggregator.Thresholds) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.aggregator.SetThresholds(direction, t); err != nil {
+		return err
+	}
+	return m.save()
+}
+
+// SetWalletThreshold gives a wallet its own ETH threshold in every direction.
+func (m *Manager) SetWalletThreshold(wallet string, eth float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.aggregator.SetWalletThreshold(wallet, eth); err != nil {
+		return err
+	}
+	return m.save()
+}
+
+// ClearWalletThreshold returns a wallet to the thresholds of its directions.
+func (m *Manager) ClearWalletThreshold(wallet string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.aggregator.ClearWalletThreshold(wallet)
+	return m.save()
+}
+
+// ReplaceRules compiles rules and puts them in effect in place of every current rule.
+func (m *Manager) ReplaceRules(rules []aggregator.Rule) error {
+	rules = append([]aggregator.Rule{}, rules...)
+	if err := aggregator.CompileRules(rules, m.watchlist); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.aggregator.SetRules(rules)
+	m.rules = rules
+	return m.save()
+}
+
+// save stores the current wallets, thresholds and rules. Must be called with m.mu held.
+func (m *Manager) save() error {
+	if m.store == nil {
+		return nil
+	}
+
+	snap := m.aggregator.Snapshot()
+	stored := Settings{
+		Wallets:          m.watcher.Wallets(),
+		Thresholds:       make(map[aggregator.Direction]Thresholds, len(snap.Thresholds)),
+		WalletThresholds: snap.WalletThresholds,
+		Rules:            m.rules,
+	}
+	for direction, t := range snap.Thresholds {
+		stored.Thresholds[direction] = Thresholds{ETH: t.ETH, USD: t.USD}
+	}
+
+	if err := m.store.Save(stored); err != nil {
+		return &SaveError{Err: err}
+	}
+	return nil
+}
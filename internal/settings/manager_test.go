@@ -0,0 +1,219 @@
+package settings
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yermakovsa/eth-watcher/internal/aggregator"
+)
+
+type MockWatcher struct {
+	wallets map[aggregator.Direction][]string
+	err     error
+}
+
+func (m *MockWatcher) Wallets() map[aggregator.Direction][]string {
+	return m.wallets
+}
+
+func (m *MockWatcher) AddWallet(direction aggregator.Direction, wallet string) error {
+	if m.err != nil {
+		return m.err
+	}
+	if !slices.Contains(m.wallets[direction], wallet) {
+		m.wallets[direction] = append(m.wallets[direction], wallet)
+	}
+	return nil
+}
+
+func (m *MockWatcher) RemoveWallet(direction aggregator.Direction, wallet string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.wallets[direction] = slices.DeleteFunc(m.wallets[direction], func(w string) bool { return w == wallet })
+	return nil
+}
+
+type MockAggregator struct {
+	snapshot   aggregator.Snapshot
+	netWallets map[string]bool
+	rules      []aggregator.Rule
+}
+
+func (m *MockAggregator) Snapshot() aggregator.Snapshot {
+	return m.snapshot
+}
+
+func (m *MockAggregator) SetThresholds(direction aggregator.Direction, t aggregator.Thresholds) error {
+	if t.ETH < 0 {
+		return errors.New("thresholds must not be negative")
+	}
+	m.snapshot.Thresholds[direction] = t
+	return nil
+}
+
+func (m *MockAggregator) SetWalletThreshold(wallet string, eth float64) error {
+	m.snapshot.WalletThresholds[wallet] = eth
+	return nil
+}
+
+func (m *MockAggregator) ClearWalletThreshold(wallet string) {
+	delete(m.snapshot.WalletThresholds, wallet)
+}
+
+func (m *MockAggregator) AddNetWallet(wallet string) {
+	m.netWallets[wallet] = true
+}
+
+func (m *MockAggregator) RemoveNetWallet(wallet string) {
+	delete(m.netWallets, wallet)
+}
+
+func (m *MockAggregator) SetRules(rules []aggregator.Rule) {
+	m.rules = rules
+}
+
+func newManager(t *testing.T) (*Manager, *MockWatcher, *MockAggregator, *Store) {
+	w := &MockWatcher{wallets: map[aggregator.Direction][]string{aggregator.From: {"0xabc"}}}
+	agg := &MockAggregator{
+		snapshot: aggregator.Snapshot{
+			Thresholds:       map[aggregator.Direction]aggregator.Thresholds{aggregator.From: {ETH: 10}},
+			WalletThresholds: map[string]float64{},
+		},
+		netWallets: map[string]bool{},
+	}
+	store := NewStore(filepath.Join(t.TempDir(), "settings.json"))
+	return NewManager(w, agg, store), w, agg, store
+}
+
+func TestManager_AddWallet(t *testing.T) {
+	m, w, agg, store := newManager(t)
+
+	require.NoError(t, m.AddWallet(aggregator.Net, "0xDEF"))
+	assert.Equal(t, []string{"0xdef"}, w.wallets[aggregator.Net])
+	assert.True(t, agg.netWallets["0xdef"])
+
+	stored, ok, err := store.Load()
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, []string{"0xabc"}, stored.Wallets[aggregator.From])
+	assert.Equal(t, []string{"0xdef"}, stored.Wallets[aggregator.Net])
+	assert.Equal(t, Thresholds{ETH: 10}, stored.Thresholds[aggregator.From])
+}
+
+func TestManager_AddWalletRollsBackAggregator(t *testing.T) {
+	m, w, agg, store := newManager(t)
+	w.err = errors.New("resubscribe: dial client: connection refused")
+
+	assert.EqualError(t, m.AddWallet(aggregator.Net, "0xdef"), "resubscribe: dial client: connection refused")
+	assert.Empty(t, agg.netWallets)
+
+	_, ok, err := store.Load()
+	require.NoError(t, err)
+	assert.False(t, ok, "failed changes are not stored")
+}
+
+func TestManager_RemoveWallet(t *testing.T) {
+	m, w, agg, store := newManager(t)
+	w.wallets[aggregator.Net] = []string{"0xabc"}
+	agg.netWallets["0xabc"] = true
+
+	require.NoError(t, m.RemoveWallet("0xABC", aggregator.Net))
+	assert.Empty(t, w.wallets[aggregator.Net])
+	assert.Empty(t, agg.netWallets)
+	assert.Equal(t, []string{"0xabc"}, w.wallets[aggregator.From])
+
+	require.NoError(t, m.RemoveWallet("0xabc"))
+	assert.Empty(t, w.wallets[aggregator.From])
+	assert.ErrorIs(t, m.RemoveWallet("0xabc"), ErrNotMonitored)
+
+	stored, _, err := store.Load()
+	require.NoError(t, err)
+	assert.Empty(t, stored.Wallets[aggregator.From])
+}
+
+func TestManager_Thresholds(t *testing.T) {
+	m, _, _, store := newManager(t)
+
+	require.NoError(t, m.SetThresholds(aggregator.To, aggregator.Thresholds{ETH: 2.5}))
+	assert.Error(t, m.SetThresholds(aggregator.To, aggregator.Thresholds{ETH: -1}))
+	require.NoError(t, m.SetWalletThreshold("0xabc", 25))
+
+	stored, _, err := store.Load()
+	require.NoError(t, err)
+	assert.Equal(t, Thresholds{ETH: 2.5}, stored.Thresholds[aggregator.To])
+	assert.Equal(t, map[string]float64{"0xabc": 25}, stored.WalletThresholds)
+
+	require.NoError(t, m.ClearWalletThreshold("0xabc"))
+	stored, _, err = store.Load()
+	require.NoError(t, err)
+	assert.Empty(t, stored.WalletThresholds)
+}
+
+func TestManager_ReplaceRules(t *testing.T) {
+	m, _, agg, store := newManager(t)
+
+	require.NoError(t, m.SetThresholds(aggregator.To, aggregator.Thresholds{ETH: 1}))
+	stored, _, err := store.Load()
+	require.NoError(t, err)
+	assert.Nil(t, stored.Rules, "the rules file stays in effect until rules are replaced")
+
+	err = m.ReplaceRules([]aggregator.Rule{{Name: "large", Condition: aggregator.Condition{Expr: "tx.value >"}}})
+	assert.ErrorContains(t, err, `rule "large"`)
+	assert.Nil(t, agg.rules, "invalid rules are not applied")
+
+	rules := []aggregator.Rule{{Name: "large", Condition: aggregator.Condition{Expr: "count >= 2"}}}
+	require.NoError(t, m.ReplaceRules(rules))
+	require.Len(t, agg.rules, 1)
+	assert.True(t, agg.rules[0].Condition.Match(aggregator.Stats{Count: 2}), "rules are compiled before they are applied")
+
+	stored, _, err = store.Load()
+	require.NoError(t, err)
+	require.Len(t, stored.Rules, 1)
+	assert.Equal(t, "count >= 2", stored.Rules[0].Condition.Expr)
+
+	require.NoError(t, m.ReplaceRules(nil))
+	stored, _, err = store.Load()
+	require.NoError(t, err)
+	assert.NotNil(t, stored.Rules, "removing every rule is stored too")
+	assert.Empty(t, stored.Rules)
+}
+
+func TestManager_KeepsStoredRules(t *testing.T) {
+	w := &MockWatcher{wallets: map[aggregator.Direction][]string{}}
+	agg := &MockAggregator{snapshot: aggregator.Snapshot{Thresholds: map[aggregator.Direction]aggregator.Thresholds{}}}
+	store := NewStore(filepath.Join(t.TempDir(), "settings.json"))
+	m := NewManager(w, agg, store, WithRules(nil, []aggregator.Rule{{Name: "large"}}))
+
+	require.NoError(t, m.SetThresholds(aggregator.From, aggregator.Thresholds{ETH: 5}))
+	stored, _, err := store.Load()
+	require.NoError(t, err)
+	require.Len(t, stored.Rules, 1)
+	assert.Equal(t, "large", stored.Rules[0].Name)
+}
+
+func TestManager_ReportsUnsavedChanges(t *testing.T) {
+	w := &MockWatcher{wallets: map[aggregator.Direction][]string{}}
+	agg := &MockAggregator{snapshot: aggregator.Snapshot{Thresholds: map[aggregator.Direction]aggregator.Thresholds{}}}
+	dir := filepath.Join(t.TempDir(), "missing")
+	m := NewManager(w, agg, NewStore(filepath.Join(dir, "settings.json")))
+
+	err := m.SetThresholds(aggregator.From, aggregator.Thresholds{ETH: 5})
+	var saveErr *SaveError
+	require.ErrorAs(t, err, &saveErr)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Equal(t, aggregator.Thresholds{ETH: 5}, agg.snapshot.Thresholds[aggregator.From], "the change stays applied")
+}
+
+func TestManager_WithoutStore(t *testing.T) {
+	w := &MockWatcher{wallets: map[aggregator.Direction][]string{}}
+	agg := &MockAggregator{netWallets: map[string]bool{}}
+
+	require.NoError(t, NewManager(w, agg, nil).AddWallet(aggregator.To, "0xabc"))
+	assert.Equal(t, []string{"0xabc"}, w.wallets[aggregator.To])
+}
@@ -0,0 +1,89 @@
+package settings
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/yermakovsa/eth-watcher/internal/aggregator"
+)
+
+// Settings are the monitored wallets, volume thresholds and alert rules changed at runtime
+// through the management API. Once stored they take precedence over the environment on startup.
+type Settings struct {
+	Wallets    map[aggregator.Direction][]string   `json:"wallets"`
+	Thresholds map[aggregator.Direction]Thresholds `json:"thresholds"`
+	// WalletThresholds replace the thresholds of every direction for single wallets, in ETH
+	WalletThresholds map[string]float64 `json:"wallet_thresholds,omitempty"`
+	// Rules replace those of the rules file once they have been changed at runtime. They are
+	// nil until then, while an empty list means every rule was removed.
+	Rules []aggregator.Rule `json:"rules"`
+}
+
+// Thresholds are the volume alert thresholds of a direction.
+type Thresholds struct {
+	ETH float64 `json:"eth"`
+	USD float64 `json:"usd,omitempty"`
+}
+
+// Store persists settings to a JSON file.
+type Store struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewStore creates a store backed by the file at path, which is created on the first save.
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Load reads the stored settings. It returns false when nothing has been stored yet.
+func (s *Store) Load() (Settings, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Settings{}, false, nil
+	}
+	if err != nil {
+		return Settings{}, false, err
+	}
+
+	var settings Settings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return Settings{}, false, fmt.Errorf("parse settings: %w", err)
+	}
+	return settings, true, nil
+}
+
+// Save replaces the stored settings. The file is written in full before it is renamed
+// into place, so a crash never leaves it truncated.
+func (s *Store) Save(settings Settings) error {
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
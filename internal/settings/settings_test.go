@@ -0,0 +1,49 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yermakovsa/eth-watcher/internal/aggregator"
+)
+
+func TestStore_SaveAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(filepath.Join(dir, "settings.json"))
+
+	_, ok, err := store.Load()
+	require.NoError(t, err)
+	assert.False(t, ok, "nothing is stored before the first save")
+
+	settings := Settings{
+		Wallets: map[aggregator.Direction][]string{
+			aggregator.From: {"0xabc"},
+			aggregator.Net:  {"0xdef"},
+		},
+		Thresholds: map[aggregator.Direction]Thresholds{
+			aggregator.From: {ETH: 10},
+			aggregator.Net:  {ETH: 20, USD: 50000},
+		},
+	}
+	require.NoError(t, store.Save(settings))
+
+	loaded, ok, err := NewStore(filepath.Join(dir, "settings.json")).Load()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, settings, loaded)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "the temporary file is renamed into place")
+}
+
+func TestStore_LoadInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+
+	_, _, err := NewStore(path).Load()
+	assert.ErrorContains(t, err, "parse settings")
+}
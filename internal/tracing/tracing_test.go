@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestNewProvider_ExportsToEndpoint(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		requests.Add(1)
+	}))
+	defer srv.Close()
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://127.0.0.1:1")
+	tp, err := NewProvider(context.Background(), srv.URL+"/v1/traces")
+	require.NoError(t, err)
+
+	_, span := Tracer(tp, "watcher").Start(context.Background(), "watcher.event")
+	span.End()
+	require.NoError(t, tp.Shutdown(context.Background()))
+
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestTracer_UsesGivenProvider(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	_, span := Tracer(tp, "aggregator").Start(context.Background(), "aggregator.process")
+	span.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "aggregator.process", spans[0].Name)
+	assert.Equal(t, "github.com/yermakovsa/eth-watcher/internal/aggregator", spans[0].InstrumentationScope.Name)
+}
+
+func TestTracer_DefaultsToGlobalProvider(t *testing.T) {
+	_, span := Tracer(nil, "watcher").Start(context.Background(), "watcher.event")
+	defer span.End()
+	assert.False(t, span.SpanContext().IsSampled(), "no provider installed")
+}
@@ -0,0 +1,146 @@
+package txexpr
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/file"
+	"github.com/expr-lang/expr/parser/lexer"
+	"github.com/expr-lang/expr/vm"
+	"github.com/yermakovsa/alchemyws"
+	"github.com/yermakovsa/eth-watcher/internal/ethrpc"
+)
+
+// units are the suffixes amount literals may carry, e.g. "100 gwei" or "1.5 ether".
+var units = map[string]struct{}{"wei": {}, "gwei": {}, "ether": {}, "eth": {}}
+
+// Tx is the transaction as seen by expressions. Amounts are in ETH.
+type Tx struct {
+	Hash        string  `expr:"hash"`
+	From        string  `expr:"from"`
+	To          string  `expr:"to"`
+	Value       float64 `expr:"value"`
+	Gas         uint64  `expr:"gas"`
+	GasPrice    float64 `expr:"gasPrice"`
+	Nonce       uint64  `expr:"nonce"`
+	Input       string  `expr:"input"`
+	Selector    string  `expr:"selector"`
+	BlockNumber uint64  `expr:"blockNumber"`
+}
+
+// Env is the typed environment every expression is compiled against.
+// Window fields are only populated when evaluated as an alert condition.
+type Env struct {
+	Tx            Tx       `expr:"tx"`
+	Direction     string   `expr:"direction"`
+	Wallet        string   `expr:"wallet"`
+	Watchlist     []string `expr:"watchlist"`
+	Total         float64  `expr:"total"`
+	Count         int      `expr:"count"`
+	PreviousTotal float64  `expr:"previousTotal"`
+
+	Wei   float64 `expr:"wei"`
+	Gwei  float64 `expr:"gwei"`
+	Ether float64 `expr:"ether"`
+	Eth   float64 `expr:"eth"`
+}
+
+// Program is a compiled, type-checked boolean expression.
+type Program struct {
+	source    string
+	program   *vm.Program
+	watchlist []string
+}
+
+// Compile parses and type checks an expression, which must evaluate to a bool.
+// Amount literals may carry a unit suffix ("100 gwei"); they are converted to ETH.
+func Compile(source string, watchlist []string) (*Program, error) {
+	program, err := expr.Compile(rewriteUnits(source), expr.Env(Env{}), expr.AsBool())
+	if err != nil {
+		return nil, fmt.Errorf("compile %q: %w", source, err)
+	}
+
+	normalized := make([]string, len(watchlist))
+	for i, addr := range watchlist {
+		normalized[i] = strings.ToLower(addr)
+	}
+
+	return &Program{source: source, program: program, watchlist: normalized}, nil
+}
+
+// rewriteUnits turns every number token followed by a unit into a product with the unit
+// constant, so "1e18 wei" becomes "(1e18 * wei)". Working on tokens leaves string
+// literals alone. Sources that fail to tokenize are returned as is for expr to report.
+func rewriteUnits(source string) string {
+	tokens, err := lexer.Lex(file.NewSource(source))
+	if err != nil {
+		return source
+	}
+
+	// Token locations count runes
+	runes := []rune(source)
+	var b strings.Builder
+	last := 0
+	for i := 0; i+1 < len(tokens); i++ {
+		num, unit := tokens[i], tokens[i+1]
+		if _, ok := units[unit.Value]; !ok || num.Kind != lexer.Number || unit.Kind != lexer.Identifier {
+			continue
+		}
+		b.WriteString(string(runes[last:num.From]))
+		fmt.Fprintf(&b, "(%s * %s)", string(runes[num.From:num.To]), unit.Value)
+		last = unit.To
+		i++
+	}
+	b.WriteString(string(runes[last:]))
+	return b.String()
+}
+
+// Eval runs the program against env, filling in the watchlist and unit constants.
+func (p *Program) Eval(env Env) (bool, error) {
+	env.Watchlist = p.watchlist
+	env.Wei = 1e-18
+	env.Gwei = 1e-9
+	env.Ether = 1
+	env.Eth = 1
+
+	out, err := expr.Run(p.program, env)
+	if err != nil {
+		return false, fmt.Errorf("eval %q: %w", p.source, err)
+	}
+	return out.(bool), nil
+}
+
+// String returns the source the program was compiled from.
+func (p *Program) String() string {
+	return p.source
+}
+
+// MarshalJSON encodes the program as its source expression.
+func (p *Program) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.source)
+}
+
+// NewTx converts a raw transaction into its expression representation.
+func NewTx(tx alchemyws.Transaction) Tx {
+	out := Tx{
+		Hash:  strings.ToLower(tx.Hash),
+		From:  strings.ToLower(tx.From),
+		To:    strings.ToLower(tx.To),
+		Input: strings.ToLower(tx.Input),
+	}
+	if v, err := ethrpc.ParseBig(tx.Value); err == nil {
+		out.Value = ethrpc.WeiToEth(v)
+	}
+	if v, err := ethrpc.ParseBig(tx.GasPrice); err == nil {
+		out.GasPrice = ethrpc.WeiToEth(v)
+	}
+	out.Gas, _ = ethrpc.ParseQuantity(tx.Gas)
+	out.Nonce, _ = ethrpc.ParseQuantity(tx.Nonce)
+	out.BlockNumber, _ = ethrpc.ParseQuantity(tx.BlockNumber)
+	if len(out.Input) >= 10 {
+		out.Selector = out.Input[:10]
+	}
+	return out
+}
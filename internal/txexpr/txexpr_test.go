@@ -0,0 +1,84 @@
+package txexpr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yermakovsa/alchemyws"
+)
+
+var sampleTx = alchemyws.Transaction{
+	Hash:        "0xhash",
+	From:        "0xABC",
+	To:          "0xDEF",
+	Value:       "0x2b5e3af16b1880000", // 50 ETH
+	GasPrice:    "0x2540be400",         // 10 gwei
+	Input:       "0xa9059cbb0000",
+	BlockNumber: "0x10",
+}
+
+func TestCompile_EvaluatesAgainstTransaction(t *testing.T) {
+	prog, err := Compile(`tx.value >= 50 && tx.to in watchlist && tx.gasPrice > 5 gwei`, []string{"0xDEF"})
+	require.NoError(t, err)
+
+	ok, err := prog.Eval(Env{Tx: NewTx(sampleTx)})
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	prog, err = Compile(`tx.gasPrice > 100 gwei`, nil)
+	require.NoError(t, err)
+
+	ok, err = prog.Eval(Env{Tx: NewTx(sampleTx)})
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestRewriteUnits(t *testing.T) {
+	tests := []struct{ source, want string }{
+		{`tx.gasPrice > 5 gwei`, `tx.gasPrice > (5 * gwei)`},
+		{`tx.value >= 1e18 wei`, `tx.value >= (1e18 * wei)`},
+		{`tx.value >= 1.5 ether && tx.value < 0x10 eth`, `tx.value >= (1.5 * ether) && tx.value < (0x10 * eth)`},
+		{`tx.hash == "5 eth" && tx.value > 5 eth`, `tx.hash == "5 eth" && tx.value > (5 * eth)`},
+		{`wallet == "é" && total > 1 eth`, `wallet == "é" && total > (1 * eth)`},
+		{`tx.value > eth`, `tx.value > eth`},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, rewriteUnits(tt.source), tt.source)
+	}
+
+	prog, err := Compile(`tx.value > 49e18 wei && tx.hash != "50 eth"`, nil)
+	require.NoError(t, err)
+	ok, err := prog.Eval(Env{Tx: NewTx(sampleTx)})
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestCompile_WindowFields(t *testing.T) {
+	prog, err := Compile(`direction == "from" && count >= 3 && total > 2 * previousTotal`, nil)
+	require.NoError(t, err)
+
+	ok, err := prog.Eval(Env{Direction: "from", Count: 3, Total: 10, PreviousTotal: 4})
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestCompile_TypeErrors(t *testing.T) {
+	_, err := Compile(`tx.value`, nil)
+	assert.Error(t, err, "non-boolean expressions must be rejected")
+
+	_, err = Compile(`tx.unknown > 1`, nil)
+	assert.Error(t, err)
+
+	_, err = Compile(`tx.value > "50"`, nil)
+	assert.Error(t, err)
+}
+
+func TestNewTx(t *testing.T) {
+	tx := NewTx(sampleTx)
+	assert.Equal(t, "0xabc", tx.From)
+	assert.InDelta(t, 50.0, tx.Value, 1e-9)
+	assert.InDelta(t, 10e-9, tx.GasPrice, 1e-15)
+	assert.Equal(t, "0xa9059cbb", tx.Selector)
+	assert.Equal(t, uint64(16), tx.BlockNumber)
+}
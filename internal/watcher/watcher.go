@@ -2,11 +2,33 @@ package watcher
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/yermakovsa/alchemyws"
 	"github.com/yermakovsa/eth-watcher/internal/aggregator"
+	"github.com/yermakovsa/eth-watcher/internal/contract"
+	"github.com/yermakovsa/eth-watcher/internal/ethrpc"
+	"github.com/yermakovsa/eth-watcher/internal/logging"
+	"github.com/yermakovsa/eth-watcher/internal/metrics"
+	"github.com/yermakovsa/eth-watcher/internal/tracing"
+	"github.com/yermakovsa/eth-watcher/internal/txexpr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	reconnectMinBackoff = 1 * time.Second
+	reconnectMaxBackoff = 1 * time.Minute
+
+	// heartbeatInterval is how often the watch loop proves it is alive while no events arrive.
+	heartbeatInterval = 10 * time.Second
 )
 
 type AlchemyClient interface {
@@ -14,84 +36,701 @@ type AlchemyClient interface {
 	Close() error
 }
 
+// Aggregator receives matched transactions. The context carries the span of the event.
 type Aggregator interface {
-	Process(tx alchemyws.MinedTxEvent, direction aggregator.Direction)
+	Process(ctx context.Context, tx alchemyws.MinedTxEvent, direction aggregator.Direction)
+	ProcessCall(ctx context.Context, tx alchemyws.MinedTxEvent, call contract.Call)
+	ProcessInternal(ctx context.Context, tx alchemyws.MinedTxEvent, direction aggregator.Direction)
+}
+
+// Tracer lists the value transfers made by contracts while executing a transaction.
+type Tracer interface {
+	InternalTransfers(ctx context.Context, hash string) ([]ethrpc.InternalTransfer, error)
+}
+
+// BlockFetcher loads full blocks over JSON-RPC, used to replay blocks missed while disconnected.
+type BlockFetcher interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+	BlockByNumber(ctx context.Context, number uint64) (*ethrpc.Block, error)
+}
+
+// Dialer creates a fresh client connection when the subscription stream is lost.
+type Dialer func() (AlchemyClient, error)
+
+// Option configures optional Watcher behaviour.
+type Option func(*Watcher)
+
+// WithDialer lets the watcher reconnect with a new client when the event stream closes.
+func WithDialer(dial Dialer) Option {
+	return func(w *Watcher) {
+		w.dial = dial
+	}
+}
+
+// WithCatchUp enables replaying blocks missed during a reconnect, up to maxBlocks per gap.
+// The chain head is polled so that gaps are found even for wallets without recent activity.
+func WithCatchUp(fetcher BlockFetcher, maxBlocks uint64) Option {
+	return func(w *Watcher) {
+		w.blocks = fetcher
+		w.maxCatchUp = maxBlocks
+	}
+}
+
+// WithNetWallets monitors wallets in the net-flow direction, covering both inflows and outflows.
+func WithNetWallets(wallets []string) Option {
+	return func(w *Watcher) {
+		w.walletsNet = toSet(wallets)
+	}
+}
+
+// WithFilter drops transactions the expression does not match before they reach the aggregator.
+func WithFilter(program *txexpr.Program) Option {
+	return func(w *Watcher) {
+		w.filter = program
+	}
+}
+
+// WithContracts watches every transaction sent to the matcher's contracts and reports matching calls.
+func WithContracts(contracts *contract.Matcher) Option {
+	return func(w *Watcher) {
+		w.contracts = contracts
+	}
+}
+
+// NonceTracker follows the nonces of transactions sent by the wallets it tracks.
+type NonceTracker interface {
+	Wallets() []string
+	Mined(ctx context.Context, wallet string, nonce uint64, hash string)
+}
+
+// WithNonces reports the nonce of every mined transaction sent by the tracker's wallets.
+func WithNonces(tracker NonceTracker) Option {
+	return func(w *Watcher) {
+		w.nonces = tracker
+	}
+}
+
+// WithMetrics records event and reconnect metrics.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(w *Watcher) {
+		w.metrics = m
+	}
+}
+
+// WithLogger sets the logger used instead of the default one.
+func WithLogger(logger *slog.Logger) Option {
+	return func(w *Watcher) {
+		w.logger = logger
+	}
+}
+
+// WithTracerProvider records spans with tp instead of the global tracer provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(w *Watcher) {
+		w.tracerProvider = tp
+	}
+}
+
+// WithTracer traces contract calls involving monitored wallets and aggregates the
+// internal value transfers they make. Wallets are subscribed on both sides so that
+// calls into a contract wallet are seen even when only its outflows are monitored.
+func WithTracer(tracer Tracer) Option {
+	return func(w *Watcher) {
+		w.tracer = tracer
+	}
 }
 
 type Watcher struct {
-	client      AlchemyClient
-	aggregator  Aggregator
+	mu         sync.Mutex
+	client     AlchemyClient
+	dial       Dialer
+	blocks     BlockFetcher
+	maxCatchUp uint64
+	// lastBlock is the latest block whose transactions are known to have been received,
+	// from matched events or from the head polled on the previous heartbeat
+	lastBlock uint64
+	// head is the chain head polled on the latest heartbeat
+	head uint64
+	// replayedTo is the latest block replayed by catch-up, whose events on the new stream are dropped
+	replayedTo uint64
+	// delivered holds the hashes of the transactions the stream delivered in lastBlock. The stream
+	// may have dropped before delivering the rest, so catch-up replays lastBlock without them.
+	delivered  map[string]uint64
+	aggregator Aggregator
+	// walletsMu guards the wallet sets, which can change while the watcher runs
+	walletsMu   sync.RWMutex
 	walletsFrom map[string]struct{}
 	walletsTo   map[string]struct{}
-	ctx         context.Context
-	cancel      context.CancelFunc
+	walletsNet  map[string]struct{}
+	// changes asks the watch loop to resubscribe after the wallets changed
+	changes   chan chan error
+	filter    *txexpr.Program
+	contracts *contract.Matcher
+	tracer    Tracer
+	nonces    NonceTracker
+	metrics   *metrics.Metrics
+	logger    *slog.Logger
+	// tracerProvider and spans record OpenTelemetry spans, unrelated to the transaction tracer
+	tracerProvider trace.TracerProvider
+	spans          trace.Tracer
+	ctx            context.Context
+	cancel         context.CancelFunc
+
+	// Health state, read by the HTTP probes. Times are Unix nanoseconds.
+	subscribed atomic.Bool
+	lastEvent  atomic.Int64 // latest event, or the latest subscription if more recent
+	heartbeat  atomic.Int64
 }
 
 // NewWatcher initializes a new transaction watcher
-func NewWatcher(ctx context.Context, client AlchemyClient, from []string, to []string, aggregator Aggregator) *Watcher {
+func NewWatcher(ctx context.Context, client AlchemyClient, from []string, to []string, aggregator Aggregator, opts ...Option) *Watcher {
 	w := &Watcher{
 		client:      client,
 		aggregator:  aggregator,
 		walletsFrom: toSet(from),
 		walletsTo:   toSet(to),
+		walletsNet:  map[string]struct{}{},
+		changes:     make(chan chan error),
+		delivered:   make(map[string]uint64),
 	}
 	w.ctx, w.cancel = context.WithCancel(ctx)
+	for _, opt := range opts {
+		opt(w)
+	}
+	w.logger = logging.Component(w.logger, "watcher")
+	w.spans = tracing.Tracer(w.tracerProvider, "watcher")
 	return w
 }
 
 // Start begins watching for mined transactions
 func (w *Watcher) Start() error {
+	events, err := w.subscribe()
+	if err != nil {
+		return err
+	}
+
+	w.logger.Info("Started transaction watcher")
+
+	if w.blocks != nil {
+		if head, err := w.blocks.BlockNumber(w.ctx); err == nil {
+			w.lastBlock, w.head = head, head
+		} else {
+			w.logger.Warn("Failed to read head block, blocks missed before the first event will not be replayed", "error", err)
+		}
+	}
+
+	w.beat()
+	go w.watch(events)
+
+	return nil
+}
+
+// Stop halts the watcher
+func (w *Watcher) Stop() {
+	w.logger.Info("Stopping watcher")
+	w.cancel()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_ = w.client.Close()
+}
+
+func (w *Watcher) subscribe() (<-chan alchemyws.MinedTxEvent, error) {
+	w.mu.Lock()
+	client := w.client
+	w.mu.Unlock()
+
+	return w.subscribeWith(client)
+}
+
+// subscribeWith subscribes client to the transactions of every monitored wallet and contract.
+func (w *Watcher) subscribeWith(client AlchemyClient) (<-chan alchemyws.MinedTxEvent, error) {
 	var filters []alchemyws.AddressFilter
+	seen := make(map[alchemyws.AddressFilter]struct{})
+	add := func(f alchemyws.AddressFilter) {
+		if _, ok := seen[f]; !ok {
+			seen[f] = struct{}{}
+			filters = append(filters, f)
+		}
+	}
+
+	w.walletsMu.RLock()
 	for wallet := range w.walletsFrom {
-		filters = append(filters, alchemyws.AddressFilter{From: wallet})
+		add(alchemyws.AddressFilter{From: wallet})
+		if w.tracer != nil {
+			add(alchemyws.AddressFilter{To: wallet})
+		}
 	}
 	for wallet := range w.walletsTo {
-		filters = append(filters, alchemyws.AddressFilter{To: wallet})
+		add(alchemyws.AddressFilter{To: wallet})
+		if w.tracer != nil {
+			add(alchemyws.AddressFilter{From: wallet})
+		}
+	}
+	for wallet := range w.walletsNet {
+		add(alchemyws.AddressFilter{From: wallet})
+		add(alchemyws.AddressFilter{To: wallet})
+	}
+	w.walletsMu.RUnlock()
+	if w.contracts != nil {
+		for _, addr := range w.contracts.Addresses() {
+			add(alchemyws.AddressFilter{To: addr})
+		}
+	}
+	if w.nonces != nil {
+		for _, wallet := range w.nonces.Wallets() {
+			add(alchemyws.AddressFilter{From: wallet})
+		}
 	}
 
-	events, err := w.client.SubscribeMined(alchemyws.MinedTxOptions{
+	events, err := client.SubscribeMined(alchemyws.MinedTxOptions{
 		Addresses:      filters,
 		IncludeRemoved: false,
 		HashesOnly:     false,
 	})
 	if err != nil {
+		return nil, err
+	}
+	w.lastEvent.Store(time.Now().UnixNano())
+	w.subscribed.Store(true)
+	return events, nil
+}
+
+// Wallets returns the monitored wallets of each direction, sorted by address.
+func (w *Watcher) Wallets() map[aggregator.Direction][]string {
+	w.walletsMu.RLock()
+	defer w.walletsMu.RUnlock()
+
+	return map[aggregator.Direction][]string{
+		aggregator.From: sortedKeys(w.walletsFrom),
+		aggregator.To:   sortedKeys(w.walletsTo),
+		aggregator.Net:  sortedKeys(w.walletsNet),
+	}
+}
+
+// AddWallet starts monitoring a wallet in a direction. Once the watcher has started, the
+// subscription is replaced by one that includes the wallet, which requires a dialer.
+// Adding a wallet that is already monitored does nothing.
+func (w *Watcher) AddWallet(direction aggregator.Direction, wallet string) error {
+	return w.updateWallets(direction, wallet, true)
+}
+
+// RemoveWallet stops monitoring a wallet in a direction, resubscribing like AddWallet.
+// Removing a wallet that is not monitored does nothing.
+func (w *Watcher) RemoveWallet(direction aggregator.Direction, wallet string) error {
+	return w.updateWallets(direction, wallet, false)
+}
+
+// updateWallets adds or removes a wallet and resubscribes, restoring the previous
+// wallets when the new subscription cannot be established.
+func (w *Watcher) updateWallets(direction aggregator.Direction, wallet string, add bool) error {
+	wallet = strings.ToLower(wallet)
+	started := w.heartbeat.Load() != 0
+	if started && w.dial == nil {
+		return errors.New("changing wallets at runtime requires a dialer")
+	}
+
+	changed, err := w.setWallet(direction, wallet, add)
+	if err != nil || !changed || !started {
 		return err
 	}
 
-	log.Println("[Watcher] Started transaction watcher")
+	reply := make(chan error, 1)
+	select {
+	case w.changes <- reply:
+		err = <-reply
+	case <-w.ctx.Done():
+		err = errors.New("watcher stopped")
+	}
+	if err != nil {
+		_, _ = w.setWallet(direction, wallet, !add)
+		return fmt.Errorf("resubscribe: %w", err)
+	}
+
+	w.logger.Info("Updated monitored wallets", "wallet", wallet, "direction", direction, "monitored", add)
+	return nil
+}
 
-	go w.watch(events)
+// setWallet adds or removes a wallet from the set of a direction, reporting whether it changed.
+func (w *Watcher) setWallet(direction aggregator.Direction, wallet string, add bool) (bool, error) {
+	w.walletsMu.Lock()
+	defer w.walletsMu.Unlock()
 
+	var set map[string]struct{}
+	switch direction {
+	case aggregator.From:
+		set = w.walletsFrom
+	case aggregator.To:
+		set = w.walletsTo
+	case aggregator.Net:
+		set = w.walletsNet
+	default:
+		return false, fmt.Errorf("unknown direction %q", direction)
+	}
+
+	if _, ok := set[wallet]; ok == add {
+		return false, nil
+	}
+	if add {
+		set[wallet] = struct{}{}
+	} else {
+		delete(set, wallet)
+	}
+	return true, nil
+}
+
+// Alive reports an error when the watch loop has not made progress within maxStall,
+// meaning it is blocked rather than waiting for events. maxStall must exceed the
+// heartbeat interval and the longest reconnect backoff.
+func (w *Watcher) Alive(maxStall time.Duration) error {
+	beat := w.heartbeat.Load()
+	if beat == 0 {
+		return errors.New("watcher not started")
+	}
+	if stalled := time.Now().Sub(time.Unix(0, beat)); stalled > maxStall {
+		return fmt.Errorf("watch loop stalled for %s", stalled.Round(time.Second))
+	}
 	return nil
 }
 
-// Stop halts the watcher
-func (w *Watcher) Stop() {
-	log.Println("[Watcher] Stopping watcher")
-	w.cancel()
-	_ = w.client.Close()
+// Ready reports an error unless the subscription is active and, when maxEventAge is
+// positive, an event arrived or the subscription was established within maxEventAge.
+func (w *Watcher) Ready(maxEventAge time.Duration) error {
+	if !w.subscribed.Load() {
+		return errors.New("not subscribed")
+	}
+	if maxEventAge <= 0 {
+		return nil
+	}
+	if age := time.Now().Sub(time.Unix(0, w.lastEvent.Load())); age > maxEventAge {
+		return fmt.Errorf("no event for %s", age.Round(time.Second))
+	}
+	return nil
+}
+
+// beat records that the watch loop is making progress.
+func (w *Watcher) beat() {
+	w.heartbeat.Store(time.Now().UnixNano())
 }
 
 func (w *Watcher) watch(events <-chan alchemyws.MinedTxEvent) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
 	for {
+		w.beat()
 		select {
 		case <-w.ctx.Done():
-			log.Println("[Watcher] Shutdown signal received")
+			w.logger.Info("Shutdown signal received")
 			return
-		case event := <-events:
-			from := strings.ToLower(event.Transaction.From)
-			to := strings.ToLower(event.Transaction.To)
+		case <-ticker.C:
+			w.pollHead()
+		case reply := <-w.changes:
+			next, err := w.resubscribe()
+			reply <- err
+			if err == nil {
+				events = next
+				// Events left unread on the old stream are replayed by catch-up
+				w.catchUpToHead()
+			}
+		case event, ok := <-events:
+			if !ok {
+				w.logger.Warn("Event stream closed, reconnecting", "block", w.lastBlock)
+				w.subscribed.Store(false)
+				if events = w.reconnect(); events == nil {
+					return
+				}
+				w.catchUpToHead()
+				continue
+			}
+			w.metrics.EventArrived()
+			w.lastEvent.Store(time.Now().UnixNano())
+
+			block, err := ethrpc.ParseQuantity(event.Transaction.BlockNumber)
+			w.logger.Debug("Received transaction", "tx_hash", event.Transaction.Hash, "block", block)
+			if err == nil {
+				if block <= w.replayedTo {
+					w.logger.Debug("Dropping transaction already replayed by catch-up", "tx_hash", event.Transaction.Hash, "block", block)
+					continue
+				}
+				w.advance(block)
+				w.delivered[event.Transaction.Hash] = block
+			}
+
+			w.dispatch(w.ctx, event, true)
+		}
+	}
+}
+
+// dispatch hands the event to the aggregator for every monitored side of the transfer,
+// within a span that the aggregator's spans are children of.
+func (w *Watcher) dispatch(ctx context.Context, event alchemyws.MinedTxEvent, async bool) {
+	ctx, span := w.spans.Start(ctx, "watcher.event", trace.WithAttributes(
+		attribute.String("tx_hash", event.Transaction.Hash),
+		attribute.String("block", event.Transaction.BlockNumber),
+		attribute.Bool("catch_up", !async),
+	))
+	defer span.End()
+
+	if w.nonces != nil {
+		if nonce, err := ethrpc.ParseQuantity(event.Transaction.Nonce); err == nil {
+			w.nonces.Mined(ctx, event.Transaction.From, nonce, event.Transaction.Hash)
+		}
+	}
+
+	process := w.aggregator.Process
+	if async {
+		process = func(ctx context.Context, tx alchemyws.MinedTxEvent, direction aggregator.Direction) {
+			go w.aggregator.Process(ctx, tx, direction)
+		}
+	}
+	w.route(ctx, event, process)
+
+	if w.contracts != nil {
+		if call, ok := w.contracts.Match(event.Transaction); ok {
+			w.metrics.EventMatched("contract")
+			if call.DecodeErr != nil {
+				w.logger.Warn("Failed to decode contract call", "tx_hash", event.Transaction.Hash, "signature", call.Signature, "error", call.DecodeErr)
+			}
+			if async {
+				go w.aggregator.ProcessCall(ctx, event, call)
+			} else {
+				w.aggregator.ProcessCall(ctx, event, call)
+			}
+		}
+	}
+
+	if w.tracer != nil && isContractCall(event.Transaction) {
+		if async {
+			go w.trace(ctx, event)
+		} else {
+			w.trace(ctx, event)
+		}
+	}
+}
+
+// route calls process once for every monitored direction of the transfer.
+func (w *Watcher) route(ctx context.Context, event alchemyws.MinedTxEvent, process func(context.Context, alchemyws.MinedTxEvent, aggregator.Direction)) {
+	from := strings.ToLower(event.Transaction.From)
+	to := strings.ToLower(event.Transaction.To)
+
+	w.walletsMu.RLock()
+	_, isFrom := w.walletsFrom[from]
+	_, isTo := w.walletsTo[to]
+	_, netFrom := w.walletsNet[from]
+	_, netTo := w.walletsNet[to]
+	w.walletsMu.RUnlock()
+
+	if isFrom {
+		w.metrics.EventReceived(string(aggregator.From))
+		if w.accept(event, aggregator.From, from) {
+			w.metrics.EventMatched(string(aggregator.From))
+			process(ctx, event, aggregator.From)
+		}
+	}
+	if isTo {
+		w.metrics.EventReceived(string(aggregator.To))
+		if w.accept(event, aggregator.To, to) {
+			w.metrics.EventMatched(string(aggregator.To))
+			process(ctx, event, aggregator.To)
+		}
+	}
+
+	// Net flow is attributed to both sides by the aggregator, so dispatch it once
+	if netFrom || netTo {
+		w.metrics.EventReceived(string(aggregator.Net))
+		if netFrom && w.accept(event, aggregator.Net, from) || netTo && w.accept(event, aggregator.Net, to) {
+			w.metrics.EventMatched(string(aggregator.Net))
+			process(ctx, event, aggregator.Net)
+		}
+	}
+}
+
+// trace routes the internal value transfers of a transaction as transfers of their own.
+func (w *Watcher) trace(ctx context.Context, event alchemyws.MinedTxEvent) {
+	transfers, err := w.tracer.InternalTransfers(ctx, event.Transaction.Hash)
+	if err != nil {
+		w.logger.Error("Failed to trace transaction", "tx_hash", event.Transaction.Hash, "error", err)
+		return
+	}
+	for _, t := range transfers {
+		internal := event
+		internal.Transaction.From = t.From
+		internal.Transaction.To = t.To
+		internal.Transaction.Value = t.Value
+		internal.Transaction.Input = "0x"
+		w.route(ctx, internal, w.aggregator.ProcessInternal)
+	}
+}
+
+// accept evaluates the configured filter for one monitored side of the transaction.
+func (w *Watcher) accept(event alchemyws.MinedTxEvent, direction aggregator.Direction, wallet string) bool {
+	if w.filter == nil {
+		return true
+	}
+	ok, err := w.filter.Eval(txexpr.Env{
+		Tx:        txexpr.NewTx(event.Transaction),
+		Direction: string(direction),
+		Wallet:    wallet,
+	})
+	if err != nil {
+		w.logger.Error("Filter failed", "tx_hash", event.Transaction.Hash, "wallet", wallet, "direction", direction, "error", err)
+	}
+	return ok
+}
 
-			if _, ok := w.walletsFrom[from]; ok {
-				go w.aggregator.Process(event, aggregator.From)
+// reconnect re-establishes the subscription with exponential backoff.
+// It returns nil once the watcher is stopped.
+func (w *Watcher) reconnect() <-chan alchemyws.MinedTxEvent {
+	backoff := reconnectMinBackoff
+	for {
+		w.beat()
+		if w.dial != nil {
+			client, err := w.dial()
+			if err == nil {
+				w.mu.Lock()
+				_ = w.client.Close()
+				w.client = client
+				w.mu.Unlock()
+			} else {
+				w.logger.Error("Failed to dial client", "error", err)
 			}
-			if _, ok := w.walletsTo[to]; ok {
-				go w.aggregator.Process(event, aggregator.To)
+		}
+
+		if w.ctx.Err() == nil {
+			events, err := w.subscribe()
+			if err == nil {
+				w.logger.Info("Resubscribed to mined transactions")
+				w.metrics.Reconnected()
+				return events
+			}
+			w.logger.Error("Failed to resubscribe", "error", err, "retry_in", backoff)
+		}
+
+		select {
+		case <-w.ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, reconnectMaxBackoff)
+	}
+}
+
+// resubscribe replaces the subscription with one on a new client covering the current wallets.
+// The old client is closed only once the new subscription is established.
+func (w *Watcher) resubscribe() (<-chan alchemyws.MinedTxEvent, error) {
+	client, err := w.dial()
+	if err != nil {
+		return nil, fmt.Errorf("dial client: %w", err)
+	}
+	events, err := w.subscribeWith(client)
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+
+	w.mu.Lock()
+	old := w.client
+	w.client = client
+	w.mu.Unlock()
+	_ = old.Close()
+
+	w.logger.Info("Resubscribed with updated wallets")
+	return events, nil
+}
+
+// pollHead advances the last received block to the head polled on the previous heartbeat,
+// whose transactions have had a full interval to arrive on the stream.
+func (w *Watcher) pollHead() {
+	if w.blocks == nil || !w.subscribed.Load() {
+		return
+	}
+	head, err := w.blocks.BlockNumber(w.ctx)
+	if err != nil {
+		w.logger.Warn("Failed to read head block", "error", err)
+		return
+	}
+	w.advance(w.head)
+	w.head = head
+}
+
+// advance moves the last received block forward, forgetting the transactions delivered
+// in the blocks before it.
+func (w *Watcher) advance(block uint64) {
+	if block <= w.lastBlock {
+		return
+	}
+	w.lastBlock = block
+	for hash, n := range w.delivered {
+		if n < block {
+			delete(w.delivered, hash)
+		}
+	}
+}
+
+// catchUpToHead replays the blocks mined since the last received block, right after the
+// stream is resumed, so that gaps are closed without waiting for the next matching event.
+// The last received block is replayed too when the stream delivered part of it.
+func (w *Watcher) catchUpToHead() {
+	if w.blocks == nil {
+		w.logger.Warn("Stream resumed with catch-up disabled, transactions mined meanwhile are not replayed", "block", w.lastBlock)
+		return
+	}
+	from := w.lastBlock + 1
+	if len(w.delivered) > 0 {
+		from = w.lastBlock
+	}
+	head, err := w.blocks.BlockNumber(w.ctx)
+	if err != nil {
+		w.logger.Error("Failed to read head block, missed blocks are not replayed", "from_block", from, "error", err)
+		return
+	}
+	if w.lastBlock > 0 && head >= from {
+		w.catchUp(from, head)
+	}
+	if head >= w.lastBlock {
+		clear(w.delivered)
+	}
+	w.lastBlock = max(w.lastBlock, head)
+	w.head = head
+	w.replayedTo = head
+}
+
+// catchUp replays the blocks in [from, to] that were mined while the stream was down,
+// oldest first. Blocks past the catch-up limit are skipped.
+func (w *Watcher) catchUp(from, to uint64) {
+	if gap := to - from + 1; w.maxCatchUp > 0 && gap > w.maxCatchUp {
+		w.logger.Error("Gap exceeds catch-up limit, transactions in skipped blocks are not replayed",
+			"gap", gap, "limit", w.maxCatchUp, "skipped_from_block", from+w.maxCatchUp, "skipped_to_block", to)
+		to = from + w.maxCatchUp - 1
+	}
+
+	w.logger.Info("Catching up on missed blocks", "from_block", from, "to_block", to)
+	for n := from; n <= to; n++ {
+		if w.ctx.Err() != nil {
+			return
+		}
+		w.beat()
+		block, err := w.blocks.BlockByNumber(w.ctx, n)
+		if err != nil {
+			w.logger.Error("Failed to fetch block", "block", n, "error", err)
+			continue
+		}
+		for _, tx := range block.Transactions {
+			if _, ok := w.delivered[tx.Hash]; ok {
+				continue
 			}
+			w.dispatch(w.ctx, alchemyws.MinedTxEvent{Transaction: tx, Hash: tx.Hash}, false)
 		}
 	}
 }
 
+// isContractCall reports whether the transaction carries calldata, the only case in which
+// a contract can move value on its behalf.
+func isContractCall(tx alchemyws.Transaction) bool {
+	return tx.Input != "" && tx.Input != "0x"
+}
+
 // toSet converts a slice of wallet addresses to a normalized set (map for fast lookup)
 func toSet(addresses []string) map[string]struct{} {
 	set := make(map[string]struct{}, len(addresses))
@@ -100,3 +739,13 @@ func toSet(addresses []string) map[string]struct{} {
 	}
 	return set
 }
+
+// sortedKeys returns the addresses of a wallet set in ascending order.
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
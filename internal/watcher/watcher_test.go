@@ -3,13 +3,25 @@ package watcher_test
 import (
 	"context"
 	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/yermakovsa/alchemyws"
 	"github.com/yermakovsa/eth-watcher/internal/aggregator"
+	"github.com/yermakovsa/eth-watcher/internal/contract"
+	"github.com/yermakovsa/eth-watcher/internal/ethrpc"
+	"github.com/yermakovsa/eth-watcher/internal/notifier"
+	"github.com/yermakovsa/eth-watcher/internal/txexpr"
 	"github.com/yermakovsa/eth-watcher/internal/watcher"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 type MockAlchemyClient struct {
@@ -25,15 +37,29 @@ func (m *MockAlchemyClient) Close() error {
 }
 
 type MockAggregator struct {
-	ProcessFunc func(event alchemyws.MinedTxEvent, direction aggregator.Direction)
+	ProcessFunc         func(event alchemyws.MinedTxEvent, direction aggregator.Direction)
+	ProcessCallFunc     func(event alchemyws.MinedTxEvent, call contract.Call)
+	ProcessInternalFunc func(event alchemyws.MinedTxEvent, direction aggregator.Direction)
 }
 
-func (m *MockAggregator) Process(event alchemyws.MinedTxEvent, direction aggregator.Direction) {
+func (m *MockAggregator) Process(ctx context.Context, event alchemyws.MinedTxEvent, direction aggregator.Direction) {
 	if m.ProcessFunc != nil {
 		m.ProcessFunc(event, direction)
 	}
 }
 
+func (m *MockAggregator) ProcessCall(ctx context.Context, event alchemyws.MinedTxEvent, call contract.Call) {
+	if m.ProcessCallFunc != nil {
+		m.ProcessCallFunc(event, call)
+	}
+}
+
+func (m *MockAggregator) ProcessInternal(ctx context.Context, event alchemyws.MinedTxEvent, direction aggregator.Direction) {
+	if m.ProcessInternalFunc != nil {
+		m.ProcessInternalFunc(event, direction)
+	}
+}
+
 func TestWatcher_Start_ProcessesFromWalletEventAndStops(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -176,3 +202,640 @@ func TestWatcher_Stop_ClosesClient(t *testing.T) {
 
 	assert.True(t, closed, "expected client to be closed on Stop()")
 }
+
+type MockBlockFetcher struct {
+	blocks map[uint64]*ethrpc.Block
+	// heads are returned by successive BlockNumber calls, repeating the last one
+	heads   []uint64
+	fetched []uint64
+}
+
+func (m *MockBlockFetcher) BlockNumber(ctx context.Context) (uint64, error) {
+	head := m.heads[0]
+	if len(m.heads) > 1 {
+		m.heads = m.heads[1:]
+	}
+	return head, nil
+}
+
+func (m *MockBlockFetcher) BlockByNumber(ctx context.Context, number uint64) (*ethrpc.Block, error) {
+	m.fetched = append(m.fetched, number)
+	block, ok := m.blocks[number]
+	if !ok {
+		return nil, errors.New("block not found")
+	}
+	return block, nil
+}
+
+func TestWatcher_CatchesUpMissedBlocksAfterReconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	processed := make(chan string, 10)
+	mockAggregator := &MockAggregator{
+		ProcessFunc: func(e alchemyws.MinedTxEvent, direction aggregator.Direction) {
+			processed <- e.Transaction.Hash
+		},
+	}
+
+	first := make(chan alchemyws.MinedTxEvent, 1)
+	first <- alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{Hash: "0x7", From: "0xabc", BlockNumber: "0x7"}}
+	close(first)
+
+	second := make(chan alchemyws.MinedTxEvent, 1)
+	second <- alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{Hash: "0xa", From: "0xabc", BlockNumber: "0xa"}}
+
+	newClient := func(events chan alchemyws.MinedTxEvent) *MockAlchemyClient {
+		return &MockAlchemyClient{
+			SubscribeMinedFunc: func(opts alchemyws.MinedTxOptions) (<-chan alchemyws.MinedTxEvent, error) {
+				return events, nil
+			},
+			CloseFunc: func() error { return nil },
+		}
+	}
+
+	fetcher := &MockBlockFetcher{blocks: map[uint64]*ethrpc.Block{
+		// The stream dropped before delivering the rest of block 7
+		7: {Transactions: []alchemyws.Transaction{
+			{Hash: "0x7", From: "0xabc", BlockNumber: "0x7"},
+			{Hash: "0x7-missed", From: "0xabc", BlockNumber: "0x7"},
+		}},
+		8: {Transactions: []alchemyws.Transaction{
+			{Hash: "0x8", From: "0xabc", BlockNumber: "0x8"},
+			{Hash: "0x8-other", From: "0xdef", BlockNumber: "0x8"},
+		}},
+		9: {Transactions: []alchemyws.Transaction{{Hash: "0x9", From: "0xABC", BlockNumber: "0x9"}}},
+	}, heads: []uint64{6, 9}}
+
+	w := watcher.NewWatcher(ctx, newClient(first), []string{"0xabc"}, []string{}, mockAggregator,
+		watcher.WithDialer(func() (watcher.AlchemyClient, error) { return newClient(second), nil }),
+		watcher.WithCatchUp(fetcher, 100),
+	)
+	assert.NoError(t, w.Start())
+
+	var hashes []string
+	for len(hashes) < 5 {
+		select {
+		case h := <-processed:
+			hashes = append(hashes, h)
+		case <-time.After(1 * time.Second):
+			t.Fatalf("expected 5 processed transactions, got %v", hashes)
+		}
+	}
+	select {
+	case h := <-processed:
+		t.Fatalf("unexpected transaction %s processed twice", h)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Live events are processed concurrently, but missed blocks must be replayed before the resumed stream
+	assert.ElementsMatch(t, []string{"0x7", "0x7-missed", "0x8", "0x9", "0xa"}, hashes)
+	assert.Equal(t, []uint64{7, 8, 9}, fetcher.fetched)
+	assert.Less(t, slices.Index(hashes, "0x8"), slices.Index(hashes, "0xa"))
+	assert.Less(t, slices.Index(hashes, "0x9"), slices.Index(hashes, "0xa"))
+}
+
+// resumingClients returns a client whose stream closes without events and a dialer for a
+// second client whose stream stays open and empty.
+func resumingClients() (*MockAlchemyClient, watcher.Dialer) {
+	newClient := func(events chan alchemyws.MinedTxEvent) *MockAlchemyClient {
+		return &MockAlchemyClient{
+			SubscribeMinedFunc: func(opts alchemyws.MinedTxOptions) (<-chan alchemyws.MinedTxEvent, error) {
+				return events, nil
+			},
+			CloseFunc: func() error { return nil },
+		}
+	}
+	closed := make(chan alchemyws.MinedTxEvent)
+	close(closed)
+	return newClient(closed), func() (watcher.AlchemyClient, error) { return newClient(make(chan alchemyws.MinedTxEvent)), nil }
+}
+
+func TestWatcher_CatchesUpQuietWalletWithoutLaterEvent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	processed := make(chan string, 10)
+	mockAggregator := &MockAggregator{
+		ProcessFunc: func(e alchemyws.MinedTxEvent, direction aggregator.Direction) {
+			processed <- e.Transaction.Hash
+		},
+	}
+	fetcher := &MockBlockFetcher{blocks: map[uint64]*ethrpc.Block{
+		5: {Transactions: []alchemyws.Transaction{{Hash: "0x5", From: "0xabc", BlockNumber: "0x5"}}},
+		6: {Transactions: []alchemyws.Transaction{{Hash: "0x6", From: "0xabc", BlockNumber: "0x6"}}},
+	}, heads: []uint64{4, 6}}
+
+	client, dial := resumingClients()
+	w := watcher.NewWatcher(ctx, client, []string{"0xabc"}, nil, mockAggregator,
+		watcher.WithDialer(dial),
+		watcher.WithCatchUp(fetcher, 100),
+	)
+	require.NoError(t, w.Start())
+
+	var hashes []string
+	for len(hashes) < 2 {
+		select {
+		case h := <-processed:
+			hashes = append(hashes, h)
+		case <-time.After(time.Second):
+			t.Fatalf("blocks since the head seen on start must be replayed on reconnect, got %v", hashes)
+		}
+	}
+	assert.Equal(t, []string{"0x5", "0x6"}, hashes)
+}
+
+func TestWatcher_CatchUpReplaysOldestBlocksOfOversizedGap(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	processed := make(chan string, 10)
+	mockAggregator := &MockAggregator{
+		ProcessFunc: func(e alchemyws.MinedTxEvent, direction aggregator.Direction) {
+			processed <- e.Transaction.Hash
+		},
+	}
+	blocks := map[uint64]*ethrpc.Block{}
+	for n := uint64(5); n <= 9; n++ {
+		hash := ethrpc.EncodeQuantity(n)
+		blocks[n] = &ethrpc.Block{Transactions: []alchemyws.Transaction{{Hash: hash, From: "0xabc", BlockNumber: hash}}}
+	}
+	fetcher := &MockBlockFetcher{blocks: blocks, heads: []uint64{4, 9}}
+
+	client, dial := resumingClients()
+	w := watcher.NewWatcher(ctx, client, []string{"0xabc"}, nil, mockAggregator,
+		watcher.WithDialer(dial),
+		watcher.WithCatchUp(fetcher, 2),
+	)
+	require.NoError(t, w.Start())
+
+	var hashes []string
+	for len(hashes) < 2 {
+		select {
+		case h := <-processed:
+			hashes = append(hashes, h)
+		case <-time.After(time.Second):
+			t.Fatalf("expected the start of the gap to be replayed, got %v", hashes)
+		}
+	}
+	select {
+	case h := <-processed:
+		t.Fatalf("unexpected replay of %s past the catch-up limit", h)
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Equal(t, []string{"0x5", "0x6"}, hashes)
+}
+
+func TestWatcher_DispatchesNetFlowOnce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	directions := make(chan aggregator.Direction, 4)
+	mockAggregator := &MockAggregator{
+		ProcessFunc: func(e alchemyws.MinedTxEvent, direction aggregator.Direction) {
+			directions <- direction
+		},
+	}
+
+	var subscribed alchemyws.MinedTxOptions
+	events := make(chan alchemyws.MinedTxEvent, 1)
+	events <- alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{From: "0xabc", To: "0xdef"}}
+
+	mockClient := &MockAlchemyClient{
+		SubscribeMinedFunc: func(opts alchemyws.MinedTxOptions) (<-chan alchemyws.MinedTxEvent, error) {
+			subscribed = opts
+			return events, nil
+		},
+		CloseFunc: func() error { return nil },
+	}
+
+	w := watcher.NewWatcher(ctx, mockClient, nil, nil, mockAggregator,
+		watcher.WithNetWallets([]string{"0xabc", "0xdef"}),
+	)
+	assert.NoError(t, w.Start())
+	assert.Len(t, subscribed.Addresses, 4)
+
+	select {
+	case d := <-directions:
+		assert.Equal(t, aggregator.Net, d)
+	case <-time.After(1 * time.Second):
+		t.Fatal("expected net flow event not received")
+	}
+
+	select {
+	case d := <-directions:
+		t.Fatalf("unexpected second dispatch in direction %s", d)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWatcher_FilterDropsNonMatchingEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	processed := make(chan string, 2)
+	mockAggregator := &MockAggregator{
+		ProcessFunc: func(e alchemyws.MinedTxEvent, direction aggregator.Direction) {
+			processed <- e.Transaction.Hash
+		},
+	}
+
+	events := make(chan alchemyws.MinedTxEvent, 2)
+	events <- alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{Hash: "0xsmall", From: "0xabc", Value: "0x1"}}
+	events <- alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{Hash: "0xlarge", From: "0xabc", Value: "0xde0b6b3a7640000"}}
+
+	mockClient := &MockAlchemyClient{
+		SubscribeMinedFunc: func(opts alchemyws.MinedTxOptions) (<-chan alchemyws.MinedTxEvent, error) {
+			return events, nil
+		},
+		CloseFunc: func() error { return nil },
+	}
+
+	filter, err := txexpr.Compile(`direction == "from" && tx.value >= 1 ether`, nil)
+	assert.NoError(t, err)
+
+	w := watcher.NewWatcher(ctx, mockClient, []string{"0xabc"}, nil, mockAggregator, watcher.WithFilter(filter))
+	assert.NoError(t, w.Start())
+
+	select {
+	case h := <-processed:
+		assert.Equal(t, "0xlarge", h)
+	case <-time.After(1 * time.Second):
+		t.Fatal("expected matching event not received")
+	}
+
+	select {
+	case h := <-processed:
+		t.Fatalf("unexpected event %s passed the filter", h)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWatcher_ReportsWatchedContractCalls(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "abi.json"), []byte(`[{"type": "function", "name": "pause", "inputs": []}]`), 0o600))
+	contracts, err := contract.New([]contract.Spec{{Address: "0xproxy", ABI: "abi.json", Functions: []string{"pause"}}}, dir)
+	assert.NoError(t, err)
+
+	calls := make(chan contract.Call, 1)
+	mockAggregator := &MockAggregator{
+		ProcessCallFunc: func(e alchemyws.MinedTxEvent, call contract.Call) {
+			calls <- call
+		},
+	}
+
+	var subscribed alchemyws.MinedTxOptions
+	events := make(chan alchemyws.MinedTxEvent, 1)
+	events <- alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{From: "0xadmin", To: "0xproxy", Input: "0x8456cb59"}}
+
+	mockClient := &MockAlchemyClient{
+		SubscribeMinedFunc: func(opts alchemyws.MinedTxOptions) (<-chan alchemyws.MinedTxEvent, error) {
+			subscribed = opts
+			return events, nil
+		},
+		CloseFunc: func() error { return nil },
+	}
+
+	w := watcher.NewWatcher(ctx, mockClient, nil, nil, mockAggregator, watcher.WithContracts(contracts))
+	assert.NoError(t, w.Start())
+	assert.Equal(t, []alchemyws.AddressFilter{{To: "0xproxy"}}, subscribed.Addresses)
+
+	select {
+	case call := <-calls:
+		assert.Equal(t, "pause()", call.String())
+	case <-time.After(1 * time.Second):
+		t.Fatal("expected contract call not received")
+	}
+}
+
+type MockTracer struct {
+	transfers map[string][]ethrpc.InternalTransfer
+}
+
+func (m *MockTracer) InternalTransfers(ctx context.Context, hash string) ([]ethrpc.InternalTransfer, error) {
+	return m.transfers[hash], nil
+}
+
+func TestWatcher_TracesInternalTransfers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type dispatched struct {
+		event     alchemyws.MinedTxEvent
+		direction aggregator.Direction
+	}
+	internal := make(chan dispatched, 2)
+	mockAggregator := &MockAggregator{
+		ProcessInternalFunc: func(e alchemyws.MinedTxEvent, direction aggregator.Direction) {
+			internal <- dispatched{e, direction}
+		},
+	}
+
+	tracer := &MockTracer{transfers: map[string][]ethrpc.InternalTransfer{
+		"0xexec": {
+			{Type: "call", From: "0xsafe", To: "0xbob", Value: "0xde0b6b3a7640000"},
+			{Type: "call", From: "0xrouter", To: "0xother", Value: "0x1"},
+		},
+		"0xplain": {{Type: "call", From: "0xsafe", To: "0xcarol", Value: "0x1"}},
+	}}
+
+	var subscribed alchemyws.MinedTxOptions
+	events := make(chan alchemyws.MinedTxEvent, 2)
+	events <- alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{Hash: "0xplain", From: "0xsigner", To: "0xsafe", Input: "0x"}}
+	events <- alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{Hash: "0xexec", From: "0xsigner", To: "0xsafe", Input: "0x6a761202"}}
+
+	mockClient := &MockAlchemyClient{
+		SubscribeMinedFunc: func(opts alchemyws.MinedTxOptions) (<-chan alchemyws.MinedTxEvent, error) {
+			subscribed = opts
+			return events, nil
+		},
+		CloseFunc: func() error { return nil },
+	}
+
+	w := watcher.NewWatcher(ctx, mockClient, []string{"0xsafe"}, nil, mockAggregator, watcher.WithTracer(tracer))
+	assert.NoError(t, w.Start())
+	assert.ElementsMatch(t, []alchemyws.AddressFilter{{From: "0xsafe"}, {To: "0xsafe"}}, subscribed.Addresses)
+
+	select {
+	case d := <-internal:
+		assert.Equal(t, aggregator.From, d.direction)
+		assert.Equal(t, "0xexec", d.event.Transaction.Hash)
+		assert.Equal(t, "0xsafe", d.event.Transaction.From)
+		assert.Equal(t, "0xbob", d.event.Transaction.To)
+		assert.Equal(t, "0xde0b6b3a7640000", d.event.Transaction.Value)
+	case <-time.After(1 * time.Second):
+		t.Fatal("expected internal transfer not received")
+	}
+
+	select {
+	case d := <-internal:
+		t.Fatalf("unexpected internal transfer %s -> %s", d.event.Transaction.From, d.event.Transaction.To)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+type MockNonceTracker struct {
+	mined chan uint64
+}
+
+func (m *MockNonceTracker) Wallets() []string {
+	return []string{"0xops"}
+}
+
+func (m *MockNonceTracker) Mined(ctx context.Context, wallet string, nonce uint64, hash string) {
+	m.mined <- nonce
+}
+
+func TestWatcher_ReportsNoncesOfTrackedWallets(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tracker := &MockNonceTracker{mined: make(chan uint64, 1)}
+
+	var subscribed alchemyws.MinedTxOptions
+	events := make(chan alchemyws.MinedTxEvent, 1)
+	events <- alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{From: "0xops", To: "0xdef", Nonce: "0x2a"}}
+
+	mockClient := &MockAlchemyClient{
+		SubscribeMinedFunc: func(opts alchemyws.MinedTxOptions) (<-chan alchemyws.MinedTxEvent, error) {
+			subscribed = opts
+			return events, nil
+		},
+		CloseFunc: func() error { return nil },
+	}
+
+	w := watcher.NewWatcher(ctx, mockClient, []string{"0xops"}, nil, &MockAggregator{}, watcher.WithNonces(tracker))
+	assert.NoError(t, w.Start())
+	assert.Equal(t, []alchemyws.AddressFilter{{From: "0xops"}}, subscribed.Addresses)
+
+	select {
+	case nonce := <-tracker.mined:
+		assert.Equal(t, uint64(42), nonce)
+	case <-time.After(1 * time.Second):
+		t.Fatal("expected nonce not reported")
+	}
+}
+
+func TestWatcher_HealthReflectsWatchLoop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan alchemyws.MinedTxEvent, 1)
+	mockClient := &MockAlchemyClient{
+		SubscribeMinedFunc: func(opts alchemyws.MinedTxOptions) (<-chan alchemyws.MinedTxEvent, error) {
+			return events, nil
+		},
+		CloseFunc: func() error { return nil },
+	}
+
+	// An unread channel blocks the watch loop in dispatch, as a wedged dependency would
+	tracker := &MockNonceTracker{mined: make(chan uint64)}
+	w := watcher.NewWatcher(ctx, mockClient, []string{"0xops"}, nil, &MockAggregator{}, watcher.WithNonces(tracker))
+
+	assert.ErrorContains(t, w.Alive(time.Hour), "not started")
+	assert.ErrorContains(t, w.Ready(time.Hour), "not subscribed")
+
+	assert.NoError(t, w.Start())
+	assert.NoError(t, w.Alive(time.Hour))
+	assert.NoError(t, w.Ready(time.Hour))
+	assert.NoError(t, w.Ready(0))
+
+	events <- alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{From: "0xops", Nonce: "0x1"}}
+	time.Sleep(100 * time.Millisecond)
+
+	assert.ErrorContains(t, w.Alive(50*time.Millisecond), "watch loop stalled")
+	assert.ErrorContains(t, w.Ready(50*time.Millisecond), "no event for")
+	assert.NoError(t, w.Ready(0), "staleness check disabled")
+
+	<-tracker.mined
+	assert.Eventually(t, func() bool { return w.Alive(50*time.Millisecond) == nil }, time.Second, 10*time.Millisecond)
+}
+
+func TestWatcher_NotReadyWhileResubscribing(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan alchemyws.MinedTxEvent)
+	calls := 0
+	mockClient := &MockAlchemyClient{
+		SubscribeMinedFunc: func(opts alchemyws.MinedTxOptions) (<-chan alchemyws.MinedTxEvent, error) {
+			calls++
+			if calls > 1 {
+				return nil, errors.New("connection refused")
+			}
+			return events, nil
+		},
+		CloseFunc: func() error { return nil },
+	}
+
+	w := watcher.NewWatcher(ctx, mockClient, []string{"0xabc"}, nil, &MockAggregator{})
+	assert.NoError(t, w.Start())
+	assert.NoError(t, w.Ready(0))
+
+	close(events)
+	assert.Eventually(t, func() bool { return w.Ready(0) != nil }, time.Second, 10*time.Millisecond)
+	assert.NoError(t, w.Alive(time.Minute), "waiting to reconnect is not a stall")
+	w.Stop()
+}
+
+type notifyFunc func(ctx context.Context, alert notifier.Alert) error
+
+func (f notifyFunc) Notify(ctx context.Context, alert notifier.Alert) error {
+	return f(ctx, alert)
+}
+
+func TestWatcher_TracesEventThroughAggregationToNotifier(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	notif := notifier.Instrument("telegram", notifyFunc(func(ctx context.Context, alert notifier.Alert) error {
+		return nil
+	}), nil, nil, tp)
+	agg := aggregator.NewAggregator(ctx, notif, 1.0, time.Minute, time.Minute, aggregator.WithTracerProvider(tp))
+
+	events := make(chan alchemyws.MinedTxEvent, 1)
+	events <- alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{
+		Hash:  "0x1",
+		From:  "0xabc",
+		Value: "0xde0b6b3a7640000", // 1 ETH
+	}}
+	mockClient := &MockAlchemyClient{
+		SubscribeMinedFunc: func(opts alchemyws.MinedTxOptions) (<-chan alchemyws.MinedTxEvent, error) {
+			return events, nil
+		},
+		CloseFunc: func() error { return nil },
+	}
+
+	w := watcher.NewWatcher(ctx, mockClient, []string{"0xabc"}, nil, agg, watcher.WithTracerProvider(tp))
+	assert.NoError(t, w.Start())
+
+	spans := make(map[string]tracetest.SpanStub)
+	require.Eventually(t, func() bool {
+		for _, s := range exporter.GetSpans() {
+			spans[s.Name] = s
+		}
+		return len(spans) == 3
+	}, time.Second, 10*time.Millisecond)
+
+	event, process, notify := spans["watcher.event"], spans["aggregator.process"], spans["notifier.notify"]
+	assert.False(t, event.Parent.IsValid(), "event span is the root")
+	assert.Equal(t, event.SpanContext.SpanID(), process.Parent.SpanID())
+	assert.Equal(t, process.SpanContext.SpanID(), notify.Parent.SpanID())
+	assert.Equal(t, event.SpanContext.TraceID(), notify.SpanContext.TraceID())
+	assert.Contains(t, event.Attributes, attribute.String("tx_hash", "0x1"))
+	require.Len(t, process.Events, 1)
+	assert.Equal(t, "alert", process.Events[0].Name)
+}
+
+func TestWatcher_Wallets(t *testing.T) {
+	w := watcher.NewWatcher(context.Background(), &MockAlchemyClient{}, []string{"0xB", "0xa"}, []string{"0xc"}, &MockAggregator{},
+		watcher.WithNetWallets([]string{"0xD"}))
+
+	assert.Equal(t, map[aggregator.Direction][]string{
+		aggregator.From: {"0xa", "0xb"},
+		aggregator.To:   {"0xc"},
+		aggregator.Net:  {"0xd"},
+	}, w.Wallets())
+}
+
+func TestWatcher_AddWalletResubscribes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	processed := make(chan string, 4)
+	mockAggregator := &MockAggregator{
+		ProcessFunc: func(e alchemyws.MinedTxEvent, direction aggregator.Direction) {
+			processed <- e.Transaction.Hash
+		},
+	}
+
+	var closed atomic.Int32
+	first := make(chan alchemyws.MinedTxEvent)
+	initial := &MockAlchemyClient{
+		SubscribeMinedFunc: func(opts alchemyws.MinedTxOptions) (<-chan alchemyws.MinedTxEvent, error) {
+			return first, nil
+		},
+		CloseFunc: func() error {
+			closed.Add(1)
+			return nil
+		},
+	}
+
+	second := make(chan alchemyws.MinedTxEvent, 1)
+	var filters []alchemyws.AddressFilter
+	dialed := &MockAlchemyClient{
+		SubscribeMinedFunc: func(opts alchemyws.MinedTxOptions) (<-chan alchemyws.MinedTxEvent, error) {
+			filters = opts.Addresses
+			return second, nil
+		},
+		CloseFunc: func() error { return nil },
+	}
+
+	w := watcher.NewWatcher(ctx, initial, []string{"0xabc"}, nil, mockAggregator,
+		watcher.WithDialer(func() (watcher.AlchemyClient, error) { return dialed, nil }),
+	)
+	require.NoError(t, w.Start())
+
+	require.NoError(t, w.AddWallet(aggregator.To, "0xDEF"))
+	assert.ElementsMatch(t, []alchemyws.AddressFilter{{From: "0xabc"}, {To: "0xdef"}}, filters)
+	assert.Equal(t, int32(1), closed.Load(), "the old client is closed once resubscribed")
+	assert.Equal(t, []string{"0xdef"}, w.Wallets()[aggregator.To])
+
+	second <- alchemyws.MinedTxEvent{Transaction: alchemyws.Transaction{Hash: "0x1", From: "0x123", To: "0xdef"}}
+	select {
+	case h := <-processed:
+		assert.Equal(t, "0x1", h)
+	case <-time.After(time.Second):
+		t.Fatal("expected the added wallet to be processed")
+	}
+
+	require.NoError(t, w.RemoveWallet(aggregator.From, "0xabc"))
+	assert.Equal(t, []alchemyws.AddressFilter{{To: "0xdef"}}, filters)
+	assert.Empty(t, w.Wallets()[aggregator.From])
+}
+
+func TestWatcher_AddWalletRestoresWalletsWhenResubscribeFails(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := &MockAlchemyClient{
+		SubscribeMinedFunc: func(opts alchemyws.MinedTxOptions) (<-chan alchemyws.MinedTxEvent, error) {
+			return make(chan alchemyws.MinedTxEvent), nil
+		},
+		CloseFunc: func() error { return nil },
+	}
+	w := watcher.NewWatcher(ctx, client, []string{"0xabc"}, nil, &MockAggregator{},
+		watcher.WithDialer(func() (watcher.AlchemyClient, error) { return nil, errors.New("connection refused") }),
+	)
+	require.NoError(t, w.Start())
+
+	assert.EqualError(t, w.AddWallet(aggregator.To, "0xdef"), "resubscribe: dial client: connection refused")
+	assert.Empty(t, w.Wallets()[aggregator.To])
+	assert.EqualError(t, w.AddWallet("sideways", "0xdef"), `unknown direction "sideways"`)
+}
+
+func TestWatcher_AddWalletRequiresDialerOnceStarted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := &MockAlchemyClient{
+		SubscribeMinedFunc: func(opts alchemyws.MinedTxOptions) (<-chan alchemyws.MinedTxEvent, error) {
+			return make(chan alchemyws.MinedTxEvent), nil
+		},
+		CloseFunc: func() error { return nil },
+	}
+	w := watcher.NewWatcher(ctx, client, nil, nil, &MockAggregator{})
+
+	// Before Start the wallet is only recorded for the initial subscription
+	require.NoError(t, w.AddWallet(aggregator.From, "0xabc"))
+	require.NoError(t, w.Start())
+
+	assert.EqualError(t, w.AddWallet(aggregator.To, "0xdef"), "changing wallets at runtime requires a dialer")
+	assert.Equal(t, []string{"0xabc"}, w.Wallets()[aggregator.From])
+}